@@ -0,0 +1,61 @@
+package appsv1alpha1
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+// printerColumn 是 CRD additionalPrinterColumns 中的一项
+type printerColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	JSONPath string `json:"jsonPath"`
+}
+
+// crdVersions 只解析 CRD 中与打印列相关的字段
+type crdVersions struct {
+	Spec struct {
+		Versions []struct {
+			Name                     string          `json:"name"`
+			AdditionalPrinterColumns []printerColumn `json:"additionalPrinterColumns"`
+			Subresources             map[string]any  `json:"subresources"`
+		} `json:"versions"`
+	} `json:"spec"`
+}
+
+func TestCRDPrinterColumns(t *testing.T) {
+	want := []printerColumn{
+		{Name: "Replicas", Type: "integer", JSONPath: ".spec.replicas"},
+		{Name: "Available", Type: "integer", JSONPath: ".status.availableReplicas"},
+		{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+	}
+	// controller-gen 生成的 CRD 和手写的安装清单都要包含打印列
+	for _, path := range []string{
+		"../../config/crd/apps.myorg.io_customdeployments.yaml",
+		"../../config/crd/customdeployments.yaml",
+	} {
+		t.Run(path, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			crd := &crdVersions{}
+			if err := yaml.Unmarshal(data, crd); err != nil {
+				t.Fatal(err)
+			}
+			if len(crd.Spec.Versions) != 1 {
+				t.Fatalf("versions = %d, want 1", len(crd.Spec.Versions))
+			}
+			version := crd.Spec.Versions[0]
+			if !slices.Equal(version.AdditionalPrinterColumns, want) {
+				t.Fatalf("additionalPrinterColumns = %+v, want %+v", version.AdditionalPrinterColumns, want)
+			}
+			if _, ok := version.Subresources["status"]; !ok {
+				t.Fatalf("subresources = %v, want the status subresource", version.Subresources)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package appsv1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -26,15 +27,205 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 }
 
 type CustomDeploymentSpec struct {
-	Replicas int32 `json:"replicas,omitempty"`
+	// Replicas 是期望的副本数，为空时按 0 处理；启用 webhook 时为空会被默认为 1，显式设置的 0 保持不变
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// ReplicaStep 设置后副本数会向上取整到它的倍数（如按可用区数量均衡），0 表示不调整
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ReplicaStep int32 `json:"replicaStep,omitempty"`
+
+	// TerminationGracePeriodSeconds 设置 Pod 的优雅终止时间，为空时使用 Kubernetes 默认值（30 秒）
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
+	// AutomountServiceAccountToken 设置 Pod 是否自动挂载 ServiceAccount token，为空时使用默认行为
+	// +optional
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
+
+	// RuntimeClassName 设置 Pod 使用的 RuntimeClass（如 GPU 或沙箱运行时），为空时使用默认运行时
+	// +optional
+	RuntimeClassName *string `json:"runtimeClassName,omitempty"`
+
+	// ConfigFrom 是同 namespace 下 ConfigMap 的名称。控制器会把它内容的 hash 写入 Pod 模板注解，
+	// ConfigMap 变化时自动滚动更新 Pod
+	// +optional
+	ConfigFrom string `json:"configFrom,omitempty"`
+
+	// ExposeService 为 true 时创建选择工作负载 Pod 的 ClusterIP Service，改为 false 会删除 Service
+	// +optional
+	ExposeService bool `json:"exposeService,omitempty"`
+
+	// ServicePort 是 Service 暴露的端口，ExposeService 为 true 时必填
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	ServicePort int32 `json:"servicePort,omitempty"`
+
+	// Ingress 设置后会创建路由到工作负载 Service 的 Ingress，删除该字段会删除 Ingress，需要同时设置 exposeService
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// ServiceMonitor 设置后会创建 Prometheus Operator 的 ServiceMonitor 抓取工作负载指标，需要同时设置 exposeService
+	// +optional
+	ServiceMonitor *ServiceMonitorSpec `json:"serviceMonitor,omitempty"`
+
+	// Size 是平台提供的规格（如 small/medium/large），对应控制器配置的副本数，设置后覆盖 Replicas
+	// +optional
+	Size string `json:"size,omitempty"`
+
+	// PodAnnotations 会被写入 Pod 模板，用于控制 Istio sidecar 注入等。
+	// 目前只支持 sidecar.istio.io/、proxy.istio.io/、traffic.sidecar.istio.io/ 前缀的注解
+	// +optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
+	// Image 是容器镜像，为空时为了兼容旧的 CR 使用 nginx:latest，并记录 Warning 事件
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ImagePullPolicy 是容器的镜像拉取策略，为空时 latest 标签使用 Always，其他使用 IfNotPresent
+	// +optional
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// ContainerName 是受管容器的名称，默认 app。修改名称会替换所有 Pod
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	ContainerName string `json:"containerName,omitempty"`
+
+	// ContainerPort 是受管容器监听的端口，为 0 时不声明端口
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	ContainerPort int32 `json:"containerPort,omitempty"`
+
+	// PortName 是 ContainerPort 的名称，供 Service 的 targetPort 按名称引用
+	// +optional
+	PortName string `json:"portName,omitempty"`
+
+	// Env 是受管容器的环境变量，按名称比较，只调整顺序不会触发滚动更新
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Resources 是容器的资源需求，未设置时使用控制器配置的默认 requests
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// LivenessProbe 是受管容器的存活探针，设置后覆盖 spec.template 中的值
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+
+	// ReadinessProbe 是受管容器的就绪探针，设置后覆盖 spec.template 中的值
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+
+	// Schedule 设置后只在时间窗口内运行，窗口外 Deployment 会被缩容到 0
+	// +optional
+	Schedule *ScheduleSpec `json:"schedule,omitempty"`
+
+	// SpreadAcrossNodes 为 true 时添加 preferred 的 Pod 反亲和，尽量把副本分散到不同节点
+	// +optional
+	SpreadAcrossNodes bool `json:"spreadAcrossNodes,omitempty"`
+
+	// Template 设置后作为 Pod 模板的基础，用于表达 volumes、securityContext、tolerations 等其他字段无法描述的内容。
+	// 与 containerName 同名的容器（没有时新建）作为受管容器，image 以及 spec 中设置的字段会覆盖模板中的值，
+	// selector 标签总是会写入模板
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Template *corev1.PodTemplateSpec `json:"template,omitempty"`
+}
+
+// ScheduleSpec 用两个 cron 表达式描述工作负载的运行时间窗口：
+// 最近一次 Start 晚于最近一次 Stop 时处于运行状态
+type ScheduleSpec struct {
+	// Start 是开始运行的时间，如 "0 8 * * 1-5"
+	Start string `json:"start"`
+
+	// Stop 是缩容到 0 的时间，如 "0 20 * * 1-5"
+	Stop string `json:"stop"`
+
+	// TimeZone 是 cron 表达式使用的时区（如 Asia/Shanghai），为空时使用 UTC
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// IngressSpec 描述生成的 Ingress
+type IngressSpec struct {
+	// Host 是对外暴露的域名，为空时匹配所有域名
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// Path 是路由前缀，默认 /
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// ServicePort 是后端 Service 的端口
+	ServicePort int32 `json:"servicePort"`
+
+	// IngressClassName 指定使用的 IngressClass，为空时使用集群默认值
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+
+	// TLSSecretName 设置后为 Host 启用 TLS
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+}
+
+// ServiceMonitorSpec 描述生成的 ServiceMonitor 抓取端点
+type ServiceMonitorSpec struct {
+	// Port 是 Service 上暴露指标的端口名
+	Port string `json:"port"`
+
+	// Path 是指标路径，默认 /metrics
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Interval 是抓取间隔，如 30s，为空时使用 Prometheus 的默认值
+	// +optional
+	Interval string `json:"interval,omitempty"`
 }
 
 type CustomDeploymentStatus struct {
 	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// Replicas 是 Deployment 当前的副本数，供 scale 子资源使用
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ObservedGeneration 是控制器最近一次完整处理成功的 spec generation，小于 metadata.generation 时说明最新的修改还没有生效
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ReconcileCount 是控制器调谐该对象的累计次数，用于发现反复调谐的对象。为减少写入，数值会有延迟
+	// +optional
+	ReconcileCount int64 `json:"reconcileCount,omitempty"`
+
+	// Selector 是字符串形式的 Pod 标签选择器（如 app=foo），HPA 通过 scale 子资源读取
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// Conditions 记录调谐过程中的各类状态，如 Ready、Progressing、Degraded 和 PolicyViolation
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="Replicas",type=integer,JSONPath=".spec.replicas"
+// +kubebuilder:printcolumn:name="Available",type=integer,JSONPath=".status.availableReplicas"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
 type CustomDeployment struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// backlogSampleInterval 是采样工作队列长度的间隔
+const backlogSampleInterval = 10 * time.Second
+
+// backlogExceeded 在工作队列长度持续超过阈值时为 1，比直接对 workqueue_depth 告警更不容易被短暂的突发触发
+var backlogExceeded = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "reconcile_backlog_exceeded",
+	Help: "1 when the CustomDeployment work queue depth has stayed above the alarm threshold for the alarm window, 0 otherwise.",
+})
+
+func init() {
+	metrics.Registry.MustRegister(backlogExceeded)
+}
+
+// BacklogAlarm 定期采样工作队列长度，长度在 Window 内一直超过 Threshold 时把 reconcile_backlog_exceeded 设为 1，
+// 回落到阈值以内后立即恢复为 0。作为 Runnable 加入 Manager
+type BacklogAlarm struct {
+	Threshold int
+	Window    time.Duration
+
+	mu sync.Mutex
+	// depth 返回当前队列长度，控制器启动、创建工作队列后才会设置
+	depth func() int
+	// since 是队列长度开始超过阈值的时间，没有超过时为零值
+	since time.Time
+}
+
+// wrapQueue 返回控制器使用的工作队列构造函数，与 controller-runtime 的默认队列相同，同时记录队列以便采样长度
+func (a *BacklogAlarm) wrapQueue() func(string, workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+	return func(name string, rateLimiter workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+		q := workqueue.NewTypedRateLimitingQueueWithConfig(rateLimiter, workqueue.TypedRateLimitingQueueConfig[reconcile.Request]{Name: name})
+		a.mu.Lock()
+		a.depth = q.Len
+		a.mu.Unlock()
+		return q
+	}
+}
+
+// observe 记录 now 时刻的队列长度并更新指标
+func (a *BacklogAlarm) observe(depth int, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if depth <= a.Threshold {
+		a.since = time.Time{}
+		backlogExceeded.Set(0)
+		return
+	}
+	if a.since.IsZero() {
+		a.since = now
+	}
+	if now.Sub(a.since) >= a.Window {
+		backlogExceeded.Set(1)
+	}
+}
+
+// Start 实现 manager.Runnable，每隔 backlogSampleInterval 采样一次队列长度，直到 ctx 结束
+func (a *BacklogAlarm) Start(ctx context.Context) error {
+	ticker := time.NewTicker(backlogSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			a.mu.Lock()
+			depth := a.depth
+			a.mu.Unlock()
+			if depth != nil {
+				a.observe(depth(), now)
+			}
+		}
+	}
+}
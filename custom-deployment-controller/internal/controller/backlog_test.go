@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// backlogExceededValue 返回 reconcile_backlog_exceeded 的当前值
+func backlogExceededValue(t *testing.T) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := backlogExceeded.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetGauge().GetValue()
+}
+
+func TestBacklogAlarm(t *testing.T) {
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	type sample struct {
+		at    time.Duration
+		depth int
+		want  float64
+	}
+	tests := []struct {
+		name    string
+		samples []sample
+	}{
+		{
+			name:    "sustained backlog",
+			samples: []sample{{0, 20, 0}, {time.Minute, 25, 0}, {5 * time.Minute, 30, 1}, {6 * time.Minute, 12, 1}},
+		},
+		{
+			// 短暂的突发不会触发告警
+			name:    "short burst",
+			samples: []sample{{0, 50, 0}, {time.Minute, 5, 0}, {2 * time.Minute, 50, 0}, {6 * time.Minute, 50, 0}},
+		},
+		{
+			name:    "recovers below the threshold",
+			samples: []sample{{0, 20, 0}, {5 * time.Minute, 20, 1}, {6 * time.Minute, 10, 0}},
+		},
+		{
+			name:    "at the threshold",
+			samples: []sample{{0, 10, 0}, {10 * time.Minute, 10, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backlogExceeded.Set(0)
+			alarm := &BacklogAlarm{Threshold: 10, Window: 5 * time.Minute}
+			for _, s := range tt.samples {
+				alarm.observe(s.depth, start.Add(s.at))
+				if got := backlogExceededValue(t); got != s.want {
+					t.Fatalf("after depth %d at +%s: reconcile_backlog_exceeded = %v, want %v", s.depth, s.at, got, s.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// generationTracker 记录每个对象上一次调谐时看到的 generation，用来发现用户修改了 spec
+type generationTracker struct {
+	mu   sync.Mutex
+	seen map[types.NamespacedName]int64
+}
+
+// changed 记录对象当前的 generation，返回它是否与上一次调谐时不同；第一次看到对象时返回 false
+func (t *generationTracker) changed(key types.NamespacedName, generation int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.seen == nil {
+		t.seen = map[types.NamespacedName]int64{}
+	}
+	previous, ok := t.seen[key]
+	t.seen[key] = generation
+	return ok && previous != generation
+}
+
+// forget 在对象被删除后清理记录
+func (t *generationTracker) forget(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.seen, key)
+}
+
+// len 返回当前记录的对象数，即控制器正在管理的对象数
+func (t *generationTracker) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.seen)
+}
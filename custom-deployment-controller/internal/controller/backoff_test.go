@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileResetsBackoffOnSpecChange(t *testing.T) {
+	tests := []struct {
+		name        string
+		bump        bool
+		wantRequeue int
+	}{
+		{"spec changed", true, 0},
+		{"spec unchanged", false, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+			limiter := workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]()
+			env.c.rateLimiter = limiter
+			env.reconcileUntilCreated(t, "web")
+
+			// 模拟之前连续失败累积的退避
+			req := requestFor("web")
+			for i := 0; i < 5; i++ {
+				limiter.When(req)
+			}
+			if tt.bump {
+				env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) { cd.Spec.Replicas = ptrInt32(3) })
+			}
+			env.reconcile(t, "web")
+
+			if got := limiter.NumRequeues(req); got != tt.wantRequeue {
+				t.Fatalf("NumRequeues = %d, want %d", got, tt.wantRequeue)
+			}
+			if tt.bump {
+				// 退避清零后下一次失败按最短间隔重试
+				if delay := limiter.When(req); delay > 10*time.Millisecond {
+					t.Fatalf("next retry delay = %v, want the base delay", delay)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerationTracker(t *testing.T) {
+	key := types.NamespacedName{Namespace: testNamespace, Name: "web"}
+	steps := []struct {
+		generation int64
+		forget     bool
+		want       bool
+	}{
+		{generation: 1, want: false},
+		{generation: 1, want: false},
+		{generation: 2, want: true},
+		{generation: 2, want: false},
+		{generation: 3, forget: true, want: false},
+	}
+	tracker := &generationTracker{}
+	for i, step := range steps {
+		if step.forget {
+			tracker.forget(key)
+		}
+		if got := tracker.changed(key, step.generation); got != step.want {
+			t.Fatalf("step %d: changed(%d) = %v, want %v", i, step.generation, got, step.want)
+		}
+	}
+	if n := tracker.len(); n != 1 {
+		t.Fatalf("len = %d, want 1", n)
+	}
+}
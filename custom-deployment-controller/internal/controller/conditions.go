@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"fmt"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CR 的汇总条件：Ready 表示期望的副本都已可用，Progressing 表示发布尚未完成，
+// Degraded 表示 Deployment 无法读取或发布失败，需要人工介入
+const (
+	ConditionReady       = "Ready"
+	ConditionProgressing = "Progressing"
+	ConditionDegraded    = "Degraded"
+)
+
+// setCondition 设置条件并记录处理的 generation
+func setCondition(cd *appsv1alpha1.CustomDeployment, t string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+		Type:               t,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: cd.Generation,
+	})
+}
+
+// deploymentStatusReported 判断 Deployment 控制器是否已经上报过状态。
+// observedGeneration 为 0 时 availableReplicas 等字段只是零值，不代表真的没有可用副本
+func deploymentStatusReported(deploy *appsv1.Deployment) bool {
+	return deploy.Status.ObservedGeneration > 0
+}
+
+// setWorkloadConditions 根据 Deployment 的状态与期望副本数设置 Ready、Progressing 和 Degraded
+func setWorkloadConditions(cd *appsv1alpha1.CustomDeployment, deploy *appsv1.Deployment, desiredReplicas int32) {
+	status := deploy.Status
+	observed := status.ObservedGeneration >= deploy.Generation && deploy.Generation > 0
+	replicasMessage := fmt.Sprintf("%d/%d replicas available", status.AvailableReplicas, desiredReplicas)
+
+	rolledOut := observed && status.UpdatedReplicas == desiredReplicas &&
+		status.Replicas == desiredReplicas && status.AvailableReplicas >= desiredReplicas
+	if rolledOut {
+		setCondition(cd, ConditionReady, metav1.ConditionTrue, "ReplicasAvailable", replicasMessage)
+		setCondition(cd, ConditionProgressing, metav1.ConditionFalse, "RolloutComplete", replicasMessage)
+	} else {
+		setCondition(cd, ConditionReady, metav1.ConditionFalse, "ReplicasUnavailable", replicasMessage)
+		setCondition(cd, ConditionProgressing, metav1.ConditionTrue, "RollingOut",
+			fmt.Sprintf("%d/%d replicas updated, %s", status.UpdatedReplicas, desiredReplicas, replicasMessage))
+	}
+
+	// 发布超时或创建 Pod 失败时 Deployment 自身不会恢复
+	if cond := findDeploymentCondition(deploy, appsv1.DeploymentProgressing); cond != nil &&
+		cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded" {
+		setCondition(cd, ConditionDegraded, metav1.ConditionTrue, cond.Reason, cond.Message)
+		return
+	}
+	if cond := findDeploymentCondition(deploy, appsv1.DeploymentReplicaFailure); cond != nil && cond.Status == corev1.ConditionTrue {
+		setCondition(cd, ConditionDegraded, metav1.ConditionTrue, cond.Reason, cond.Message)
+		return
+	}
+	setCondition(cd, ConditionDegraded, metav1.ConditionFalse, "AsExpected", "Deployment is healthy")
+}
+
+// setDeploymentErrorConditions 在无法读取或写入 Deployment 时设置条件
+func setDeploymentErrorConditions(cd *appsv1alpha1.CustomDeployment, reason string, err error) {
+	setCondition(cd, ConditionReady, metav1.ConditionFalse, reason, err.Error())
+	setCondition(cd, ConditionDegraded, metav1.ConditionTrue, reason, err.Error())
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestReconcileConditions(t *testing.T) {
+	type want struct {
+		ready, progressing, degraded metav1.ConditionStatus
+		reason                       string
+	}
+	tests := []struct {
+		name     string
+		status   func(*appsv1.DeploymentStatus)
+		fetchErr error
+		want     want
+	}{
+		{
+			name: "rollout complete",
+			status: func(s *appsv1.DeploymentStatus) {
+				s.ObservedGeneration, s.Replicas, s.UpdatedReplicas, s.AvailableReplicas = 1, 2, 2, 2
+			},
+			want: want{metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, "ReplicasAvailable"},
+		},
+		{
+			name: "rolling out",
+			status: func(s *appsv1.DeploymentStatus) {
+				s.ObservedGeneration, s.Replicas, s.UpdatedReplicas, s.AvailableReplicas = 1, 2, 1, 1
+			},
+			want: want{metav1.ConditionFalse, metav1.ConditionTrue, metav1.ConditionFalse, "ReplicasUnavailable"},
+		},
+		{
+			name:     "Deployment fetch failed",
+			fetchErr: apierrors.NewServiceUnavailable("apiserver is shutting down"),
+			want:     want{ready: metav1.ConditionFalse, degraded: metav1.ConditionTrue, reason: "DeploymentFetchFailed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetchErr error
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Generation = 3
+			})}, withInterceptor(interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					if _, ok := obj.(*appsv1.Deployment); ok && fetchErr != nil {
+						return fetchErr
+					}
+					return c.Get(ctx, key, obj, opts...)
+				},
+			}))
+			env.reconcileUntilCreated(t, "web")
+
+			// 刚创建时 Deployment 还没有上报状态，不设置汇总条件
+			conditions := env.customDeployment(t, "web").Status.Conditions
+			if cond := meta.FindStatusCondition(conditions, ConditionReady); cond != nil {
+				t.Fatalf("Ready condition after create = %+v, want none before the Deployment reports status", cond)
+			}
+
+			if tt.status != nil {
+				// fake client 创建对象时不设置 generation
+				deploy := env.deployment(t, "web")
+				deploy.Generation = 1
+				if err := env.c.Update(context.Background(), deploy); err != nil {
+					t.Fatal(err)
+				}
+				env.setDeploymentStatus(t, "web", tt.status)
+			}
+			fetchErr = tt.fetchErr
+			_, err := env.c.Reconcile(context.Background(), requestFor("web"))
+			if !errors.Is(err, tt.fetchErr) {
+				t.Fatalf("Reconcile error = %v, want %v", err, tt.fetchErr)
+			}
+
+			conditions = env.customDeployment(t, "web").Status.Conditions
+			for condType, status := range map[string]metav1.ConditionStatus{
+				ConditionReady:       tt.want.ready,
+				ConditionProgressing: tt.want.progressing,
+				ConditionDegraded:    tt.want.degraded,
+			} {
+				if status == "" {
+					continue
+				}
+				cond := meta.FindStatusCondition(conditions, condType)
+				if cond == nil || cond.Status != status {
+					t.Fatalf("%s condition = %+v, want %s", condType, cond, status)
+				}
+				if cond.ObservedGeneration != 3 {
+					t.Fatalf("%s observedGeneration = %d, want 3", condType, cond.ObservedGeneration)
+				}
+			}
+			if got := meta.FindStatusCondition(conditions, ConditionReady).Reason; got != tt.want.reason {
+				t.Fatalf("Ready reason = %q, want %q", got, tt.want.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// configHashAnnotation 是 Pod 模板上记录 spec.configFrom 内容 hash 的注解，hash 变化会触发滚动更新
+const configHashAnnotation = "apps.myorg.io/config-hash"
+
+// configChecksumLabel 是 Deployment 上记录 configFrom 内容 hash 的标签，供按配置版本区分的外部指标 HPA 使用。
+// 标签值最长 63 个字符，只取 hash 的前 16 位
+const configChecksumLabel = "apps.myorg.io/config-checksum"
+
+// configFromIndex 是按 spec.configFrom 查找 CustomDeployment 的字段索引
+const configFromIndex = ".spec.configFrom"
+
+func indexConfigFrom(obj client.Object) []string {
+	cd, ok := obj.(*appsv1alpha1.CustomDeployment)
+	if !ok || cd.Spec.ConfigFrom == "" {
+		return nil
+	}
+	return []string{cd.Spec.ConfigFrom}
+}
+
+// configMapHash 计算 ConfigMap 数据的 hash，按 key 排序保证结果稳定
+func configMapHash(cm *corev1.ConfigMap) string {
+	h := sha256.New()
+
+	keys := make([]string, 0, len(cm.Data))
+	for k := range cm.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, cm.Data[k])
+	}
+
+	binaryKeys := make([]string, 0, len(cm.BinaryData))
+	for k := range cm.BinaryData {
+		binaryKeys = append(binaryKeys, k)
+	}
+	sort.Strings(binaryKeys)
+	for _, k := range binaryKeys {
+		fmt.Fprintf(h, "%s=", k)
+		h.Write(cm.BinaryData[k])
+		h.Write([]byte("\n"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// applyConfigHash 把 spec.configFrom 指向的 ConfigMap 内容 hash 写入 Pod 模板注解和 Deployment 标签
+func (c *CustomDeploymentController) applyConfigHash(ctx context.Context, cd *appsv1alpha1.CustomDeployment, deploy *appsv1.Deployment) error {
+	if cd.Spec.ConfigFrom == "" {
+		return nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Name: cd.Spec.ConfigFrom, Namespace: cd.Namespace}, cm); err != nil {
+		return fmt.Errorf("get configFrom ConfigMap %q: %w", cd.Spec.ConfigFrom, err)
+	}
+
+	if deploy.Spec.Template.Annotations == nil {
+		deploy.Spec.Template.Annotations = map[string]string{}
+	}
+	hash := configMapHash(cm)
+	deploy.Spec.Template.Annotations[configHashAnnotation] = hash
+
+	// Deployment 的标签与 selector 共用同一个 map，复制后再修改
+	labels := make(map[string]string, len(deploy.Labels)+1)
+	for k, v := range deploy.Labels {
+		labels[k] = v
+	}
+	labels[configChecksumLabel] = hash[:16]
+	deploy.Labels = labels
+	return nil
+}
+
+// recordConfigChange 在 configFrom 的内容 hash 变化并已写入 Deployment 时记录事件，
+// 便于把滚动更新与配置修改对应起来。首次设置或移除 configFrom 时也会记录
+func (c *CustomDeploymentController) recordConfigChange(cd *appsv1alpha1.CustomDeployment, oldHash, newHash string) {
+	if oldHash == newHash {
+		return
+	}
+	c.Recorder.Eventf(cd, corev1.EventTypeNormal, "ConfigChanged", "Config of configFrom %q changed, rolling out: hash %s -> %s",
+		cd.Spec.ConfigFrom, shortHash(oldHash), shortHash(newHash))
+}
+
+// shortHash 返回事件中展示的 hash 前缀，空值显示为 none
+func shortHash(hash string) string {
+	if hash == "" {
+		return "none"
+	}
+	if len(hash) > 16 {
+		return hash[:16]
+	}
+	return hash
+}
+
+// configMapToCustomDeployments 找出通过 spec.configFrom 引用该 ConfigMap 的 CustomDeployment
+func (c *CustomDeploymentController) configMapToCustomDeployments(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	if obj.GetLabels()[podDefaultsLabel] == "true" {
+		return c.podDefaultsToCustomDeployments(ctx, obj)
+	}
+
+	list := &appsv1alpha1.CustomDeploymentList{}
+	if err := c.List(ctx, list,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{configFromIndex: obj.GetName()},
+	); err != nil {
+		logger.Error(err, "Failed to list CustomDeployments for ConfigMap", "configmap", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, cd := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cd)})
+	}
+	return requests
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newConfigFromEnv(t *testing.T) *testEnv {
+	t.Helper()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: testNamespace},
+		Data:       map[string]string{"level": "info"},
+	}
+	cd := newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+		cd.Spec.ConfigFrom = "app-config"
+	})
+	return newTestEnv(t, []client.Object{cm, cd})
+}
+
+// updateConfigMap 修改 configFrom 指向的 ConfigMap
+func (e *testEnv) updateConfigMap(t *testing.T, data map[string]string) {
+	t.Helper()
+	cm := &corev1.ConfigMap{}
+	if err := e.c.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "app-config"}, cm); err != nil {
+		t.Fatal(err)
+	}
+	cm.Data = data
+	if err := e.c.Update(context.Background(), cm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcileConfigMapChangeRollsDeployment(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     map[string]string
+		wantRoll bool
+	}{
+		{"data changed", map[string]string{"level": "debug"}, true},
+		{"key added", map[string]string{"level": "info", "format": "json"}, true},
+		{"unchanged", map[string]string{"level": "info"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newConfigFromEnv(t)
+			before := env.reconcileUntilCreated(t, "web").Spec.Template.Annotations[configHashAnnotation]
+			if before == "" {
+				t.Fatalf("expected %s on the pod template", configHashAnnotation)
+			}
+
+			env.updateConfigMap(t, tt.data)
+			env.writes.reset()
+			env.reconcile(t, "web")
+			after := env.deployment(t, "web").Spec.Template.Annotations[configHashAnnotation]
+			if rolled := after != before; rolled != tt.wantRoll {
+				t.Fatalf("pod template hash changed = %v, want %v (before %s, after %s)", rolled, tt.wantRoll, before, after)
+			}
+			if n := env.writes.get("update/Deployment"); (n > 0) != tt.wantRoll {
+				t.Fatalf("Deployment updates = %d, want rollout %v", n, tt.wantRoll)
+			}
+		})
+	}
+}
+
+func TestReconcileConfigChecksumLabel(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       map[string]string
+		wantChange bool
+	}{
+		{"unchanged data keeps the label", map[string]string{"level": "info"}, false},
+		{"changed data updates the label", map[string]string{"level": "debug"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newConfigFromEnv(t)
+			before := env.reconcileUntilCreated(t, "web").Labels[configChecksumLabel]
+			if len(before) != 16 {
+				t.Fatalf("%s = %q, want a 16-character hash", configChecksumLabel, before)
+			}
+			// 没有变化时重复调谐，标签保持稳定
+			env.reconcile(t, "web")
+			if got := env.deployment(t, "web").Labels[configChecksumLabel]; got != before {
+				t.Fatalf("%s changed from %q to %q without a config change", configChecksumLabel, before, got)
+			}
+
+			env.updateConfigMap(t, tt.data)
+			env.reconcile(t, "web")
+			after := env.deployment(t, "web").Labels[configChecksumLabel]
+			if changed := after != before; changed != tt.wantChange {
+				t.Fatalf("%s changed = %v, want %v (before %s, after %s)", configChecksumLabel, changed, tt.wantChange, before, after)
+			}
+		})
+	}
+}
+
+func TestReconcileConfigChecksumLabelRemoved(t *testing.T) {
+	env := newConfigFromEnv(t)
+	env.reconcileUntilCreated(t, "web")
+	env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) { cd.Spec.ConfigFrom = "" })
+	env.reconcile(t, "web")
+
+	if got, ok := env.deployment(t, "web").Labels[configChecksumLabel]; ok {
+		t.Fatalf("%s = %q, want it removed with configFrom", configChecksumLabel, got)
+	}
+}
+
+func TestReconcileConfigChangedEvent(t *testing.T) {
+	steps := []struct {
+		name       string
+		data       map[string]string
+		wantEvents int
+	}{
+		{"changed", map[string]string{"level": "debug"}, 1},
+		{"no-op reconcile", nil, 0},
+		{"changed back", map[string]string{"level": "info"}, 1},
+	}
+	env := newConfigFromEnv(t)
+	hash := env.reconcileUntilCreated(t, "web").Spec.Template.Annotations[configHashAnnotation]
+	if containsEvent(env.events(), "ConfigChanged") {
+		t.Fatal("unexpected ConfigChanged event on create")
+	}
+	for _, step := range steps {
+		if step.data != nil {
+			env.updateConfigMap(t, step.data)
+		}
+		env.reconcile(t, "web")
+		newHash := env.deployment(t, "web").Spec.Template.Annotations[configHashAnnotation]
+
+		var changed []string
+		for _, e := range env.events() {
+			if strings.Contains(e, " ConfigChanged ") {
+				changed = append(changed, e)
+			}
+		}
+		if len(changed) != step.wantEvents {
+			t.Fatalf("%s: ConfigChanged events = %v, want %d", step.name, changed, step.wantEvents)
+		}
+		if step.wantEvents > 0 && !strings.Contains(changed[0], "hash "+shortHash(hash)+" -> "+shortHash(newHash)) {
+			t.Fatalf("%s: event %q, want hashes %s -> %s", step.name, changed[0], shortHash(hash), shortHash(newHash))
+		}
+		hash = newHash
+	}
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// defaultContainerName 是 spec.containerName 为空时受管容器的名称
+const defaultContainerName = "app"
+
+// containerName 返回 CR 管理的容器名称
+func containerName(cd *appsv1alpha1.CustomDeployment) string {
+	if cd.Spec.ContainerName != "" {
+		return cd.Spec.ContainerName
+	}
+	return defaultContainerName
+}
+
+// validateContainerName 校验 spec.containerName 是合法的 DNS label
+func validateContainerName(cd *appsv1alpha1.CustomDeployment) error {
+	if cd.Spec.ContainerName == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(cd.Spec.ContainerName); len(errs) > 0 {
+		return fmt.Errorf("invalid containerName %q: %s", cd.Spec.ContainerName, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// managedContainers 返回线上和期望的 Deployment 中由 CR 管理的容器，期望的 Deployment 只有这一个容器
+func managedContainers(live, desired *appsv1.Deployment) (liveContainer, desiredContainer *corev1.Container) {
+	if len(desired.Spec.Template.Spec.Containers) == 0 {
+		return nil, nil
+	}
+	desiredContainer = &desired.Spec.Template.Spec.Containers[0]
+	return findContainer(live, desiredContainer.Name), desiredContainer
+}
+
+// renamedContainer 判断 spec.containerName 是否改变：线上找不到期望名称的容器时，
+// 返回控制器创建的第一个容器的旧名称
+func renamedContainer(live, desired *appsv1.Deployment) (oldName string, renamed bool) {
+	liveContainer, desiredContainer := managedContainers(live, desired)
+	if liveContainer != nil || desiredContainer == nil || len(live.Spec.Template.Spec.Containers) == 0 {
+		return "", false
+	}
+	return live.Spec.Template.Spec.Containers[0].Name, true
+}
+
+// syncContainerName 在容器改名时原地修改受管容器的名称，其余字段由后续的同步逻辑更新。
+// 改名会替换所有 Pod，按 Deployment 的滚动更新策略进行
+func syncContainerName(live, desired *appsv1.Deployment) bool {
+	if _, renamed := renamedContainer(live, desired); !renamed {
+		return false
+	}
+	live.Spec.Template.Spec.Containers[0].Name = desired.Spec.Template.Spec.Containers[0].Name
+	return true
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileContainerName(t *testing.T) {
+	tests := []struct {
+		name        string
+		initial     string
+		updated     string
+		wantInitial string
+		wantUpdated string
+	}{
+		{name: "default name", wantInitial: defaultContainerName, wantUpdated: defaultContainerName},
+		{name: "custom name", initial: "web", updated: "web", wantInitial: "web", wantUpdated: "web"},
+		{name: "renamed", initial: "web", updated: "server", wantInitial: "web", wantUpdated: "server"},
+		{name: "reset to default", initial: "web", wantInitial: "web", wantUpdated: defaultContainerName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.ContainerName = tt.initial
+			})})
+			containers := env.reconcileUntilCreated(t, "web").Spec.Template.Spec.Containers
+			if len(containers) != 1 || containers[0].Name != tt.wantInitial {
+				t.Fatalf("after create: containers = %v, want one named %q", containers, tt.wantInitial)
+			}
+
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.ContainerName = tt.updated
+				cd.Spec.Image = "registry.example.com/app:v2"
+			})
+			env.reconcile(t, "web")
+			containers = env.deployment(t, "web").Spec.Template.Spec.Containers
+			// 改名后其余字段仍然同步到同一个容器上
+			if len(containers) != 1 || containers[0].Name != tt.wantUpdated || containers[0].Image != "registry.example.com/app:v2" {
+				t.Fatalf("after update: containers = %v, want one named %q running v2", containers, tt.wantUpdated)
+			}
+		})
+	}
+}
@@ -3,34 +3,151 @@ package controller
 import (
 	"context"
 	"custom-deployment-controller/api/appsv1alpha1"
+	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const customDeploymentFinalizer = "apps.myorg.io/finalizer"
 
+// DefaultImage 是 CR 没有指定镜像时使用的镜像，defaulting webhook 也会写入它
+const DefaultImage = "nginx:latest"
+
+// errNilScheme 在没有设置 Scheme 时返回，否则 SetControllerReference 会在深处 panic
+var errNilScheme = fmt.Errorf("CustomDeploymentController.Scheme is nil, set it to the manager's scheme")
+
+// DefaultSelectorLabelKey 是 Deployment selector 和 Pod 标签默认使用的 key
+const DefaultSelectorLabelKey = "app"
+
 type CustomDeploymentController struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Resolver 可选，设置后会把镜像解析出的 digest 记录到 CR 的注解上
+	Resolver ImageResolver
+
+	// AllowedRegistries 是允许使用的镜像仓库，为空时不限制
+	AllowedRegistries []string
+
+	// NoBlockOwnerDeletion 为 true 时 OwnerReference 的 blockOwnerDeletion 设为 false，
+	// 适用于控制器没有 owner 的 finalizers 子资源权限的受限环境
+	NoBlockOwnerDeletion bool
+
+	// DeadLetter 可选，记录持续失败的对象
+	DeadLetter *DeadLetterRecorder
+
+	// SelectorLabelKey 是 selector 和 Pod 标签使用的 key，为空时使用 DefaultSelectorLabelKey。
+	// Deployment 的 selector 不可修改，更改后已有的 Deployment 需要删除重建（或设置 allow-recreate 注解）
+	SelectorLabelKey string
+
+	// DefaultRequests 是 CR 没有设置 resources 时容器使用的 requests
+	DefaultRequests corev1.ResourceList
+
+	// Sizes 是 spec.size 可选的规格及对应的副本数
+	Sizes map[string]int32
+
+	// MaxReplicas 是允许的最大副本数，超过时视为无效 spec，0 表示不限制
+	MaxReplicas int32
+
+	// FinalizerTimeout 是 CR 开始删除后等待清理完成的最长时间，超时后强制移除 finalizer，0 表示一直等待
+	FinalizerTimeout time.Duration
+
+	// PSADefaults 为 true 时为 Pod 和容器写入满足 restricted Pod Security Standard 的 securityContext
+	PSADefaults bool
+
+	// BacklogAlarm 可选，工作队列长度持续超过阈值时通过指标告警
+	BacklogAlarm *BacklogAlarm
+
+	// StatusBatcher 可选，设置后状态写入会按对象合并，而不是每次调谐都立即写入
+	StatusBatcher *StatusBatcher
+
+	// Recorder 用于在 CR 上记录事件
+	Recorder record.EventRecorder
+
+	// Clock 可选，用于计算 spec.schedule 的运行窗口，为空时使用系统时间
+	Clock clock.PassiveClock
+
+	// rateLimiter 是工作队列使用的限速器，spec 变化时用它清零对象的退避
+	rateLimiter     workqueue.TypedRateLimiter[reconcile.Request]
+	generations     generationTracker
+	reconcileCounts reconcileCounter
+	stats           reconcileStats
+	results         reconcileResults
+}
+
+// selectorLabels 返回 CR 下属对象统一使用的 selector 标签
+func (c *CustomDeploymentController) selectorLabels(cd *appsv1alpha1.CustomDeployment) map[string]string {
+	key := c.SelectorLabelKey
+	if key == "" {
+		key = DefaultSelectorLabelKey
+	}
+	return map[string]string{key: cd.Name}
 }
 
 func (c *CustomDeploymentController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	defer observeReconcileDuration(time.Now())
+
+	result, err := c.reconcile(ctx, req)
+	if isVersionSkewError(err) {
+		log.FromContext(ctx).Info("CustomDeployment API version is not available, CRD may be upgrading; requeueing", "error", err.Error())
+		return ctrl.Result{RequeueAfter: versionSkewRequeueAfter}, nil
+	}
+	c.stats.observe(err)
+	c.results.record(req.NamespacedName, err)
+	if c.DeadLetter != nil {
+		c.DeadLetter.Observe(ctx, req.NamespacedName, err)
+	}
+	return result, err
+}
+
+func (c *CustomDeploymentController) reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
 	logger := log.FromContext(ctx)
 
 	cd := &appsv1alpha1.CustomDeployment{}
 	if err := c.Get(ctx, req.NamespacedName, cd); err != nil {
+		if errors.IsNotFound(err) {
+			c.generations.forget(req.NamespacedName)
+			c.reconcileCounts.forget(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	// 失败原因记录为事件，kubectl describe 即可看到；冲突会立即重试，不记录
+	defer func() {
+		if err != nil && !errors.IsConflict(err) {
+			c.Recorder.Eventf(cd, corev1.EventTypeWarning, "ReconcileFailed", "Reconcile failed: %v", err)
+		}
+	}()
+	c.reconcileCounts.observe(req.NamespacedName, cd.Status.ReconcileCount)
+
+	// 用户修改了 spec（通常是在修复失败原因），清零之前累积的退避，失败时能尽快重试
+	if c.generations.changed(req.NamespacedName, cd.Generation) {
+		logger.V(1).Info("Generation changed, resetting reconcile backoff", "generation", cd.Generation)
+		if c.rateLimiter != nil {
+			c.rateLimiter.Forget(req)
+		}
+		if c.DeadLetter != nil {
+			c.DeadLetter.Reset(req.NamespacedName)
+		}
+	}
 
 	if cd.DeletionTimestamp.IsZero() {
 		if !controllerutil.ContainsFinalizer(cd, customDeploymentFinalizer) {
@@ -44,10 +161,26 @@ func (c *CustomDeploymentController) Reconcile(ctx context.Context, req ctrl.Req
 		}
 	} else {
 		if controllerutil.ContainsFinalizer(cd, customDeploymentFinalizer) {
-			deleted, err := c.handleDeletion(ctx, cd)
-			if err != nil {
-				logger.Error(err, "Failed to clean up Deployment before deletion")
-				return ctrl.Result{}, err
+			deleted, cleanupErr := c.handleDeletion(ctx, cd)
+			if cleanupErr != nil {
+				logger.Error(cleanupErr, "Failed to clean up Deployment before deletion")
+			}
+			// 清理无法完成时 finalizer 会让 CR 永远无法删除，namespace 正在删除或等待超时后强制移除
+			var requeueAfter time.Duration
+			if !deleted {
+				reason, wait, err := c.stuckFinalizer(ctx, cd)
+				if err != nil {
+					logger.Error(err, "Failed to check whether the finalizer is stuck")
+					return ctrl.Result{}, err
+				}
+				if reason != "" {
+					logger.Info("Warning: force-removing finalizer, the Deployment may be left behind", "reason", reason)
+					c.Recorder.Eventf(cd, corev1.EventTypeWarning, "FinalizerForceRemoved", "Removing finalizer without finishing cleanup: %s", reason)
+					deleted = true
+				} else if cleanupErr != nil {
+					return ctrl.Result{}, cleanupErr
+				}
+				requeueAfter = wait
 			}
 			if deleted {
 				controllerutil.RemoveFinalizer(cd, customDeploymentFinalizer)
@@ -55,62 +188,205 @@ func (c *CustomDeploymentController) Reconcile(ctx context.Context, req ctrl.Req
 					logger.Error(err, "Failed to remove finalizer")
 					return ctrl.Result{}, err
 				}
+				return ctrl.Result{}, nil
 			}
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
 		}
 
 		return ctrl.Result{}, nil
 	}
 
-	if err := c.handleCreateOrUpdate(ctx, cd); err != nil {
+	// spec 与依赖都没有变化且 Deployment 状态已同步时，跳过 Deployment 的写入；
+	// Service 等子资源和镜像 digest 仍然每次调谐，被删除或修改的子资源能够恢复
+	// 指纹只用于跳过写入，计算失败时走完整调谐，由 handleCreateOrUpdate 报告错误并设置条件
+	hash, inSync, err := c.specFingerprint(ctx, cd)
+	if err != nil {
+		logger.Error(err, "Failed to compute spec fingerprint")
+	}
+	if err == nil && inSync && hash != "" && cd.Annotations[specHashAnnotation] == hash {
+		logger.V(1).Info("Spec unchanged and Deployment in sync, skipping Deployment")
+		// 跳过的调谐同样计数，按节流条件写入 status.reconcileCount
+		if err := c.updateStatus(ctx, cd, cd.Status.DeepCopy()); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err := c.handleCreateOrUpdate(ctx, cd); err != nil {
 		logger.Error(err, "Failed to create or update Deployment")
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	if err := c.reconcileService(ctx, cd); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := c.reconcileIngress(ctx, cd); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := c.reconcileServiceMonitor(ctx, cd); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := c.recordImageDigest(ctx, cd); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// 所有子资源都处理成功后才认为该 generation 已被观察到
+	if cd.Status.ObservedGeneration != cd.Generation {
+		originalStatus := cd.Status.DeepCopy()
+		cd.Status.ObservedGeneration = cd.Generation
+		if err := c.updateStatus(ctx, cd, originalStatus); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := c.recordSpecHash(ctx, cd); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// 配置了 schedule 时在下一个窗口边界重新调谐
+	return ctrl.Result{RequeueAfter: c.scheduleRequeueAfter(cd)}, nil
 }
 
 func (c *CustomDeploymentController) handleCreateOrUpdate(ctx context.Context, cd *appsv1alpha1.CustomDeployment) error {
 	logger := log.FromContext(ctx)
+	if c.Scheme == nil {
+		return errNilScheme
+	}
+	originalStatus := cd.Status.DeepCopy()
+
+	// 违反策略时不写入 Deployment，只更新状态；用户修改 CR 之前重试没有意义
+	policyErr := c.checkImagePolicy(cd)
+	setPolicyCondition(cd, policyErr)
+	if policyErr != nil {
+		logger.Info("CustomDeployment violates policy, skipping Deployment", "reason", policyErr.Error())
+		return c.updateStatus(ctx, cd, originalStatus)
+	}
+
+	// spec.size 无法识别时同样只更新状态，等待用户修改
+	replicas, specErr := c.desiredReplicas(cd)
+	if specErr == nil {
+		specErr = validatePodAnnotations(cd)
+	}
+	if specErr == nil {
+		specErr = validateContainerPort(cd)
+	}
+	if specErr == nil {
+		specErr = validateContainerName(cd)
+	}
+	if specErr == nil {
+		specErr = validateService(cd)
+	}
+	if specErr == nil {
+		specErr = validateIngress(cd)
+	}
+	if specErr == nil {
+		specErr = validateServiceMonitor(cd)
+	}
+	setInvalidSpecCondition(cd, specErr)
+	if specErr == nil {
+		requested, _ := c.requestedReplicas(cd)
+		setReplicasAdjustedCondition(cd, requested)
+		setOverriddenCondition(cd)
+	}
+	if specErr != nil {
+		logger.Info("CustomDeployment has an invalid spec, skipping Deployment", "reason", specErr.Error())
+		c.Recorder.Eventf(cd, corev1.EventTypeWarning, "InvalidSpec", "Invalid spec: %v", specErr)
+		return c.updateStatus(ctx, cd, originalStatus)
+	}
+
+	// 默认的 nginx:latest 通常不是用户想要的，提醒用户显式指定镜像
+	if image, defaulted := imageOrDefault(cd); defaulted {
+		logger.Info("No image specified, falling back to the default image", "image", image)
+		c.Recorder.Eventf(cd, corev1.EventTypeWarning, "DefaultImage", "No image specified, using the default image %s; set spec.image", image)
+	}
+
+	desired, err := c.buildDeployment(ctx, cd, replicas)
+	if err != nil {
+		logger.Error(err, "Failed to build desired Deployment")
+		return err
+	}
+
 	deployName := cd.Name
 	deploy := &appsv1.Deployment{}
-	err := c.Get(ctx, types.NamespacedName{Name: deployName, Namespace: cd.Namespace}, deploy)
+	err = c.Get(ctx, types.NamespacedName{Name: deployName, Namespace: cd.Namespace}, deploy)
 	if err != nil && errors.IsNotFound(err) {
 		// 创建 Deployment
-		deploy = desiredDeployment(cd)
-		if err := ctrl.SetControllerReference(cd, deploy, c.Scheme); err != nil {
+		deploy = desired
+		if err := c.setOwner(cd, deploy); err != nil {
 			logger.Error(err, "Failed to set owner reference")
 			return err
 		}
 		if err := c.Create(ctx, deploy); err != nil {
 			logger.Error(err, "Failed to create Deployment")
+			setDeploymentErrorConditions(cd, "DeploymentCreateFailed", err)
+			if statusErr := c.updateStatus(ctx, cd, originalStatus); statusErr != nil {
+				logger.Error(statusErr, "Failed to update status")
+			}
 			return err
 		}
 		logger.Info("Deployment created successfully", "name", deploy.Name)
+		c.Recorder.Eventf(cd, corev1.EventTypeNormal, "CreatedDeployment", "Created Deployment %s", deploy.Name)
 	} else if err != nil {
 		logger.Error(err, "Failed to get Deployment")
+		setDeploymentErrorConditions(cd, "DeploymentFetchFailed", err)
+		if statusErr := c.updateStatus(ctx, cd, originalStatus); statusErr != nil {
+			logger.Error(statusErr, "Failed to update status")
+		}
 		return err
+	} else if !deploy.DeletionTimestamp.IsZero() {
+		// 正在为重建而删除，等删除完成后由 Owns 的事件触发重新创建
+		logger.Info("Deployment is being deleted, waiting before recreating it", "name", deploy.Name)
 	} else {
-		updated := false
-		if deploy.Spec.Replicas == nil || *deploy.Spec.Replicas != cd.Spec.Replicas {
-			deploy.Spec.Replicas = ptr.To(cd.Spec.Replicas)
-			updated = true
+		if oldName, renamed := renamedContainer(deploy, desired); renamed {
+			logger.Info("Container name changed, all pods will be replaced", "from", oldName, "to", containerName(cd))
 		}
-		if updated {
+		oldConfigHash := deploy.Spec.Template.Annotations[configHashAnnotation]
+		if syncDeploymentSpec(deploy, desired) {
 			if err := c.Update(ctx, deploy); err != nil {
+				if isImmutableFieldError(err) && allowRecreate(cd) {
+					return c.recreateDeployment(ctx, deploy)
+				}
 				logger.Error(err, "Failed to update Deployment")
 				return err
 			}
 
 			logger.Info("Deployment updated successfully", "name", deploy.Name)
+			c.Recorder.Eventf(cd, corev1.EventTypeNormal, "UpdatedDeployment", "Updated Deployment %s", deploy.Name)
+			c.recordConfigChange(cd, oldConfigHash, deploy.Spec.Template.Annotations[configHashAnnotation])
 		}
 	}
 
-	if cd.Status.AvailableReplicas != deploy.Status.AvailableReplicas {
+	cd.Status.Selector = metav1.FormatLabelSelector(deploy.Spec.Selector)
+	// 刚创建的 Deployment 还没有被 Deployment 控制器处理过，状态中的 0 只是“尚未上报”，
+	// 不写入 CR，等 Deployment 上报状态后由 Owns 的事件再次触发调谐
+	if deploymentStatusReported(deploy) {
 		cd.Status.AvailableReplicas = deploy.Status.AvailableReplicas
-		if err := c.Status().Update(ctx, cd); err != nil {
-			logger.Error(err, "Failed to update CustomDeployment status")
-			return err
-		}
+		cd.Status.Replicas = deploy.Status.Replicas
+		setRolloutConditions(cd, deploy)
+		setWorkloadConditions(cd, deploy, replicas)
+	}
+
+	problems, err := c.scaleToZeroProblems(ctx, cd, deploy)
+	if err != nil {
+		logger.Error(err, "Failed to check schedule compatibility")
+		return err
+	}
+	c.setScaleToZeroCondition(cd, problems)
+	return c.updateStatus(ctx, cd, originalStatus)
+}
+
+// updateStatus 仅在状态有变化时写回，避免无意义的更新
+func (c *CustomDeploymentController) updateStatus(ctx context.Context, cd *appsv1alpha1.CustomDeployment, original *appsv1alpha1.CustomDeploymentStatus) error {
+	cd.Status.ReconcileCount = c.reconcileCounts.statusValue(client.ObjectKeyFromObject(cd), original.ReconcileCount, c.now())
+	if equality.Semantic.DeepEqual(*original, cd.Status) {
+		return nil
+	}
+	if c.StatusBatcher != nil && c.StatusBatcher.Enqueue(cd) {
+		return nil
+	}
+	if err := c.Status().Update(ctx, cd); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to update CustomDeployment status")
+		return err
 	}
 	return nil
 }
@@ -139,31 +415,234 @@ func (c *CustomDeploymentController) handleDeletion(ctx context.Context, cd *app
 	return false, nil
 }
 
-func desiredDeployment(cd *appsv1alpha1.CustomDeployment) *appsv1.Deployment {
-	labels := map[string]string{
-		"app": cd.Name,
+// setOwner 把 CR 设置为 obj 的 controller owner
+func (c *CustomDeploymentController) setOwner(cd *appsv1alpha1.CustomDeployment, obj client.Object) error {
+	if c.Scheme == nil {
+		return errNilScheme
+	}
+	if err := ctrl.SetControllerReference(cd, obj, c.Scheme); err != nil {
+		return err
 	}
+	if c.NoBlockOwnerDeletion {
+		refs := obj.GetOwnerReferences()
+		for i := range refs {
+			if refs[i].UID == cd.UID {
+				refs[i].BlockOwnerDeletion = ptr.To(false)
+			}
+		}
+		obj.SetOwnerReferences(refs)
+	}
+	return nil
+}
+
+// buildDeployment 在 desiredDeployment 的基础上补充需要查询集群才能得到的内容
+func (c *CustomDeploymentController) buildDeployment(ctx context.Context, cd *appsv1alpha1.CustomDeployment, replicas int32) (*appsv1.Deployment, error) {
+	deploy := desiredDeployment(cd, c.selectorLabels(cd))
+	deploy.Spec.Replicas = ptr.To(replicas)
+	deploy.Spec.Template.Spec.Containers[0].Resources = c.containerResources(cd)
+	if err := c.applyConfigHash(ctx, cd, deploy); err != nil {
+		return nil, err
+	}
+	if err := c.applyPodDefaults(ctx, cd, deploy); err != nil {
+		return nil, err
+	}
+	applySpreadAcrossNodes(cd, deploy, c.selectorLabels(cd))
+	if c.PSADefaults {
+		applyPSADefaults(deploy)
+	}
+	return deploy, nil
+}
 
-	return &appsv1.Deployment{
+// imageOrDefault 返回 CR 使用的镜像，defaulted 表示 CR 没有指定镜像而使用了 DefaultImage
+func imageOrDefault(cd *appsv1alpha1.CustomDeployment) (image string, defaulted bool) {
+	if cd.Spec.Image != "" {
+		return cd.Spec.Image, false
+	}
+	return DefaultImage, true
+}
+
+func containerImage(cd *appsv1alpha1.CustomDeployment) string {
+	image, _ := imageOrDefault(cd)
+	return image
+}
+
+func desiredDeployment(cd *appsv1alpha1.CustomDeployment, labels map[string]string) *appsv1.Deployment {
+	// 模板是副本，后续写入的 config-hash 等注解不会改到 CR 上
+	template := basePodTemplate(cd, labels)
+	if len(cd.Spec.PodAnnotations) > 0 && template.Annotations == nil {
+		template.Annotations = make(map[string]string, len(cd.Spec.PodAnnotations))
+	}
+	for k, v := range cd.Spec.PodAnnotations {
+		template.Annotations[k] = v
+	}
+
+	// spec 中设置的字段覆盖模板中的值
+	pod := &template.Spec
+	if cd.Spec.TerminationGracePeriodSeconds != nil {
+		pod.TerminationGracePeriodSeconds = cd.Spec.TerminationGracePeriodSeconds
+	}
+	if cd.Spec.AutomountServiceAccountToken != nil {
+		pod.AutomountServiceAccountToken = cd.Spec.AutomountServiceAccountToken
+	}
+	if cd.Spec.RuntimeClassName != nil {
+		pod.RuntimeClassName = cd.Spec.RuntimeClassName
+	}
+
+	// 受管容器总是第一个容器
+	container, others := takeManagedContainer(pod, containerName(cd))
+	container.Image = containerImage(cd)
+	container.ImagePullPolicy = imagePullPolicy(cd, containerImage(cd))
+	if ports := containerPorts(cd); len(ports) > 0 {
+		container.Ports = ports
+	}
+	container.Env = mergeEnv(container.Env, containerEnv(cd))
+	applyProbes(cd, &container)
+	pod.Containers = append([]corev1.Container{container}, others...)
+
+	deploy := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      cd.Name,
 			Namespace: cd.Namespace,
 			Labels:    labels,
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: ptr.To(cd.Spec.Replicas),
+			Replicas: ptr.To(ptr.Deref(cd.Spec.Replicas, 0)),
 			Selector: &metav1.LabelSelector{MatchLabels: labels},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{Labels: labels},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "app",
-							Image: "nginx:latest",
-						},
-					},
-				},
-			},
+			Template: template,
 		},
 	}
+	if hash := templateHash(cd); hash != "" {
+		deploy.Annotations = map[string]string{templateHashAnnotation: hash}
+	}
+	return deploy
+}
+
+// syncDeploymentSpec 把期望的 Deployment 中由 CR 管理的字段同步到线上对象，返回是否有变化
+func syncDeploymentSpec(live, desired *appsv1.Deployment) bool {
+	updated := false
+	if live.Spec.Replicas == nil || *live.Spec.Replicas != *desired.Spec.Replicas {
+		live.Spec.Replicas = desired.Spec.Replicas
+		updated = true
+	}
+
+	// selector 不可修改，变化时 Update 会失败，需要通过 allow-recreate 注解重建
+	if !equality.Semantic.DeepEqual(live.Spec.Selector, desired.Spec.Selector) {
+		live.Spec.Selector = desired.Spec.Selector
+		live.Spec.Template.Labels = desired.Spec.Template.Labels
+		updated = true
+	}
+
+	// 先处理容器改名，后续按名称同步容器字段
+	if syncContainerName(live, desired) {
+		updated = true
+	}
+
+	if syncPodTemplate(live, desired) {
+		updated = true
+	}
+
+	livePod, desiredPod := &live.Spec.Template.Spec, &desired.Spec.Template.Spec
+	// 未设置时 API Server 会默认填充 30 秒，按默认值比较避免反复更新
+	if ptr.Deref(livePod.TerminationGracePeriodSeconds, corev1.DefaultTerminationGracePeriodSeconds) !=
+		ptr.Deref(desiredPod.TerminationGracePeriodSeconds, corev1.DefaultTerminationGracePeriodSeconds) {
+		livePod.TerminationGracePeriodSeconds = desiredPod.TerminationGracePeriodSeconds
+		updated = true
+	}
+	if !ptr.Equal(livePod.AutomountServiceAccountToken, desiredPod.AutomountServiceAccountToken) {
+		livePod.AutomountServiceAccountToken = desiredPod.AutomountServiceAccountToken
+		updated = true
+	}
+	if !ptr.Equal(livePod.RuntimeClassName, desiredPod.RuntimeClassName) {
+		livePod.RuntimeClassName = desiredPod.RuntimeClassName
+		updated = true
+	}
+
+	if live.Labels[configChecksumLabel] != desired.Labels[configChecksumLabel] {
+		if desired.Labels[configChecksumLabel] == "" {
+			delete(live.Labels, configChecksumLabel)
+		} else {
+			if live.Labels == nil {
+				live.Labels = map[string]string{}
+			}
+			live.Labels[configChecksumLabel] = desired.Labels[configChecksumLabel]
+		}
+		updated = true
+	}
+
+	// 只比较控制器管理的注解，保留 kubectl rollout restart 等写入的其他注解
+	liveHash := live.Spec.Template.Annotations[configHashAnnotation]
+	desiredHash := desired.Spec.Template.Annotations[configHashAnnotation]
+	if liveHash != desiredHash {
+		if desiredHash == "" {
+			delete(live.Spec.Template.Annotations, configHashAnnotation)
+		} else {
+			if live.Spec.Template.Annotations == nil {
+				live.Spec.Template.Annotations = map[string]string{}
+			}
+			live.Spec.Template.Annotations[configHashAnnotation] = desiredHash
+		}
+		updated = true
+	}
+
+	if syncPodAnnotations(live, desired) {
+		updated = true
+	}
+
+	if syncContainerResources(live, desired) {
+		updated = true
+	}
+
+	if syncImage(live, desired) {
+		updated = true
+	}
+
+	if syncContainerPorts(live, desired) {
+		updated = true
+	}
+
+	if syncEnv(live, desired) {
+		updated = true
+	}
+
+	if syncProbes(live, desired) {
+		updated = true
+	}
+
+	if syncPodScheduling(live, desired) {
+		updated = true
+	}
+
+	if syncSecurityContext(live, desired) {
+		updated = true
+	}
+	return updated
+}
+
+func (c *CustomDeploymentController) SetupWithManager(mgr ctrl.Manager) error {
+	if c.Scheme == nil {
+		return errNilScheme
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &appsv1alpha1.CustomDeployment{}, configFromIndex, indexConfigFrom); err != nil {
+		return err
+	}
+
+	// 与 controller-runtime 默认的限速器相同，自己持有以便在 spec 变化时重置退避
+	c.rateLimiter = workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]()
+
+	opts := controller.Options{RateLimiter: c.rateLimiter}
+	if c.BacklogAlarm != nil {
+		opts.NewQueue = c.BacklogAlarm.wrapQueue()
+		if err := mgr.Add(c.BacklogAlarm); err != nil {
+			return err
+		}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&appsv1alpha1.CustomDeployment{}).
+		WithOptions(opts).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Owns(&networkingv1.Ingress{}).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(c.configMapToCustomDeployments)).
+		Complete(c)
 }
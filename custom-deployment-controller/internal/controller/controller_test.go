@@ -0,0 +1,326 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestReconcileTerminationGracePeriod(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial *int64
+		updated *int64
+	}{
+		{"unset", nil, nil},
+		{"set on create", ptr.To[int64](60), ptr.To[int64](60)},
+		{"changed", ptr.To[int64](60), ptr.To[int64](10)},
+		{"set later", nil, ptr.To[int64](45)},
+		{"removed", ptr.To[int64](60), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.TerminationGracePeriodSeconds = tt.initial
+			})})
+			deploy := env.reconcileUntilCreated(t, "web")
+			if got := deploy.Spec.Template.Spec.TerminationGracePeriodSeconds; !ptr.Equal(got, tt.initial) {
+				t.Fatalf("after create: terminationGracePeriodSeconds = %v, want %v", ptr.Deref(got, -1), ptr.Deref(tt.initial, -1))
+			}
+
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.TerminationGracePeriodSeconds = tt.updated
+			})
+			env.reconcile(t, "web")
+			if got := env.deployment(t, "web").Spec.Template.Spec.TerminationGracePeriodSeconds; !ptr.Equal(got, tt.updated) {
+				t.Fatalf("after update: terminationGracePeriodSeconds = %v, want %v", ptr.Deref(got, -1), ptr.Deref(tt.updated, -1))
+			}
+		})
+	}
+}
+
+func TestReconcileBlockOwnerDeletion(t *testing.T) {
+	tests := []struct {
+		name                 string
+		noBlockOwnerDeletion bool
+		want                 bool
+	}{
+		{"default", false, true},
+		{"disabled by flag", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.UID = "web-uid"
+				cd.Spec.ExposeService = true
+				cd.Spec.ServicePort = 80
+			})})
+			env.c.NoBlockOwnerDeletion = tt.noBlockOwnerDeletion
+			deploy := env.reconcileUntilCreated(t, "web")
+			svc := &corev1.Service{}
+			if err := env.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "web"}, svc); err != nil {
+				t.Fatalf("get Service: %v", err)
+			}
+
+			for kind, refs := range map[string][]metav1.OwnerReference{
+				"Deployment": deploy.OwnerReferences,
+				"Service":    svc.OwnerReferences,
+			} {
+				if len(refs) != 1 || refs[0].UID != "web-uid" {
+					t.Fatalf("%s owner references = %v, want one pointing at the CR", kind, refs)
+				}
+				if got := ptr.Deref(refs[0].BlockOwnerDeletion, false); got != tt.want {
+					t.Fatalf("%s blockOwnerDeletion = %v, want %v", kind, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileSelectorLabelKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantKey string
+	}{
+		{"default key", "", DefaultSelectorLabelKey},
+		{"configured key", "app.kubernetes.io/name", "app.kubernetes.io/name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.ExposeService = true
+				cd.Spec.ServicePort = 80
+				cd.Spec.SpreadAcrossNodes = true
+			})})
+			env.c.SelectorLabelKey = tt.key
+			deploy := env.reconcileUntilCreated(t, "web")
+			want := map[string]string{tt.wantKey: "web"}
+
+			if got := deploy.Spec.Selector.MatchLabels; !maps.Equal(got, want) {
+				t.Errorf("Deployment selector = %v, want %v", got, want)
+			}
+			if got := deploy.Spec.Template.Labels[tt.wantKey]; got != "web" {
+				t.Errorf("pod label %s = %q, want web", tt.wantKey, got)
+			}
+			terms := deploy.Spec.Template.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+			if len(terms) != 1 || !maps.Equal(terms[0].PodAffinityTerm.LabelSelector.MatchLabels, want) {
+				t.Errorf("anti-affinity terms = %v, want selector %v", terms, want)
+			}
+			svc := &corev1.Service{}
+			if err := env.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "web"}, svc); err != nil {
+				t.Fatalf("get Service: %v", err)
+			}
+			if !maps.Equal(svc.Spec.Selector, want) {
+				t.Errorf("Service selector = %v, want %v", svc.Spec.Selector, want)
+			}
+			if got := env.customDeployment(t, "web").Status.Selector; got != tt.wantKey+"=web" {
+				t.Errorf("status.selector = %q, want %q", got, tt.wantKey+"=web")
+			}
+		})
+	}
+}
+
+func TestReconcileStatusSelector(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		mutate func(*appsv1.Deployment)
+		want   string
+	}{
+		{name: "formatted from the Deployment selector", want: "app=web"},
+		{name: "follows a selector key change", key: "app.kubernetes.io/name", want: "app.kubernetes.io/name=web"},
+		{
+			// 控制器把 selector 恢复为期望值，状态跟随恢复后的 Deployment
+			name: "follows the reverted selector after drift",
+			mutate: func(d *appsv1.Deployment) {
+				d.Spec.Selector.MatchExpressions = []metav1.LabelSelectorRequirement{
+					{Key: "track", Operator: metav1.LabelSelectorOpIn, Values: []string{"stable"}},
+				}
+			},
+			want: "app=web",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				// 旧值需要被纠正
+				cd.Status.Selector = "stale=true"
+			})})
+			deploy := env.reconcileUntilCreated(t, "web")
+			if got := env.customDeployment(t, "web").Status.Selector; got != "app=web" {
+				t.Fatalf("status.selector = %q, want app=web", got)
+			}
+
+			env.c.SelectorLabelKey = tt.key
+			if tt.mutate != nil {
+				// 修改 spec 时 API Server 会增加 generation，fake client 不会
+				tt.mutate(deploy)
+				deploy.Generation++
+				if err := env.c.Update(context.Background(), deploy); err != nil {
+					t.Fatal(err)
+				}
+			}
+			env.reconcile(t, "web")
+
+			if got := env.customDeployment(t, "web").Status.Selector; got != tt.want {
+				t.Fatalf("status.selector = %q, want %q", got, tt.want)
+			}
+			if got := metav1.FormatLabelSelector(env.deployment(t, "web").Spec.Selector); got != tt.want {
+				t.Fatalf("Deployment selector = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileAutomountServiceAccountToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial *bool
+		updated *bool
+	}{
+		{"unset", nil, nil},
+		{"false on create", ptr.To(false), ptr.To(false)},
+		{"true on create", ptr.To(true), ptr.To(true)},
+		{"disabled later", nil, ptr.To(false)},
+		{"changed", ptr.To(false), ptr.To(true)},
+		{"removed", ptr.To(false), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.AutomountServiceAccountToken = tt.initial
+			})})
+			deploy := env.reconcileUntilCreated(t, "web")
+			if got := deploy.Spec.Template.Spec.AutomountServiceAccountToken; !ptr.Equal(got, tt.initial) {
+				t.Fatalf("initial automountServiceAccountToken = %v, want %v", ptr.Deref(got, true), ptr.Deref(tt.initial, true))
+			}
+
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.AutomountServiceAccountToken = tt.updated
+			})
+			env.reconcile(t, "web")
+			if got := env.deployment(t, "web").Spec.Template.Spec.AutomountServiceAccountToken; !ptr.Equal(got, tt.updated) {
+				t.Fatalf("updated automountServiceAccountToken = %v, want %v", ptr.Deref(got, true), ptr.Deref(tt.updated, true))
+			}
+		})
+	}
+}
+
+func TestReconcileRuntimeClassName(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial *string
+		updated *string
+	}{
+		{"unset", nil, nil},
+		{"set on create", ptr.To("gvisor"), ptr.To("gvisor")},
+		{"changed", ptr.To("gvisor"), ptr.To("nvidia")},
+		{"set later", nil, ptr.To("kata")},
+		{"removed", ptr.To("gvisor"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.RuntimeClassName = tt.initial
+			})})
+			deploy := env.reconcileUntilCreated(t, "web")
+			if got := deploy.Spec.Template.Spec.RuntimeClassName; !ptr.Equal(got, tt.initial) {
+				t.Fatalf("after create: runtimeClassName = %q, want %q", ptr.Deref(got, ""), ptr.Deref(tt.initial, ""))
+			}
+
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.RuntimeClassName = tt.updated
+			})
+			env.reconcile(t, "web")
+			if got := env.deployment(t, "web").Spec.Template.Spec.RuntimeClassName; !ptr.Equal(got, tt.updated) {
+				t.Fatalf("after update: runtimeClassName = %q, want %q", ptr.Deref(got, ""), ptr.Deref(tt.updated, ""))
+			}
+		})
+	}
+}
+
+func TestReconcileEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		createErr error
+		update    bool
+		want      []string
+		wantNone  []string
+	}{
+		{name: "created", want: []string{"Normal CreatedDeployment Created Deployment web"}, wantNone: []string{"UpdatedDeployment", "ReconcileFailed"}},
+		{name: "updated", update: true, want: []string{"Normal UpdatedDeployment Updated Deployment web"}},
+		{name: "create failed", createErr: apierrors.NewForbidden(appsv1.Resource("deployments"), "web", errors.New("quota exceeded")), want: []string{"Warning ReconcileFailed"}, wantNone: []string{"CreatedDeployment"}},
+		// 冲突会立即重试，不记录事件
+		{name: "conflict", createErr: apierrors.NewConflict(appsv1.Resource("deployments"), "web", errors.New("modified")), wantNone: []string{"ReconcileFailed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web")}, withInterceptor(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					if _, ok := obj.(*appsv1.Deployment); ok && tt.createErr != nil {
+						return tt.createErr
+					}
+					return c.Create(ctx, obj, opts...)
+				},
+			}))
+			env.reconcile(t, "web")
+			_, err := env.c.Reconcile(context.Background(), requestFor("web"))
+			if !errors.Is(err, tt.createErr) {
+				t.Fatalf("Reconcile error = %v, want %v", err, tt.createErr)
+			}
+			if tt.update {
+				env.events()
+				env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+					cd.Spec.Image = "registry.example.com/app:v2"
+				})
+				env.reconcile(t, "web")
+			}
+
+			events := env.events()
+			for _, want := range tt.want {
+				if !slices.ContainsFunc(events, func(e string) bool { return strings.HasPrefix(e, want) }) {
+					t.Errorf("events = %v, want one starting with %q", events, want)
+				}
+			}
+			for _, reason := range tt.wantNone {
+				if containsEvent(events, reason) {
+					t.Errorf("events = %v, want no %s event", events, reason)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileNilScheme(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+	env.c.Scheme = nil
+
+	// 第一次调谐只添加 finalizer
+	env.reconcile(t, "web")
+	_, err := env.c.Reconcile(context.Background(), requestFor("web"))
+	if !errors.Is(err, errNilScheme) {
+		t.Fatalf("Reconcile error = %v, want %v", err, errNilScheme)
+	}
+	if err := env.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "web"}, &appsv1.Deployment{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("get Deployment: %v, want NotFound", err)
+	}
+	// 在使用 manager 之前就返回错误
+	if err := env.c.SetupWithManager(nil); !errors.Is(err, errNilScheme) {
+		t.Fatalf("SetupWithManager error = %v, want %v", err, errNilScheme)
+	}
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// deadLetterEntry 是死信 ConfigMap 中每个对象对应的记录
+type deadLetterEntry struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Failures  int       `json:"failures"`
+	LastError string    `json:"lastError"`
+	Time      time.Time `json:"time"`
+}
+
+// DeadLetterRecorder 把连续失败超过阈值的对象记录到控制器所在 namespace 的 ConfigMap 中，
+// 作为事后分析用的持久化失败列表。记录数超过 MaxEntries 时淘汰最旧的记录。
+type DeadLetterRecorder struct {
+	Client    client.Client
+	Namespace string
+	Name      string
+
+	// After 是写入死信记录前允许的连续失败次数
+	After int
+	// MaxEntries 是 ConfigMap 中最多保留的记录数
+	MaxEntries int
+
+	mu       sync.Mutex
+	failures map[types.NamespacedName]int
+}
+
+// Observe 记录一次调谐结果：成功时清零失败计数，连续失败达到 After 次后写入死信记录。
+// 写入失败只记录日志，不影响调谐本身。
+func (d *DeadLetterRecorder) Observe(ctx context.Context, key types.NamespacedName, reconcileErr error) {
+	d.mu.Lock()
+	if d.failures == nil {
+		d.failures = map[types.NamespacedName]int{}
+	}
+	if reconcileErr == nil {
+		delete(d.failures, key)
+		d.mu.Unlock()
+		return
+	}
+	d.failures[key]++
+	failures := d.failures[key]
+	d.mu.Unlock()
+
+	if failures < d.After {
+		return
+	}
+	entry := deadLetterEntry{
+		Namespace: key.Namespace,
+		Name:      key.Name,
+		Failures:  failures,
+		LastError: reconcileErr.Error(),
+		Time:      time.Now().UTC(),
+	}
+	if err := d.record(ctx, entry); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to write dead-letter record", "configmap", d.Name)
+	}
+}
+
+// Reset 清零对象的连续失败计数
+func (d *DeadLetterRecorder) Reset(key types.NamespacedName) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.failures, key)
+}
+
+func (d *DeadLetterRecorder) record(ctx context.Context, entry deadLetterEntry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	// ConfigMap 的 key 只能包含字母数字和 -._，用 _ 分隔 namespace 和 name
+	dataKey := entry.Namespace + "_" + entry.Name
+
+	cm := &corev1.ConfigMap{}
+	err = d.Client.Get(ctx, types.NamespacedName{Namespace: d.Namespace, Name: d.Name}, cm)
+	if errors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: d.Namespace, Name: d.Name},
+			Data:       map[string]string{dataKey: string(value)},
+		}
+		return d.Client.Create(ctx, cm)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[dataKey] = string(value)
+	trimDeadLetters(cm.Data, d.MaxEntries)
+	return d.Client.Update(ctx, cm)
+}
+
+// trimDeadLetters 按记录时间淘汰最旧的记录，直到不超过 max 条
+func trimDeadLetters(data map[string]string, max int) {
+	if max <= 0 || len(data) <= max {
+		return
+	}
+	type keyed struct {
+		key  string
+		time time.Time
+	}
+	entries := make([]keyed, 0, len(data))
+	for k, v := range data {
+		var e deadLetterEntry
+		_ = json.Unmarshal([]byte(v), &e)
+		entries = append(entries, keyed{key: k, time: e.Time})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].time.Before(entries[j].time) })
+	for _, e := range entries[:len(entries)-max] {
+		delete(data, e.key)
+	}
+}
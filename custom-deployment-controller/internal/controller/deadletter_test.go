@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestDeadLetterRecordsRepeatedFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		wantEntry bool
+	}{
+		{"below the threshold", 2, false},
+		{"at the threshold", 3, true},
+		{"above the threshold", 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web")}, withInterceptor(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					if _, ok := obj.(*appsv1.Deployment); ok {
+						return errors.New("quota exceeded")
+					}
+					return c.Create(ctx, obj, opts...)
+				},
+			}))
+			env.c.DeadLetter = &DeadLetterRecorder{Client: env.c.Client, Namespace: testNamespace, Name: "dead-letters", After: 3, MaxEntries: 10}
+			env.reconcile(t, "web") // 添加 finalizer
+
+			for i := 0; i < tt.failures; i++ {
+				if _, err := env.c.Reconcile(context.Background(), requestFor("web")); err == nil {
+					t.Fatal("expected the reconcile to fail")
+				}
+			}
+
+			cm := &corev1.ConfigMap{}
+			err := env.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "dead-letters"}, cm)
+			if !tt.wantEntry {
+				if !apierrors.IsNotFound(err) {
+					t.Fatalf("expected no dead-letter record, got %v (err %v)", cm.Data, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("get dead-letter ConfigMap: %v", err)
+			}
+			var entry deadLetterEntry
+			if err := json.Unmarshal([]byte(cm.Data[testNamespace+"_web"]), &entry); err != nil {
+				t.Fatalf("decode entry %q: %v", cm.Data[testNamespace+"_web"], err)
+			}
+			if entry.Failures != tt.failures || entry.Name != "web" || entry.Namespace != testNamespace {
+				t.Fatalf("entry = %+v, want %d failures of %s/web", entry, tt.failures, testNamespace)
+			}
+			if entry.LastError == "" {
+				t.Fatal("expected the last error to be recorded")
+			}
+		})
+	}
+}
+
+func TestDeadLetterResetOnSuccess(t *testing.T) {
+	cl := newTestEnv(t, nil).c.Client
+	d := &DeadLetterRecorder{Client: cl, Namespace: testNamespace, Name: "dead-letters", After: 2}
+	key := types.NamespacedName{Namespace: testNamespace, Name: "web"}
+	fail := errors.New("boom")
+
+	d.Observe(context.Background(), key, fail)
+	d.Observe(context.Background(), key, nil)
+	d.Observe(context.Background(), key, fail)
+	if err := cl.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "dead-letters"}, &corev1.ConfigMap{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected a success to reset the failure count, got err %v", err)
+	}
+}
+
+func TestTrimDeadLetters(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := map[string]string{}
+	for i := 0; i < 5; i++ {
+		value, _ := json.Marshal(deadLetterEntry{Name: fmt.Sprint(i), Time: base.Add(time.Duration(i) * time.Minute)})
+		data[fmt.Sprintf("ns_%d", i)] = string(value)
+	}
+	trimDeadLetters(data, 3)
+	for _, key := range []string{"ns_2", "ns_3", "ns_4"} {
+		if _, ok := data[key]; !ok {
+			t.Errorf("expected newest entry %s to be kept", key)
+		}
+	}
+	if len(data) != 3 {
+		t.Fatalf("kept %d entries, want 3", len(data))
+	}
+}
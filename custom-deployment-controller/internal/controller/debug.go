@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// objectDump 是 /debug/object 返回的控制器视角：CR 的 spec、控制器将要生成的 Deployment、
+// 线上的 Deployment，以及把线上对象同步到期望状态时会产生的补丁
+type objectDump struct {
+	Spec    appsv1alpha1.CustomDeploymentSpec `json:"spec"`
+	Desired *appsv1.Deployment                `json:"desired,omitempty"`
+	Live    *appsv1.Deployment                `json:"live,omitempty"`
+	Diff    json.RawMessage                   `json:"diff,omitempty"`
+	Error   string                            `json:"error,omitempty"`
+}
+
+// DebugHandler 返回只读的调试接口，GET /debug/object?ns=x&name=y 输出单个对象的 objectDump。
+// 只读取缓存，不会写入任何对象，也不会记录事件
+func (c *CustomDeploymentController) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key := types.NamespacedName{Namespace: r.URL.Query().Get("ns"), Name: r.URL.Query().Get("name")}
+		if key.Namespace == "" || key.Name == "" {
+			http.Error(w, "ns and name are required", http.StatusBadRequest)
+			return
+		}
+
+		ctx := r.Context()
+		cd := &appsv1alpha1.CustomDeployment{}
+		if err := c.Get(ctx, key, cd); err != nil {
+			status := http.StatusInternalServerError
+			if errors.IsNotFound(err) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		dump := objectDump{Spec: cd.Spec}
+		live := &appsv1.Deployment{}
+		if err := c.Get(ctx, key, live); err == nil {
+			dump.Live = live
+		} else if !errors.IsNotFound(err) {
+			dump.Error = err.Error()
+		}
+
+		replicas, err := c.desiredReplicas(cd)
+		if err == nil {
+			dump.Desired, err = c.buildDeployment(ctx, cd, replicas)
+		}
+		if err != nil {
+			dump.Error = err.Error()
+		}
+
+		if dump.Live != nil && dump.Desired != nil {
+			synced := dump.Live.DeepCopy()
+			syncDeploymentSpec(synced, dump.Desired)
+			diff, err := deploymentDiff(dump.Live, synced)
+			if err != nil {
+				dump.Error = err.Error()
+			}
+			dump.Diff = diff
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(dump)
+	})
+}
+
+// deploymentDiff 返回从 live 到 synced 的策略合并补丁，没有差异时为 {}
+func deploymentDiff(live, synced *appsv1.Deployment) (json.RawMessage, error) {
+	original, err := json.Marshal(live)
+	if err != nil {
+		return nil, err
+	}
+	modified, err := json.Marshal(synced)
+	if err != nil {
+		return nil, err
+	}
+	return strategicpatch.CreateTwoWayMergePatch(original, modified, appsv1.Deployment{})
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestDebugHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		changeSpec bool
+		wantStatus int
+		wantDiff   string
+	}{
+		{name: "diff after a spec change", query: "ns=default&name=web", changeSpec: true, wantStatus: http.StatusOK, wantDiff: "registry.example.com/app:v2"},
+		{name: "no diff when in sync", query: "ns=default&name=web", wantStatus: http.StatusOK, wantDiff: "{}"},
+		{name: "unknown object", query: "ns=default&name=absent", wantStatus: http.StatusNotFound},
+		{name: "missing name", query: "ns=default", wantStatus: http.StatusBadRequest},
+		{name: "read only", method: http.MethodPost, query: "ns=default&name=web", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+			env.reconcileUntilCreated(t, "web")
+			if tt.changeSpec {
+				env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) { cd.Spec.Image = "registry.example.com/app:v2" })
+			}
+			env.writes.reset()
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			env.c.DebugHandler().ServeHTTP(rec, httptest.NewRequest(method, "/debug/object?"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if n := env.writes.total(); n != 0 {
+				t.Fatalf("debug endpoint made %d writes, want none", n)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var dump objectDump
+			if err := json.Unmarshal(rec.Body.Bytes(), &dump); err != nil {
+				t.Fatal(err)
+			}
+			if dump.Desired == nil || dump.Live == nil {
+				t.Fatalf("dump = %s, want both desired and live Deployments", rec.Body.String())
+			}
+			if diff := string(dump.Diff); !strings.Contains(diff, tt.wantDiff) {
+				t.Fatalf("diff = %s, want it to contain %s", diff, tt.wantDiff)
+			}
+		})
+	}
+}
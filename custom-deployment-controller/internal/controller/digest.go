@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"context"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// resolvedDigestAnnotation 记录容器镜像解析出的 digest，方便直接在 CR 上看到实际运行的镜像
+const resolvedDigestAnnotation = "apps.myorg.io/resolved-image-digest"
+
+// ImageResolver 把镜像引用（tag 或 digest 形式）解析为不可变的 digest，如 "sha256:..."
+type ImageResolver interface {
+	Resolve(ctx context.Context, image string) (string, error)
+}
+
+// recordImageDigest 在 CR 上记录镜像当前解析出的 digest，tag 指向新 digest 时随之更新。
+// 未配置 Resolver 或镜像违反仓库白名单时不做任何事情，不访问被禁止的仓库；解析失败只记录日志，不影响 Deployment 的调谐。
+func (c *CustomDeploymentController) recordImageDigest(ctx context.Context, cd *appsv1alpha1.CustomDeployment) error {
+	if c.Resolver == nil || c.checkImagePolicy(cd) != nil {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	image := containerImage(cd)
+	digest, err := c.Resolver.Resolve(ctx, image)
+	if err != nil {
+		logger.Error(err, "Failed to resolve image digest", "image", image)
+		return nil
+	}
+	if cd.Annotations[resolvedDigestAnnotation] == digest {
+		return nil
+	}
+
+	patch := client.MergeFrom(cd.DeepCopy())
+	if cd.Annotations == nil {
+		cd.Annotations = map[string]string{}
+	}
+	cd.Annotations[resolvedDigestAnnotation] = digest
+	if err := c.Patch(ctx, cd, patch); err != nil {
+		logger.Error(err, "Failed to record resolved image digest")
+		return err
+	}
+	logger.Info("Resolved image digest recorded", "image", image, "digest", digest)
+	return nil
+}
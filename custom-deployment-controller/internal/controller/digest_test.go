@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// stubResolver 按镜像返回预设的 digest
+type stubResolver struct {
+	digests map[string]string
+	calls   int
+}
+
+func (s *stubResolver) Resolve(_ context.Context, image string) (string, error) {
+	s.calls++
+	return s.digests[image], nil
+}
+
+func TestRecordImageDigestTracksResolver(t *testing.T) {
+	cd := newCustomDeployment("web")
+	env := newTestEnv(t, []client.Object{cd})
+	resolver := &stubResolver{digests: map[string]string{cd.Spec.Image: "sha256:1111"}}
+	env.c.Resolver = resolver
+
+	env.reconcileUntilCreated(t, "web")
+	if got := env.customDeployment(t, "web").Annotations[resolvedDigestAnnotation]; got != "sha256:1111" {
+		t.Fatalf("digest annotation = %q, want sha256:1111", got)
+	}
+
+	// tag 指向了新的 digest，spec 没有变化
+	resolver.digests[cd.Spec.Image] = "sha256:2222"
+	env.reconcile(t, "web")
+	if got := env.customDeployment(t, "web").Annotations[resolvedDigestAnnotation]; got != "sha256:2222" {
+		t.Fatalf("digest annotation = %q, want sha256:2222", got)
+	}
+}
+
+func TestRecordImageDigestSkipsDisallowedRegistry(t *testing.T) {
+	cd := newCustomDeployment("web")
+	env := newTestEnv(t, []client.Object{cd})
+	resolver := &stubResolver{digests: map[string]string{cd.Spec.Image: "sha256:1111"}}
+	env.c.Resolver = resolver
+	env.c.AllowedRegistries = []string{"registry.local"}
+
+	env.reconcile(t, "web")
+	if resolver.calls != 0 {
+		t.Fatalf("resolver was called %d times for an image from a disallowed registry", resolver.calls)
+	}
+	if got, ok := env.customDeployment(t, "web").Annotations[resolvedDigestAnnotation]; ok {
+		t.Fatalf("unexpected digest annotation %q", got)
+	}
+}
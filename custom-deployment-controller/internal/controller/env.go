@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// containerEnv 返回受管容器的环境变量。fieldRef 的 apiVersion 会被 API Server 默认填充为 v1，
+// 这里提前填上，避免与线上对象比较时反复更新
+func containerEnv(cd *appsv1alpha1.CustomDeployment) []corev1.EnvVar {
+	if len(cd.Spec.Env) == 0 {
+		return nil
+	}
+	env := make([]corev1.EnvVar, len(cd.Spec.Env))
+	for i := range cd.Spec.Env {
+		cd.Spec.Env[i].DeepCopyInto(&env[i])
+		if ref := env[i].ValueFrom; ref != nil && ref.FieldRef != nil && ref.FieldRef.APIVersion == "" {
+			ref.FieldRef.APIVersion = "v1"
+		}
+	}
+	return env
+}
+
+// envEqual 按名称比较两组环境变量，忽略顺序
+func envEqual(a, b []corev1.EnvVar) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	byName := make(map[string]corev1.EnvVar, len(a))
+	for _, e := range a {
+		byName[e.Name] = e
+	}
+	for _, e := range b {
+		other, ok := byName[e.Name]
+		if !ok || !equality.Semantic.DeepEqual(e, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// syncEnv 同步受管容器的环境变量，返回是否有变化
+func syncEnv(live, desired *appsv1.Deployment) bool {
+	liveContainer, desiredContainer := managedContainers(live, desired)
+	if liveContainer == nil || desiredContainer == nil {
+		return false
+	}
+	if envEqual(liveContainer.Env, desiredContainer.Env) {
+		return false
+	}
+	liveContainer.Env = desiredContainer.Env
+	return true
+}
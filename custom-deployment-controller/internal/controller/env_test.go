@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileEnv(t *testing.T) {
+	logLevel := corev1.EnvVar{Name: "LOG_LEVEL", Value: "info"}
+	region := corev1.EnvVar{Name: "REGION", Value: "eu-west-1"}
+	tests := []struct {
+		name        string
+		updated     []corev1.EnvVar
+		wantUpdates int
+	}{
+		{name: "one removed", updated: []corev1.EnvVar{logLevel}, wantUpdates: 1},
+		{name: "value changed", updated: []corev1.EnvVar{logLevel, {Name: "REGION", Value: "us-east-1"}}, wantUpdates: 1},
+		{name: "all removed", updated: nil, wantUpdates: 1},
+		// 只调换顺序不应触发更新
+		{name: "reordered", updated: []corev1.EnvVar{region, logLevel}, wantUpdates: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.Env = []corev1.EnvVar{logLevel, region}
+			})})
+			deploy := env.reconcileUntilCreated(t, "web")
+			if got := deploy.Spec.Template.Spec.Containers[0].Env; !envEqual(got, []corev1.EnvVar{logLevel, region}) {
+				t.Fatalf("after create: env = %v, want LOG_LEVEL and REGION", got)
+			}
+			env.writes.reset()
+
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.Env = tt.updated
+			})
+			env.reconcile(t, "web")
+
+			if got := env.deployment(t, "web").Spec.Template.Spec.Containers[0].Env; !envEqual(got, tt.updated) {
+				t.Fatalf("after update: env = %v, want %v", got, tt.updated)
+			}
+			if got := env.writes.get("update/Deployment"); got != tt.wantUpdates {
+				t.Fatalf("Deployment updates = %d, want %d", got, tt.wantUpdates)
+			}
+		})
+	}
+}
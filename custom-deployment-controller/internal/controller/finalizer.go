@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// stuckFinalizer 判断正在删除的 CR 的 finalizer 是否应该被强制移除，返回原因；
+// 不需要移除时 requeueAfter 是距离超时还剩的时间（没有配置 FinalizerTimeout 时为 0）。
+// namespace 正在删除时清理可能永远无法完成（如 Deployment 的 finalizer 依赖已被删除的组件），直接移除
+func (c *CustomDeploymentController) stuckFinalizer(ctx context.Context, cd *appsv1alpha1.CustomDeployment) (reason string, requeueAfter time.Duration, err error) {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: cd.Namespace}, ns); client.IgnoreNotFound(err) != nil {
+		return "", 0, err
+	} else if err == nil && !ns.DeletionTimestamp.IsZero() {
+		return fmt.Sprintf("namespace %s is terminating", cd.Namespace), 0, nil
+	}
+
+	if c.FinalizerTimeout <= 0 {
+		return "", 0, nil
+	}
+	elapsed := c.now().Sub(cd.DeletionTimestamp.Time)
+	if elapsed >= c.FinalizerTimeout {
+		return fmt.Sprintf("cleanup did not finish within %s", c.FinalizerTimeout), 0, nil
+	}
+	return "", c.FinalizerTimeout - elapsed, nil
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	testingclock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileStuckFinalizer(t *testing.T) {
+	tests := []struct {
+		name            string
+		timeout         time.Duration
+		nsTerminating   bool
+		after           time.Duration
+		wantRemoved     bool
+		wantRequeue     time.Duration
+		later           time.Duration
+		wantLaterRemove bool
+	}{
+		{
+			name:            "removed after the timeout",
+			timeout:         5 * time.Minute,
+			after:           time.Minute,
+			wantRequeue:     4 * time.Minute,
+			later:           6 * time.Minute,
+			wantLaterRemove: true,
+		},
+		{name: "namespace terminating", nsTerminating: true, wantRemoved: true},
+		// 没有配置超时时一直等待清理完成
+		{name: "no timeout", after: time.Hour, later: 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNamespace}}
+			if tt.nsTerminating {
+				ns.Finalizers = []string{"kubernetes"}
+				ns.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			}
+			env := newTestEnv(t, []client.Object{ns, newCustomDeployment("web")})
+			env.c.FinalizerTimeout = tt.timeout
+			deploy := env.reconcileUntilCreated(t, "web")
+
+			// Deployment 上的其他 finalizer 让清理永远无法完成
+			deploy.Finalizers = append(deploy.Finalizers, "example.com/block")
+			if err := env.c.Update(context.Background(), deploy); err != nil {
+				t.Fatal(err)
+			}
+			if err := env.c.Delete(context.Background(), env.customDeployment(t, "web")); err != nil {
+				t.Fatal(err)
+			}
+			deletedAt := env.customDeployment(t, "web").DeletionTimestamp.Time
+			clock := testingclock.NewFakePassiveClock(deletedAt.Add(tt.after))
+			env.c.Clock = clock
+
+			result := env.reconcile(t, "web")
+			if removed := cdGone(t, env); removed != tt.wantRemoved {
+				t.Fatalf("finalizer removed = %v, want %v", removed, tt.wantRemoved)
+			}
+			if tt.wantRemoved {
+				if !containsEvent(env.events(), "FinalizerForceRemoved") {
+					t.Fatalf("expected a FinalizerForceRemoved event")
+				}
+				return
+			}
+			if result.RequeueAfter != tt.wantRequeue {
+				t.Fatalf("RequeueAfter = %v, want %v", result.RequeueAfter, tt.wantRequeue)
+			}
+
+			clock.SetTime(deletedAt.Add(tt.later))
+			env.reconcile(t, "web")
+			if removed := cdGone(t, env); removed != tt.wantLaterRemove {
+				t.Fatalf("finalizer removed later = %v, want %v", removed, tt.wantLaterRemove)
+			}
+			if got := containsEvent(env.events(), "FinalizerForceRemoved"); got != tt.wantLaterRemove {
+				t.Fatalf("FinalizerForceRemoved event = %v, want %v", got, tt.wantLaterRemove)
+			}
+			// 强制移除时 Deployment 可能留下
+			if err := env.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "web"}, &appsv1.Deployment{}); err != nil {
+				t.Fatalf("get Deployment: %v", err)
+			}
+		})
+	}
+}
+
+// cdGone 判断移除 finalizer 后 CR 是否已被删除
+func cdGone(t *testing.T, env *testEnv) bool {
+	t.Helper()
+	err := env.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "web"}, &appsv1alpha1.CustomDeployment{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		t.Fatal(err)
+	}
+	return apierrors.IsNotFound(err)
+}
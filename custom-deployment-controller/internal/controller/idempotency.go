@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// specHashAnnotation 记录上一次完整调谐时的输入指纹，指纹不变且 Deployment 状态已同步时跳过 Deployment 的写入
+const specHashAnnotation = "apps.myorg.io/spec-hash"
+
+// specFingerprint 计算调谐输入的指纹：spec、CR 的 generation、Deployment 的 generation
+// （人工修改 Deployment 会改变它）、影响期望 Deployment 的控制器参数以及 configFrom 和 namespace 默认策略 ConfigMap 的 resourceVersion。
+// Deployment 不存在时返回空指纹；inSync 表示 Deployment 状态（包括发布条件）已经被观察并同步到 CR 上。
+func (c *CustomDeploymentController) specFingerprint(ctx context.Context, cd *appsv1alpha1.CustomDeployment) (hash string, inSync bool, err error) {
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Name: cd.Name, Namespace: cd.Namespace}, deploy); err != nil {
+		return "", false, client.IgnoreNotFound(err)
+	}
+
+	configVersion := ""
+	if cd.Spec.ConfigFrom != "" {
+		cm := &corev1.ConfigMap{}
+		err := c.Get(ctx, types.NamespacedName{Name: cd.Spec.ConfigFrom, Namespace: cd.Namespace}, cm)
+		if err != nil && !errors.IsNotFound(err) {
+			return "", false, err
+		}
+		configVersion = cm.ResourceVersion
+	}
+	defaults, err := c.podDefaultsConfigMap(ctx, cd.Namespace)
+	if err != nil {
+		return "", false, err
+	}
+	if defaults != nil {
+		configVersion += "/" + defaults.ResourceVersion
+	}
+
+	spec, err := json.Marshal(cd.Spec)
+	if err != nil {
+		return "", false, err
+	}
+	h := sha256.New()
+	h.Write(spec)
+	fmt.Fprintf(h, "\n%d/%d/%s", cd.Generation, deploy.Generation, configVersion)
+	// 注解的变化不会增加 generation，影响副本数的 replica-override 需要单独计入指纹
+	if v, ok := cd.Annotations[replicaOverrideAnnotation]; ok {
+		fmt.Fprintf(h, "/override=%s", v)
+	}
+	// 控制器参数不在 spec 中，修改后需要重新调谐已有的对象
+	config, err := c.configFingerprint()
+	if err != nil {
+		return "", false, err
+	}
+	fmt.Fprintf(h, "/config=%s", config)
+	// 运行窗口的边界不会产生任何事件，把当前是否处于窗口内也计入指纹
+	if cd.Spec.Schedule != nil {
+		active, _, _ := evaluateSchedule(cd.Spec.Schedule, c.now())
+		fmt.Fprintf(h, "/%t", active)
+	}
+
+	inSync = deploy.Status.ObservedGeneration == deploy.Generation &&
+		deploy.Status.AvailableReplicas == cd.Status.AvailableReplicas &&
+		deploy.Status.Replicas == cd.Status.Replicas &&
+		rolloutConditionsInSync(cd, deploy)
+	return hex.EncodeToString(h.Sum(nil))[:16], inSync, nil
+}
+
+// configFingerprint 序列化影响期望 Deployment 的控制器参数；map 按 key 排序序列化，结果稳定
+func (c *CustomDeploymentController) configFingerprint() ([]byte, error) {
+	return json.Marshal(struct {
+		AllowedRegistries    []string
+		SelectorLabelKey     string
+		DefaultRequests      corev1.ResourceList
+		Sizes                map[string]int32
+		MaxReplicas          int32
+		PSADefaults          bool
+		NoBlockOwnerDeletion bool
+	}{
+		AllowedRegistries:    c.AllowedRegistries,
+		SelectorLabelKey:     c.SelectorLabelKey,
+		DefaultRequests:      c.DefaultRequests,
+		Sizes:                c.Sizes,
+		MaxReplicas:          c.MaxReplicas,
+		PSADefaults:          c.PSADefaults,
+		NoBlockOwnerDeletion: c.NoBlockOwnerDeletion,
+	})
+}
+
+// recordSpecHash 在完整调谐结束后记录当前指纹
+func (c *CustomDeploymentController) recordSpecHash(ctx context.Context, cd *appsv1alpha1.CustomDeployment) error {
+	hash, _, err := c.specFingerprint(ctx, cd)
+	if err != nil || hash == "" || cd.Annotations[specHashAnnotation] == hash {
+		return err
+	}
+
+	patch := client.MergeFrom(cd.DeepCopy())
+	if cd.Annotations == nil {
+		cd.Annotations = map[string]string{}
+	}
+	cd.Annotations[specHashAnnotation] = hash
+	if err := c.Patch(ctx, cd, patch); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to record spec hash")
+		return err
+	}
+	return nil
+}
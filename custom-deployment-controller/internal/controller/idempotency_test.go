@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileNoChangeDoesNoWrites(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+	env.reconcileUntilCreated(t, "web")
+	// 第三次调谐记录 spec hash，之后输入不变
+	env.reconcile(t, "web")
+
+	env.writes.reset()
+	for i := 0; i < 3; i++ {
+		env.reconcile(t, "web")
+	}
+	if n := env.writes.total(); n != 0 {
+		t.Fatalf("expected no writes for unchanged reconciles, got %v", env.writes.counts)
+	}
+}
+
+func TestReconcileRestoresDeletedServiceWhenSpecUnchanged(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+		cd.Spec.ExposeService = true
+		cd.Spec.ServicePort = 80
+	})})
+	env.reconcileUntilCreated(t, "web")
+	env.reconcile(t, "web")
+
+	key := types.NamespacedName{Namespace: testNamespace, Name: "web"}
+	svc := &corev1.Service{}
+	if err := env.c.Get(context.Background(), key, svc); err != nil {
+		t.Fatalf("get Service: %v", err)
+	}
+	if err := env.c.Delete(context.Background(), svc); err != nil {
+		t.Fatal(err)
+	}
+
+	env.writes.reset()
+	env.reconcile(t, "web")
+	if err := env.c.Get(context.Background(), key, &corev1.Service{}); err != nil {
+		t.Fatalf("expected Service to be restored, got %v", err)
+	}
+	if n := env.writes.get("update/Deployment"); n != 0 {
+		t.Fatalf("expected the Deployment write to be skipped, got %d updates", n)
+	}
+}
+
+func TestSpecFingerprintChangesWithControllerConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*CustomDeploymentController)
+	}{
+		{"allowed registries", func(c *CustomDeploymentController) { c.AllowedRegistries = []string{"registry.example.com"} }},
+		{"selector label key", func(c *CustomDeploymentController) { c.SelectorLabelKey = "app.kubernetes.io/name" }},
+		{"default requests", func(c *CustomDeploymentController) {
+			c.DefaultRequests, _ = ParseResourceRequests("100m", "")
+		}},
+		{"sizes", func(c *CustomDeploymentController) { c.Sizes = map[string]int32{"small": 2} }},
+		{"max replicas", func(c *CustomDeploymentController) { c.MaxReplicas = 10 }},
+		{"psa defaults", func(c *CustomDeploymentController) { c.PSADefaults = true }},
+		{"no block owner deletion", func(c *CustomDeploymentController) { c.NoBlockOwnerDeletion = true }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+			env.reconcileUntilCreated(t, "web")
+			cd := env.customDeployment(t, "web")
+
+			before, _, err := env.c.specFingerprint(context.Background(), cd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.mutate(env.c)
+			after, _, err := env.c.specFingerprint(context.Background(), cd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if before == after {
+				t.Fatalf("fingerprint did not change after changing %s", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"strings"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// imagePullPolicy 返回容器的拉取策略：未设置时按 Kubernetes 的约定，
+// 使用 latest 标签（或没有标签）的镜像为 Always，其他为 IfNotPresent
+func imagePullPolicy(cd *appsv1alpha1.CustomDeployment, image string) corev1.PullPolicy {
+	if cd.Spec.ImagePullPolicy != "" {
+		return cd.Spec.ImagePullPolicy
+	}
+	if isLatestImage(image) {
+		return corev1.PullAlways
+	}
+	return corev1.PullIfNotPresent
+}
+
+// isLatestImage 判断镜像引用是否使用 latest 标签；digest 引用不会变化，不算 latest
+func isLatestImage(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	// 最后一个 "/" 之后的 ":" 才是标签分隔符，之前的可能是仓库端口
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, found := strings.Cut(name, ":")
+	return !found || tag == "latest"
+}
+
+// syncImage 同步受管容器的镜像和拉取策略，返回是否有变化。镜像变化会触发滚动更新
+func syncImage(live, desired *appsv1.Deployment) bool {
+	liveContainer, desiredContainer := managedContainers(live, desired)
+	if liveContainer == nil || desiredContainer == nil {
+		return false
+	}
+	updated := false
+	if liveContainer.Image != desiredContainer.Image {
+		liveContainer.Image = desiredContainer.Image
+		updated = true
+	}
+	if liveContainer.ImagePullPolicy != desiredContainer.ImagePullPolicy {
+		liveContainer.ImagePullPolicy = desiredContainer.ImagePullPolicy
+		updated = true
+	}
+	return updated
+}
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileDefaultImageWarning(t *testing.T) {
+	tests := []struct {
+		name        string
+		image       string
+		wantImage   string
+		wantWarning bool
+	}{
+		{name: "image omitted", wantImage: DefaultImage, wantWarning: true},
+		{name: "explicit image", image: "registry.example.com/app:v1", wantImage: "registry.example.com/app:v1"},
+		{name: "explicit nginx:latest", image: "nginx:latest", wantImage: "nginx:latest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.Image = tt.image
+			})})
+			deploy := env.reconcileUntilCreated(t, "web")
+
+			if got := deploy.Spec.Template.Spec.Containers[0].Image; got != tt.wantImage {
+				t.Fatalf("image = %q, want %q", got, tt.wantImage)
+			}
+			if got := containsEvent(env.events(), "DefaultImage"); got != tt.wantWarning {
+				t.Fatalf("DefaultImage warning recorded = %v, want %v", got, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestReconcileImagePullPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		policy  corev1.PullPolicy
+		want    corev1.PullPolicy
+		updated corev1.PullPolicy
+	}{
+		{name: "tagged image defaults to IfNotPresent", image: "registry.example.com/app:v1", want: corev1.PullIfNotPresent, updated: corev1.PullNever},
+		{name: "latest defaults to Always", image: "registry.example.com/app:latest", want: corev1.PullAlways, updated: corev1.PullIfNotPresent},
+		{name: "untagged defaults to Always", image: "registry.example.com:5000/app", want: corev1.PullAlways, updated: corev1.PullIfNotPresent},
+		{name: "digest defaults to IfNotPresent", image: "app@sha256:" + strings.Repeat("a", 64), want: corev1.PullIfNotPresent, updated: corev1.PullAlways},
+		{name: "explicit policy wins over latest", image: "app:latest", policy: corev1.PullIfNotPresent, want: corev1.PullIfNotPresent, updated: corev1.PullAlways},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.Image = tt.image
+				cd.Spec.ImagePullPolicy = tt.policy
+			})})
+			if got := env.reconcileUntilCreated(t, "web").Spec.Template.Spec.Containers[0].ImagePullPolicy; got != tt.want {
+				t.Fatalf("imagePullPolicy = %s, want %s", got, tt.want)
+			}
+
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) { cd.Spec.ImagePullPolicy = tt.updated })
+			env.reconcile(t, "web")
+			if got := env.deployment(t, "web").Spec.Template.Spec.Containers[0].ImagePullPolicy; got != tt.updated {
+				t.Fatalf("after update: imagePullPolicy = %s, want %s", got, tt.updated)
+			}
+		})
+	}
+}
+
+func TestReconcileImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		updated string
+		want    string
+	}{
+		{name: "custom image updated in place", image: "registry.example.com/app:v1", updated: "registry.example.com/app:v2", want: "registry.example.com/app:v2"},
+		{name: "default image replaced", updated: "registry.example.com/app:v1", want: "registry.example.com/app:v1"},
+		{name: "image removed falls back to the default", image: "registry.example.com/app:v1", want: DefaultImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.Image = tt.image
+			})})
+			wantInitial := tt.image
+			if wantInitial == "" {
+				wantInitial = DefaultImage
+			}
+			if got := env.reconcileUntilCreated(t, "web").Spec.Template.Spec.Containers[0].Image; got != wantInitial {
+				t.Fatalf("image on create = %q, want %q", got, wantInitial)
+			}
+
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) { cd.Spec.Image = tt.updated })
+			env.writes.reset()
+			env.reconcile(t, "web")
+
+			if got := env.deployment(t, "web").Spec.Template.Spec.Containers[0].Image; got != tt.want {
+				t.Fatalf("image after update = %q, want %q", got, tt.want)
+			}
+			// 原地更新已有的 Deployment，而不是删除重建
+			if got := env.writes.get("update/Deployment"); got != 1 {
+				t.Fatalf("Deployment updates = %d, want 1", got)
+			}
+			if got := env.writes.get("create/Deployment") + env.writes.get("delete/Deployment"); got != 0 {
+				t.Fatalf("Deployment recreated (%d writes), want an in-place update", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// validateIngress 校验 spec.ingress 依赖的 Service：Ingress 的后端是 spec.exposeService 创建的 Service，
+// 没有 Service 时 Ingress 无法路由
+func validateIngress(cd *appsv1alpha1.CustomDeployment) error {
+	if cd.Spec.Ingress != nil && !cd.Spec.ExposeService {
+		return fmt.Errorf("ingress requires exposeService=true, its backend is the Service created for the workload")
+	}
+	return nil
+}
+
+func desiredIngress(cd *appsv1alpha1.CustomDeployment, labels map[string]string) *networkingv1.Ingress {
+	spec := cd.Spec.Ingress
+	path := spec.Path
+	if path == "" {
+		path = "/"
+	}
+
+	ing := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+			Labels:    labels,
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: spec.IngressClassName,
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: spec.Host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     path,
+									PathType: ptr.To(networkingv1.PathTypePrefix),
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: cd.Name,
+											Port: networkingv1.ServiceBackendPort{Number: spec.ServicePort},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if spec.TLSSecretName != "" {
+		tls := networkingv1.IngressTLS{SecretName: spec.TLSSecretName}
+		if spec.Host != "" {
+			tls.Hosts = []string{spec.Host}
+		}
+		ing.Spec.TLS = []networkingv1.IngressTLS{tls}
+	}
+	return ing
+}
+
+// reconcileIngress 按 spec.ingress 创建、更新或删除路由到工作负载 Service 的 Ingress
+func (c *CustomDeploymentController) reconcileIngress(ctx context.Context, cd *appsv1alpha1.CustomDeployment) error {
+	logger := log.FromContext(ctx)
+
+	existing := &networkingv1.Ingress{}
+	err := c.Get(ctx, types.NamespacedName{Name: cd.Name, Namespace: cd.Namespace}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get Ingress")
+		return err
+	}
+	found := err == nil
+
+	if cd.Spec.Ingress == nil {
+		if found && metav1.IsControlledBy(existing, cd) {
+			if err := c.Delete(ctx, existing); err != nil && !errors.IsNotFound(err) {
+				logger.Error(err, "Failed to delete Ingress")
+				return err
+			}
+			logger.Info("Ingress deleted", "name", existing.Name)
+		}
+		return nil
+	}
+	// spec 无效时已经记录了 InvalidSpec 条件，等待用户修改
+	if validateIngress(cd) != nil {
+		return nil
+	}
+
+	desired := desiredIngress(cd, c.selectorLabels(cd))
+	if !found {
+		if err := c.setOwner(cd, desired); err != nil {
+			logger.Error(err, "Failed to set owner reference")
+			return err
+		}
+		if err := c.Create(ctx, desired); err != nil {
+			logger.Error(err, "Failed to create Ingress")
+			return err
+		}
+		logger.Info("Ingress created successfully", "name", desired.Name)
+		return nil
+	}
+
+	if equality.Semantic.DeepEqual(existing.Spec, desired.Spec) {
+		return nil
+	}
+	existing.Spec = desired.Spec
+	if err := c.Update(ctx, existing); err != nil {
+		logger.Error(err, "Failed to update Ingress")
+		return err
+	}
+	logger.Info("Ingress updated successfully", "name", existing.Name)
+	return nil
+}
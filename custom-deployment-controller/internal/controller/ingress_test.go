@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileIngress(t *testing.T) {
+	tests := []struct {
+		name          string
+		exposeService bool
+		wantIngress   bool
+	}{
+		{"backend points at the managed Service", true, true},
+		{"refused without a Service", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.ExposeService = tt.exposeService
+				cd.Spec.ServicePort = 8080
+				cd.Spec.Ingress = &appsv1alpha1.IngressSpec{Host: "web.example.com", ServicePort: 8080}
+			})})
+			env.reconcile(t, "web")
+			env.reconcile(t, "web")
+
+			key := types.NamespacedName{Namespace: testNamespace, Name: "web"}
+			ing := &networkingv1.Ingress{}
+			err := env.c.Get(context.Background(), key, ing)
+			if !tt.wantIngress {
+				if !errors.IsNotFound(err) {
+					t.Fatalf("expected no Ingress, got err=%v", err)
+				}
+				cond := meta.FindStatusCondition(env.customDeployment(t, "web").Status.Conditions, ConditionInvalidSpec)
+				if cond == nil || cond.Status != "True" || !strings.Contains(cond.Message, "exposeService") {
+					t.Fatalf("expected InvalidSpec condition mentioning exposeService, got %+v", cond)
+				}
+				if !containsEvent(env.events(), "InvalidSpec") {
+					t.Fatalf("expected an InvalidSpec event")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("get Ingress: %v", err)
+			}
+
+			svc := &corev1.Service{}
+			if err := env.c.Get(context.Background(), key, svc); err != nil {
+				t.Fatalf("get Service: %v", err)
+			}
+			backend := ing.Spec.Rules[0].HTTP.Paths[0].Backend.Service
+			if backend.Name != svc.Name || backend.Port.Number != svc.Spec.Ports[0].Port {
+				t.Fatalf("Ingress backend = %s:%d, want Service %s:%d", backend.Name, backend.Port.Number, svc.Name, svc.Spec.Ports[0].Port)
+			}
+		})
+	}
+}
+
+func containsEvent(events []string, reason string) bool {
+	for _, e := range events {
+		if strings.Contains(e, " "+reason+" ") {
+			return true
+		}
+	}
+	return false
+}
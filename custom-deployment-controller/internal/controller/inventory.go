@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// reconcileResult 是某个 CustomDeployment 最近一次调谐的结果
+type reconcileResult struct {
+	Time  time.Time
+	Error string
+}
+
+// reconcileResults 记录每个 CustomDeployment 最近一次调谐的结果，供清单导出使用
+type reconcileResults struct {
+	mu      sync.Mutex
+	results map[types.NamespacedName]reconcileResult
+}
+
+func (r *reconcileResults) record(key types.NamespacedName, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.results == nil {
+		r.results = map[types.NamespacedName]reconcileResult{}
+	}
+	result := reconcileResult{Time: time.Now()}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	r.results[key] = result
+}
+
+func (r *reconcileResults) get(key types.NamespacedName) (reconcileResult, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result, ok := r.results[key]
+	return result, ok
+}
+
+// inventoryEntry 是清单中的一个受管对象
+type inventoryEntry struct {
+	Kind            string     `json:"kind"`
+	Namespace       string     `json:"namespace"`
+	Name            string     `json:"name"`
+	Source          string     `json:"source"`
+	LastReconcile   *time.Time `json:"lastReconcile,omitempty"`
+	LastReconcileOK *bool      `json:"lastReconcileOK,omitempty"`
+	LastError       string     `json:"lastError,omitempty"`
+}
+
+// dumpInventory 以 JSON 导出控制器管理的 Deployment 及其 CustomDeployment 最近一次调谐的结果。
+// 通过 reader 分页列出 CR，CR 数量很多时也只在内存中保留一页
+func (c *CustomDeploymentController) dumpInventory(ctx context.Context, reader client.Reader) ([]byte, error) {
+	var entries []inventoryEntry
+	err := ForEachCustomDeployment(ctx, reader, DefaultListPageSize, func(cd *appsv1alpha1.CustomDeployment) error {
+		key := client.ObjectKeyFromObject(cd)
+		entry := inventoryEntry{Kind: "Deployment", Namespace: cd.Namespace, Name: cd.Name, Source: key.String()}
+		if result, ok := c.results.get(key); ok {
+			ok := result.Error == ""
+			entry.LastReconcile = &result.Time
+			entry.LastReconcileOK = &ok
+			entry.LastError = result.Error
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []inventoryEntry{}
+	}
+	return json.Marshal(entries)
+}
+
+// DumpInventoryOnSignal 返回在 Manager 中运行的任务：每次收到 SIGUSR1 时把受管对象清单写到日志，
+// 用于没有暴露端口的受限环境中在线排查：kill -USR1 <pid>。reader 应为 mgr.GetAPIReader()，缓存 client 不支持分页
+func (c *CustomDeploymentController) DumpInventoryOnSignal(reader client.Reader) manager.Runnable {
+	return manager.RunnableFunc(func(ctx context.Context) error {
+		logger := log.FromContext(ctx).WithName("inventory")
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGUSR1)
+		defer signal.Stop(signals)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-signals:
+				inventory, err := c.dumpInventory(ctx, reader)
+				if err != nil {
+					logger.Error(err, "Failed to dump managed object inventory")
+					continue
+				}
+				logger.Info("Managed object inventory", "inventory", string(inventory))
+			}
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestDumpInventory(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("api"), newCustomDeployment("web")})
+	env.c.results.record(types.NamespacedName{Namespace: testNamespace, Name: "api"}, nil)
+	env.c.results.record(types.NamespacedName{Namespace: testNamespace, Name: "web"}, errors.New("boom"))
+
+	data, err := env.c.dumpInventory(context.Background(), env.c.Client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []inventoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("inventory is not valid JSON: %v\n%s", err, data)
+	}
+
+	want := map[string]struct {
+		ok        bool
+		lastError string
+	}{
+		"api": {ok: true},
+		"web": {ok: false, lastError: "boom"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %s", len(entries), len(want), data)
+	}
+	for _, e := range entries {
+		w, found := want[e.Name]
+		if !found {
+			t.Fatalf("unexpected entry %+v", e)
+		}
+		if e.Kind != "Deployment" || e.Namespace != testNamespace || e.Source != testNamespace+"/"+e.Name {
+			t.Errorf("entry %s has unexpected identity: %+v", e.Name, e)
+		}
+		if e.LastReconcileOK == nil || *e.LastReconcileOK != w.ok || e.LastError != w.lastError {
+			t.Errorf("entry %s: ok=%v lastError=%q, want ok=%v lastError=%q", e.Name, e.LastReconcileOK, e.LastError, w.ok, w.lastError)
+		}
+	}
+}
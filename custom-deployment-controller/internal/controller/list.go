@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"context"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultListPageSize 是分页列出 CustomDeployment 时每页的默认数量
+const DefaultListPageSize int64 = 500
+
+// ForEachCustomDeployment 分页列出 CustomDeployment 并逐个回调，用于全量重同步/一次性任务（如 SIGUSR1 清单导出），
+// 保证内存中同时只保留一页对象。CR 数量成千上万时避免一次 List 把所有对象载入内存。
+//
+// 注意：Manager 的缓存 client 会忽略 Limit/Continue，这里应传入 mgr.GetAPIReader()。
+// Informer 启动时的初始 List 由 client-go 的 reflector 自行分页，不经过这里。
+func ForEachCustomDeployment(ctx context.Context, reader client.Reader, pageSize int64, fn func(*appsv1alpha1.CustomDeployment) error, opts ...client.ListOption) error {
+	if pageSize <= 0 {
+		pageSize = DefaultListPageSize
+	}
+
+	continueToken := ""
+	for {
+		list := &appsv1alpha1.CustomDeploymentList{}
+		listOpts := append([]client.ListOption{client.Limit(pageSize), client.Continue(continueToken)}, opts...)
+		if err := reader.List(ctx, list, listOpts...); err != nil {
+			return err
+		}
+		for i := range list.Items {
+			if err := fn(&list.Items[i]); err != nil {
+				return err
+			}
+		}
+		continueToken = list.Continue
+		if continueToken == "" {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// pagedReader 按 Limit 和 Continue 分页返回预设的对象，并记录每次 List 的参数
+type pagedReader struct {
+	client.Reader
+	items []appsv1alpha1.CustomDeployment
+	calls []client.ListOptions
+}
+
+func (p *pagedReader) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	o := client.ListOptions{}
+	o.ApplyOptions(opts)
+	p.calls = append(p.calls, o)
+
+	start := 0
+	if o.Continue != "" {
+		if _, err := fmt.Sscanf(o.Continue, "%d", &start); err != nil {
+			return err
+		}
+	}
+	end := min(start+int(o.Limit), len(p.items))
+	cdList := list.(*appsv1alpha1.CustomDeploymentList)
+	cdList.Items = append([]appsv1alpha1.CustomDeployment(nil), p.items[start:end]...)
+	if end < len(p.items) {
+		cdList.Continue = fmt.Sprint(end)
+	}
+	return nil
+}
+
+func TestForEachCustomDeploymentPages(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     int
+		pageSize  int64
+		wantCalls int
+	}{
+		{"empty", 0, 2, 1},
+		{"single partial page", 1, 2, 1},
+		{"exact pages", 4, 2, 2},
+		{"last page partial", 5, 2, 3},
+		{"default page size", 3, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &pagedReader{}
+			for i := 0; i < tt.items; i++ {
+				reader.items = append(reader.items, appsv1alpha1.CustomDeployment{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: fmt.Sprintf("cd-%d", i)},
+				})
+			}
+
+			var seen []string
+			err := ForEachCustomDeployment(context.Background(), reader, tt.pageSize, func(cd *appsv1alpha1.CustomDeployment) error {
+				// 回调发生在读取下一页之前，说明同一时间只持有一页
+				if want := len(seen)/int(pageSizeOrDefault(tt.pageSize)) + 1; len(reader.calls) != want {
+					t.Errorf("item %s processed after %d List calls, want %d", cd.Name, len(reader.calls), want)
+				}
+				seen = append(seen, cd.Name)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(seen) != tt.items {
+				t.Fatalf("processed %d items, want %d", len(seen), tt.items)
+			}
+			if len(reader.calls) != tt.wantCalls {
+				t.Fatalf("List called %d times, want %d", len(reader.calls), tt.wantCalls)
+			}
+			for _, call := range reader.calls {
+				if call.Limit != pageSizeOrDefault(tt.pageSize) {
+					t.Errorf("List limit = %d, want %d", call.Limit, pageSizeOrDefault(tt.pageSize))
+				}
+			}
+		})
+	}
+}
+
+func TestForEachCustomDeploymentStopsOnError(t *testing.T) {
+	reader := &pagedReader{items: make([]appsv1alpha1.CustomDeployment, 4)}
+	calls := 0
+	err := ForEachCustomDeployment(context.Background(), reader, 2, func(*appsv1alpha1.CustomDeployment) error {
+		calls++
+		return fmt.Errorf("stop")
+	})
+	if err == nil || calls != 1 || len(reader.calls) != 1 {
+		t.Fatalf("err = %v, callbacks = %d, List calls = %d; want an error after the first item", err, calls, len(reader.calls))
+	}
+}
+
+func pageSizeOrDefault(n int64) int64 {
+	if n <= 0 {
+		return DefaultListPageSize
+	}
+	return n
+}
@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"time"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// reconcileDurationBuckets 覆盖几毫秒的缓存命中到数十秒的慢调谐，便于计算 p50/p95/p99
+var reconcileDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// reconcileDuration 按控制器和资源 GVK 记录调谐耗时
+var reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "reconcile_duration_seconds",
+	Help:    "Duration of reconciles in seconds, including ones that returned an error.",
+	Buckets: reconcileDurationBuckets,
+}, []string{"controller", "group", "version", "kind"})
+
+func init() {
+	metrics.Registry.MustRegister(reconcileDuration)
+}
+
+// observeReconcileDuration 记录一次 CustomDeployment 调谐的耗时，配合 defer 使用，错误返回时也会记录
+func observeReconcileDuration(start time.Time) {
+	reconcileDuration.WithLabelValues("customdeployment",
+		appsv1alpha1.GroupVersion.Group, appsv1alpha1.GroupVersion.Version, "CustomDeployment",
+	).Observe(time.Since(start).Seconds())
+}
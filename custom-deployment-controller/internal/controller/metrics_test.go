@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// reconcileDurationCount 返回 CustomDeployment 调谐耗时直方图的观测次数
+func reconcileDurationCount(t *testing.T) uint64 {
+	t.Helper()
+	observer := reconcileDuration.WithLabelValues("customdeployment",
+		appsv1alpha1.GroupVersion.Group, appsv1alpha1.GroupVersion.Version, "CustomDeployment")
+	m := &dto.Metric{}
+	if err := observer.(prometheus.Histogram).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestReconcileDurationObservedOnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		getErr  error
+		wantErr bool
+	}{
+		{"success", nil, false},
+		{"get fails", errors.New("apiserver unavailable"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			funcs := interceptor.Funcs{}
+			if tt.getErr != nil {
+				funcs.Get = func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+					return tt.getErr
+				}
+			}
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web")}, withInterceptor(funcs))
+
+			before := reconcileDurationCount(t)
+			_, err := env.c.Reconcile(context.Background(), requestFor("web"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reconcile error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := reconcileDurationCount(t) - before; got != 1 {
+				t.Fatalf("recorded %d observations, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// replicaOverrideAnnotation 用于故障时临时指定副本数，不需要通过 GitOps 修改 spec；删除注解后恢复 spec 的副本数
+const replicaOverrideAnnotation = "apps.myorg.io/replica-override"
+
+// ConditionOverridden 表示副本数由 replica-override 注解决定，而不是 spec
+const ConditionOverridden = "Overridden"
+
+// replicaOverride 解析 replica-override 注解，ok 为 false 表示没有设置
+func replicaOverride(cd *appsv1alpha1.CustomDeployment) (replicas int32, ok bool, err error) {
+	v, ok := cd.Annotations[replicaOverrideAnnotation]
+	if !ok {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("invalid %s %q: must be a non-negative integer", replicaOverrideAnnotation, v)
+	}
+	return int32(n), true, nil
+}
+
+// setOverriddenCondition 记录副本数是否被 replica-override 注解覆盖
+func setOverriddenCondition(cd *appsv1alpha1.CustomDeployment) {
+	replicas, ok, _ := replicaOverride(cd)
+	if ok {
+		meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+			Type:               ConditionOverridden,
+			Status:             metav1.ConditionTrue,
+			Reason:             "ReplicaOverride",
+			Message:            fmt.Sprintf("Replicas set to %d by the %s annotation, spec.replicas is ignored until it is removed", replicas, replicaOverrideAnnotation),
+			ObservedGeneration: cd.Generation,
+		})
+		return
+	}
+	if meta.FindStatusCondition(cd.Status.Conditions, ConditionOverridden) != nil {
+		meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+			Type:               ConditionOverridden,
+			Status:             metav1.ConditionFalse,
+			Reason:             "SpecReplicas",
+			Message:            "Replicas follow the spec",
+			ObservedGeneration: cd.Generation,
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileReplicaOverride(t *testing.T) {
+	tests := []struct {
+		name           string
+		override       string
+		want           int32
+		wantOverridden bool
+		wantInvalid    bool
+		wantAdjusted   bool
+	}{
+		{name: "override wins", override: "5", want: 5, wantOverridden: true},
+		// 覆盖值不按 replicaStep 取整
+		{name: "scale to zero", override: "0", want: 0, wantOverridden: true},
+		// spec 无效时条件保持不变
+		{name: "invalid value", override: "-1", want: 3, wantInvalid: true, wantAdjusted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.ReplicaStep = 3
+			})})
+			env.reconcileUntilCreated(t, "web")
+
+			// 注解的修改不会增加 generation
+			setOverride := func(value *string) {
+				t.Helper()
+				cd := env.customDeployment(t, "web")
+				if value == nil {
+					delete(cd.Annotations, replicaOverrideAnnotation)
+				} else {
+					if cd.Annotations == nil {
+						cd.Annotations = map[string]string{}
+					}
+					cd.Annotations[replicaOverrideAnnotation] = *value
+				}
+				if err := env.c.Update(context.Background(), cd); err != nil {
+					t.Fatal(err)
+				}
+			}
+			setOverride(&tt.override)
+			env.reconcile(t, "web")
+
+			if got := ptr.Deref(env.deployment(t, "web").Spec.Replicas, -1); got != tt.want {
+				t.Fatalf("replicas with override = %d, want %d", got, tt.want)
+			}
+			conditions := env.customDeployment(t, "web").Status.Conditions
+			if got := meta.IsStatusConditionTrue(conditions, ConditionOverridden); got != tt.wantOverridden {
+				t.Fatalf("%s = %v, want %v", ConditionOverridden, got, tt.wantOverridden)
+			}
+			if got := meta.IsStatusConditionTrue(conditions, ConditionInvalidSpec); got != tt.wantInvalid {
+				t.Fatalf("%s = %v, want %v", ConditionInvalidSpec, got, tt.wantInvalid)
+			}
+			if got := meta.IsStatusConditionTrue(conditions, ConditionReplicasAdjusted); got != tt.wantAdjusted {
+				t.Fatalf("%s = %v, want %v", ConditionReplicasAdjusted, got, tt.wantAdjusted)
+			}
+
+			// 删除注解后恢复 spec 的副本数
+			setOverride(nil)
+			env.reconcile(t, "web")
+			if got := ptr.Deref(env.deployment(t, "web").Spec.Replicas, -1); got != 3 {
+				t.Fatalf("replicas after removing the override = %d, want 3", got)
+			}
+			conditions = env.customDeployment(t, "web").Status.Conditions
+			if meta.IsStatusConditionTrue(conditions, ConditionOverridden) {
+				t.Fatalf("%s still set after removing the override", ConditionOverridden)
+			}
+			if !meta.IsStatusConditionTrue(conditions, ConditionReplicasAdjusted) {
+				t.Fatalf("%s not restored after removing the override", ConditionReplicasAdjusted)
+			}
+		})
+	}
+}
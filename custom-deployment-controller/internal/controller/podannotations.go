@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// podAnnotationPrefixes 是 spec.podAnnotations 允许的注解前缀。
+// Pod 模板上这些前缀的注解完全由 CR 管理，从 CR 中删除后也会从 Pod 模板上移除
+var podAnnotationPrefixes = []string{
+	"sidecar.istio.io/",
+	"proxy.istio.io/",
+	"traffic.sidecar.istio.io/",
+}
+
+func isPassthroughAnnotation(key string) bool {
+	for _, prefix := range podAnnotationPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePodAnnotations 检查 spec.podAnnotations 中的注解是否都在允许的前缀内
+func validatePodAnnotations(cd *appsv1alpha1.CustomDeployment) error {
+	for key := range cd.Spec.PodAnnotations {
+		if !isPassthroughAnnotation(key) {
+			return fmt.Errorf("pod annotation %q is not supported, allowed prefixes are %v", key, podAnnotationPrefixes)
+		}
+	}
+	return nil
+}
+
+// syncPodAnnotations 把期望的透传注解同步到线上 Pod 模板，保留其他来源的注解，返回是否有变化
+func syncPodAnnotations(live, desired *appsv1.Deployment) bool {
+	updated := false
+	for key := range live.Spec.Template.Annotations {
+		if _, ok := desired.Spec.Template.Annotations[key]; !ok && isPassthroughAnnotation(key) {
+			delete(live.Spec.Template.Annotations, key)
+			updated = true
+		}
+	}
+	for key, value := range desired.Spec.Template.Annotations {
+		if !isPassthroughAnnotation(key) || live.Spec.Template.Annotations[key] == value {
+			continue
+		}
+		if live.Spec.Template.Annotations == nil {
+			live.Spec.Template.Annotations = map[string]string{}
+		}
+		live.Spec.Template.Annotations[key] = value
+		updated = true
+	}
+	return updated
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcilePodAnnotations(t *testing.T) {
+	const inject = "sidecar.istio.io/inject"
+	tests := []struct {
+		name    string
+		initial map[string]string
+		updated map[string]string
+	}{
+		{name: "injection enabled on create", initial: map[string]string{inject: "true"}, updated: map[string]string{inject: "true"}},
+		{name: "injection disabled later", initial: map[string]string{inject: "true"}, updated: map[string]string{inject: "false"}},
+		{name: "annotation removed", initial: map[string]string{inject: "true"}},
+		{name: "annotation added later", updated: map[string]string{"proxy.istio.io/config": "{}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.PodAnnotations = tt.initial
+			})})
+			deploy := env.reconcileUntilCreated(t, "web")
+			for k, v := range tt.initial {
+				if got := deploy.Spec.Template.Annotations[k]; got != v {
+					t.Fatalf("pod template %s = %q, want %q", k, got, v)
+				}
+			}
+
+			// 其他来源写入的注解（如 kubectl rollout restart）需要保留
+			if deploy.Spec.Template.Annotations == nil {
+				deploy.Spec.Template.Annotations = map[string]string{}
+			}
+			deploy.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] = "now"
+			if err := env.c.Update(context.Background(), deploy); err != nil {
+				t.Fatal(err)
+			}
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) { cd.Spec.PodAnnotations = tt.updated })
+			env.reconcile(t, "web")
+
+			got := env.deployment(t, "web").Spec.Template.Annotations
+			for k := range got {
+				if isPassthroughAnnotation(k) && got[k] != tt.updated[k] {
+					t.Fatalf("pod template %s = %q, want %q", k, got[k], tt.updated[k])
+				}
+			}
+			for k, v := range tt.updated {
+				if got[k] != v {
+					t.Fatalf("pod template %s = %q, want %q", k, got[k], v)
+				}
+			}
+			if got["kubectl.kubernetes.io/restartedAt"] != "now" {
+				t.Fatal("expected annotations from other sources to be kept")
+			}
+		})
+	}
+}
+
+func TestReconcileRejectsUnsupportedPodAnnotations(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+		cd.Spec.PodAnnotations = map[string]string{"example.com/team": "payments"}
+	})})
+	env.reconcile(t, "web")
+	_, _ = env.c.Reconcile(context.Background(), requestFor("web"))
+
+	cond := meta.FindStatusCondition(env.customDeployment(t, "web").Status.Conditions, ConditionInvalidSpec)
+	if cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Fatalf("InvalidSpec condition = %+v, want True", cond)
+	}
+}
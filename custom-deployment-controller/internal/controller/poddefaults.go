@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+)
+
+// podDefaultsLabel 标记 namespace 级别的 Pod 默认策略 ConfigMap（值为 true）。
+// 支持的 key：terminationGracePeriodSeconds、nodeSelector、tolerations、affinity，
+// 后三者的值是对应字段的 YAML 或 JSON。CR 上设置的字段优先于默认策略
+const podDefaultsLabel = "apps.myorg.io/pod-defaults"
+
+// podDefaults 是从默认策略 ConfigMap 解析出的 Pod 字段
+type podDefaults struct {
+	TerminationGracePeriodSeconds *int64
+	NodeSelector                  map[string]string
+	Tolerations                   []corev1.Toleration
+	Affinity                      *corev1.Affinity
+}
+
+// podDefaultsConfigMap 返回 namespace 下的默认策略 ConfigMap，不存在时返回 nil。
+// 同一 namespace 只允许一个，多个时无法确定优先级，返回错误
+func (c *CustomDeploymentController) podDefaultsConfigMap(ctx context.Context, namespace string) (*corev1.ConfigMap, error) {
+	list := &corev1.ConfigMapList{}
+	if err := c.List(ctx, list, client.InNamespace(namespace), client.MatchingLabels{podDefaultsLabel: "true"}); err != nil {
+		return nil, fmt.Errorf("list pod defaults ConfigMaps: %w", err)
+	}
+	switch len(list.Items) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &list.Items[0], nil
+	default:
+		return nil, fmt.Errorf("found %d ConfigMaps labeled %s=true in namespace %s, expected at most one", len(list.Items), podDefaultsLabel, namespace)
+	}
+}
+
+// parsePodDefaults 解析默认策略 ConfigMap
+func parsePodDefaults(cm *corev1.ConfigMap) (*podDefaults, error) {
+	defaults := &podDefaults{}
+	if v, ok := cm.Data["terminationGracePeriodSeconds"]; ok {
+		seconds, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("pod defaults %s: invalid terminationGracePeriodSeconds %q", cm.Name, v)
+		}
+		defaults.TerminationGracePeriodSeconds = &seconds
+	}
+	for key, out := range map[string]any{
+		"nodeSelector": &defaults.NodeSelector,
+		"tolerations":  &defaults.Tolerations,
+		"affinity":     &defaults.Affinity,
+	} {
+		v, ok := cm.Data[key]
+		if !ok {
+			continue
+		}
+		if err := yaml.UnmarshalStrict([]byte(v), out); err != nil {
+			return nil, fmt.Errorf("pod defaults %s: invalid %s: %w", cm.Name, key, err)
+		}
+	}
+	return defaults, nil
+}
+
+// applyPodDefaults 把 namespace 的默认策略合并到 Pod 模板中 CR 没有设置的字段
+func (c *CustomDeploymentController) applyPodDefaults(ctx context.Context, cd *appsv1alpha1.CustomDeployment, deploy *appsv1.Deployment) error {
+	cm, err := c.podDefaultsConfigMap(ctx, cd.Namespace)
+	if err != nil || cm == nil {
+		return err
+	}
+	defaults, err := parsePodDefaults(cm)
+	if err != nil {
+		return err
+	}
+
+	pod := &deploy.Spec.Template.Spec
+	if pod.TerminationGracePeriodSeconds == nil {
+		pod.TerminationGracePeriodSeconds = defaults.TerminationGracePeriodSeconds
+	}
+	if pod.NodeSelector == nil {
+		pod.NodeSelector = defaults.NodeSelector
+	}
+	if pod.Tolerations == nil {
+		pod.Tolerations = defaults.Tolerations
+	}
+	if pod.Affinity == nil {
+		pod.Affinity = defaults.Affinity
+	}
+	return nil
+}
+
+// syncPodScheduling 同步 nodeSelector、tolerations 和 affinity，返回是否有变化
+func syncPodScheduling(live, desired *appsv1.Deployment) bool {
+	livePod, desiredPod := &live.Spec.Template.Spec, &desired.Spec.Template.Spec
+	updated := false
+	if len(livePod.NodeSelector) != len(desiredPod.NodeSelector) ||
+		(len(desiredPod.NodeSelector) > 0 && !equality.Semantic.DeepEqual(livePod.NodeSelector, desiredPod.NodeSelector)) {
+		livePod.NodeSelector = desiredPod.NodeSelector
+		updated = true
+	}
+	if len(livePod.Tolerations) != len(desiredPod.Tolerations) ||
+		(len(desiredPod.Tolerations) > 0 && !equality.Semantic.DeepEqual(livePod.Tolerations, desiredPod.Tolerations)) {
+		livePod.Tolerations = desiredPod.Tolerations
+		updated = true
+	}
+	if !equality.Semantic.DeepEqual(livePod.Affinity, desiredPod.Affinity) {
+		livePod.Affinity = desiredPod.Affinity
+		updated = true
+	}
+	return updated
+}
+
+// podDefaultsToCustomDeployments 默认策略变化时调谐同 namespace 下的所有 CustomDeployment
+func (c *CustomDeploymentController) podDefaultsToCustomDeployments(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &appsv1alpha1.CustomDeploymentList{}
+	if err := c.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list CustomDeployments for pod defaults", "configmap", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, cd := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cd)})
+	}
+	return requests
+}
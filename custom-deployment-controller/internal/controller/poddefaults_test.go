@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcilePodDefaults(t *testing.T) {
+	defaults := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-defaults",
+			Namespace: testNamespace,
+			Labels:    map[string]string{podDefaultsLabel: "true"},
+		},
+		Data: map[string]string{
+			"terminationGracePeriodSeconds": "90",
+			"nodeSelector":                  "pool: general",
+			"tolerations":                   "- key: dedicated\n  operator: Exists\n  effect: NoSchedule",
+			"affinity":                      `{"nodeAffinity":{"preferredDuringSchedulingIgnoredDuringExecution":[{"weight":1,"preference":{"matchExpressions":[{"key":"zone","operator":"In","values":["a"]}]}}]}}`,
+		},
+	}
+	tests := []struct {
+		name            string
+		noDefaults      bool
+		mutate          func(*appsv1alpha1.CustomDeployment)
+		wantGrace       *int64
+		wantNodeSel     string
+		wantTolerations int
+		wantAntiAff     bool
+		wantNodeAff     bool
+	}{
+		{name: "no defaults ConfigMap", noDefaults: true},
+		{name: "namespace defaults apply", wantGrace: ptr.To[int64](90), wantNodeSel: "general", wantTolerations: 1, wantNodeAff: true},
+		{
+			name:            "per-CR grace period overrides",
+			mutate:          func(cd *appsv1alpha1.CustomDeployment) { cd.Spec.TerminationGracePeriodSeconds = ptr.To[int64](5) },
+			wantGrace:       ptr.To[int64](5),
+			wantNodeSel:     "general",
+			wantTolerations: 1,
+			wantNodeAff:     true,
+		},
+		{
+			// CR 生成的反亲和追加到默认策略的 affinity 上
+			name:            "per-CR spread merges with default affinity",
+			mutate:          func(cd *appsv1alpha1.CustomDeployment) { cd.Spec.SpreadAcrossNodes = true },
+			wantGrace:       ptr.To[int64](90),
+			wantNodeSel:     "general",
+			wantTolerations: 1,
+			wantAntiAff:     true,
+			wantNodeAff:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				if tt.mutate != nil {
+					tt.mutate(cd)
+				}
+			})}
+			if !tt.noDefaults {
+				objs = append(objs, defaults.DeepCopy())
+			}
+			env := newTestEnv(t, objs)
+			pod := env.reconcileUntilCreated(t, "web").Spec.Template.Spec
+
+			if !ptr.Equal(pod.TerminationGracePeriodSeconds, tt.wantGrace) {
+				t.Errorf("terminationGracePeriodSeconds = %v, want %v", ptr.Deref(pod.TerminationGracePeriodSeconds, -1), ptr.Deref(tt.wantGrace, -1))
+			}
+			if got := pod.NodeSelector["pool"]; got != tt.wantNodeSel {
+				t.Errorf("nodeSelector pool = %q, want %q", got, tt.wantNodeSel)
+			}
+			if len(pod.Tolerations) != tt.wantTolerations {
+				t.Errorf("tolerations = %v, want %d", pod.Tolerations, tt.wantTolerations)
+			}
+			if got := pod.Affinity != nil && pod.Affinity.PodAntiAffinity != nil; got != tt.wantAntiAff {
+				t.Errorf("pod anti-affinity present = %v, want %v", got, tt.wantAntiAff)
+			}
+			if got := pod.Affinity != nil && pod.Affinity.NodeAffinity != nil; got != tt.wantNodeAff {
+				t.Errorf("node affinity present = %v, want %v", got, tt.wantNodeAff)
+			}
+		})
+	}
+}
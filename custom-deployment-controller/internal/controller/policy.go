@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionPolicyViolation 表示 CR 违反了控制器配置的策略（如镜像仓库白名单），Deployment 不会被写入
+const ConditionPolicyViolation = "PolicyViolation"
+
+// registryAllowed 判断仓库是否在白名单中。带端口的条目（registry.local:5000）只匹配该端口，
+// 不带端口的条目（registry.local）匹配该主机的任意端口
+func registryAllowed(registry string, allowed []string) bool {
+	if slices.Contains(allowed, registry) {
+		return true
+	}
+	host, _, hasPort := strings.Cut(registry, ":")
+	return hasPort && slices.Contains(allowed, host)
+}
+
+// checkImagePolicy 校验镜像是否来自允许的仓库，未配置白名单时允许所有镜像
+func (c *CustomDeploymentController) checkImagePolicy(cd *appsv1alpha1.CustomDeployment) error {
+	if len(c.AllowedRegistries) == 0 {
+		return nil
+	}
+	image := containerImage(cd)
+	registry := imageRegistry(image)
+	if registryAllowed(registry, c.AllowedRegistries) {
+		return nil
+	}
+	return fmt.Errorf("image %q is from registry %q, which is not in the allowed registries %v", image, registry, c.AllowedRegistries)
+}
+
+// setPolicyCondition 根据策略检查结果设置 PolicyViolation 条件
+func setPolicyCondition(cd *appsv1alpha1.CustomDeployment, policyErr error) {
+	if policyErr != nil {
+		meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+			Type:               ConditionPolicyViolation,
+			Status:             metav1.ConditionTrue,
+			Reason:             "RegistryNotAllowed",
+			Message:            policyErr.Error(),
+			ObservedGeneration: cd.Generation,
+		})
+		return
+	}
+	if meta.FindStatusCondition(cd.Status.Conditions, ConditionPolicyViolation) != nil {
+		meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+			Type:               ConditionPolicyViolation,
+			Status:             metav1.ConditionFalse,
+			Reason:             "Compliant",
+			Message:            "Spec complies with the controller policy",
+			ObservedGeneration: cd.Generation,
+		})
+	}
+}
+
+// ParseRegistries 解析逗号分隔的仓库列表，去掉空白并统一为小写
+func ParseRegistries(s string) []string {
+	var registries []string
+	for _, r := range strings.Split(s, ",") {
+		if r = strings.ToLower(strings.TrimSpace(r)); r != "" {
+			registries = append(registries, r)
+		}
+	}
+	return registries
+}
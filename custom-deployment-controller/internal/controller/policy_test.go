@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+)
+
+func TestImageRegistry(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"nginx", "docker.io"},
+		{"nginx:1.25", "docker.io"},
+		{"library/nginx@sha256:abcd", "docker.io"},
+		{"ghcr.io/org/app:v1", "ghcr.io"},
+		{"Registry.Local:5000/app", "registry.local:5000"},
+		{"localhost/app", "localhost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := imageRegistry(tt.image); got != tt.want {
+				t.Fatalf("imageRegistry(%q) = %q, want %q", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckImagePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed string
+		image   string
+		wantErr bool
+	}{
+		{"no allowlist", "", "evil.example.com/app:v1", false},
+		{"allowed registry", "ghcr.io,registry.example.com", "registry.example.com/app:v1", false},
+		{"disallowed registry", "ghcr.io", "registry.example.com/app:v1", true},
+		{"docker hub by default name", "docker.io", "nginx:1.25", false},
+		{"docker hub not allowed", "ghcr.io", "nginx:1.25", true},
+		{"entry without port allows any port", "registry.local", "registry.local:5000/app", false},
+		{"entry with port allows that port", "registry.local:5000", "registry.local:5000/app", false},
+		{"entry with port rejects other ports", "registry.local:5000", "registry.local:5001/app", true},
+		{"entry with port rejects default port", "registry.local:5000", "registry.local/app", true},
+		{"entries are case-insensitive", "GHCR.io", "ghcr.io/org/app", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &CustomDeploymentController{AllowedRegistries: ParseRegistries(tt.allowed)}
+			cd := newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) { cd.Spec.Image = tt.image })
+			if err := c.checkImagePolicy(cd); (err != nil) != tt.wantErr {
+				t.Fatalf("checkImagePolicy(%q) with %q: err = %v, wantErr %v", tt.image, tt.allowed, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"fmt"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// validateContainerPort 校验 spec.containerPort，0 表示不声明端口
+func validateContainerPort(cd *appsv1alpha1.CustomDeployment) error {
+	port := cd.Spec.ContainerPort
+	if port < 0 || port > 65535 {
+		return fmt.Errorf("containerPort %d is out of range, must be between 1 and 65535", port)
+	}
+	if port == 0 && cd.Spec.PortName != "" {
+		return fmt.Errorf("portName %q requires containerPort", cd.Spec.PortName)
+	}
+	return nil
+}
+
+// containerPorts 返回受管容器声明的端口。显式写出 TCP 协议，与 API Server 的默认值一致，避免反复更新
+func containerPorts(cd *appsv1alpha1.CustomDeployment) []corev1.ContainerPort {
+	if cd.Spec.ContainerPort == 0 {
+		return nil
+	}
+	return []corev1.ContainerPort{{
+		Name:          cd.Spec.PortName,
+		ContainerPort: cd.Spec.ContainerPort,
+		Protocol:      corev1.ProtocolTCP,
+	}}
+}
+
+// syncContainerPorts 同步受管容器的端口，返回是否有变化。删除 spec.containerPort 会清空端口
+func syncContainerPorts(live, desired *appsv1.Deployment) bool {
+	liveContainer, desiredContainer := managedContainers(live, desired)
+	if liveContainer == nil || desiredContainer == nil {
+		return false
+	}
+	if len(liveContainer.Ports) == 0 && len(desiredContainer.Ports) == 0 {
+		return false
+	}
+	if equality.Semantic.DeepEqual(liveContainer.Ports, desiredContainer.Ports) {
+		return false
+	}
+	liveContainer.Ports = desiredContainer.Ports
+	return true
+}
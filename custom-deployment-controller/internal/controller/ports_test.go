@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileContainerPort(t *testing.T) {
+	tests := []struct {
+		name            string
+		initial         int32
+		updated         int32
+		updatedName     string
+		want            []corev1.ContainerPort
+		wantUpdates     int
+		wantInvalidSpec string
+	}{
+		{name: "unchanged", initial: 8080, updated: 8080, want: []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}}},
+		{name: "added", updated: 8080, updatedName: "http", want: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080, Protocol: corev1.ProtocolTCP}}, wantUpdates: 1},
+		{name: "changed", initial: 8080, updated: 9090, want: []corev1.ContainerPort{{ContainerPort: 9090, Protocol: corev1.ProtocolTCP}}, wantUpdates: 1},
+		{name: "removed", initial: 8080, wantUpdates: 1},
+		{
+			// 越界的端口不会写入 Deployment，原端口保持不变
+			name:            "out of range",
+			initial:         8080,
+			updated:         70000,
+			want:            []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}},
+			wantInvalidSpec: "out of range",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.ContainerPort = tt.initial
+			})})
+			env.reconcileUntilCreated(t, "web")
+			env.writes.reset()
+
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.ContainerPort = tt.updated
+				cd.Spec.PortName = tt.updatedName
+			})
+			env.reconcile(t, "web")
+
+			if got := env.deployment(t, "web").Spec.Template.Spec.Containers[0].Ports; !equality.Semantic.DeepEqual(got, tt.want) {
+				t.Fatalf("ports = %v, want %v", got, tt.want)
+			}
+			if got := env.writes.get("update/Deployment"); got != tt.wantUpdates {
+				t.Fatalf("Deployment updates = %d, want %d", got, tt.wantUpdates)
+			}
+			conditions := env.customDeployment(t, "web").Status.Conditions
+			if tt.wantInvalidSpec == "" {
+				if meta.IsStatusConditionTrue(conditions, ConditionInvalidSpec) {
+					t.Fatalf("unexpected InvalidSpec condition")
+				}
+				return
+			}
+			cond := meta.FindStatusCondition(conditions, ConditionInvalidSpec)
+			if cond == nil || cond.Status != metav1.ConditionTrue || !strings.Contains(cond.Message, tt.wantInvalidSpec) {
+				t.Fatalf("InvalidSpec condition = %+v, want one mentioning %q", cond, tt.wantInvalidSpec)
+			}
+			if !containsEvent(env.events(), "InvalidSpec") {
+				t.Fatalf("expected an InvalidSpec event")
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/utils/ptr"
+)
+
+// applyProbes 把 spec 中设置的探针写入受管容器，未设置的保留 spec.template 中的值
+func applyProbes(cd *appsv1alpha1.CustomDeployment, container *corev1.Container) {
+	if cd.Spec.LivenessProbe != nil {
+		container.LivenessProbe = cd.Spec.LivenessProbe.DeepCopy()
+	}
+	if cd.Spec.ReadinessProbe != nil {
+		container.ReadinessProbe = cd.Spec.ReadinessProbe.DeepCopy()
+	}
+}
+
+// probeWithDefaults 返回填充了 API Server 默认值的探针副本，用于比较，避免未设置的字段被默认填充后反复更新
+func probeWithDefaults(p *corev1.Probe) *corev1.Probe {
+	if p == nil {
+		return nil
+	}
+	p = p.DeepCopy()
+	if p.TimeoutSeconds == 0 {
+		p.TimeoutSeconds = 1
+	}
+	if p.PeriodSeconds == 0 {
+		p.PeriodSeconds = 10
+	}
+	if p.SuccessThreshold == 0 {
+		p.SuccessThreshold = 1
+	}
+	if p.FailureThreshold == 0 {
+		p.FailureThreshold = 3
+	}
+	if p.HTTPGet != nil {
+		if p.HTTPGet.Path == "" {
+			p.HTTPGet.Path = "/"
+		}
+		if p.HTTPGet.Scheme == "" {
+			p.HTTPGet.Scheme = corev1.URISchemeHTTP
+		}
+	}
+	if p.GRPC != nil && p.GRPC.Service == nil {
+		p.GRPC.Service = ptr.To("")
+	}
+	return p
+}
+
+// syncProbes 同步受管容器的存活和就绪探针，返回是否有变化。删除 spec 中的探针会移除线上的探针
+func syncProbes(live, desired *appsv1.Deployment) bool {
+	liveContainer, desiredContainer := managedContainers(live, desired)
+	if liveContainer == nil || desiredContainer == nil {
+		return false
+	}
+	updated := false
+	if !equality.Semantic.DeepEqual(probeWithDefaults(liveContainer.LivenessProbe), probeWithDefaults(desiredContainer.LivenessProbe)) {
+		liveContainer.LivenessProbe = desiredContainer.LivenessProbe
+		updated = true
+	}
+	if !equality.Semantic.DeepEqual(probeWithDefaults(liveContainer.ReadinessProbe), probeWithDefaults(desiredContainer.ReadinessProbe)) {
+		liveContainer.ReadinessProbe = desiredContainer.ReadinessProbe
+		updated = true
+	}
+	return updated
+}
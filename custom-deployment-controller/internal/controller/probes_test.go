@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// httpProbe 返回访问 path 的 HTTP 探针
+func httpProbe(path string) *corev1.Probe {
+	return &corev1.Probe{ProbeHandler: corev1.ProbeHandler{
+		HTTPGet: &corev1.HTTPGetAction{Path: path, Port: intstr.FromInt32(8080)},
+	}}
+}
+
+func TestReconcileReadinessProbe(t *testing.T) {
+	tests := []struct {
+		name        string
+		initial     *corev1.Probe
+		updated     *corev1.Probe
+		wantUpdates int
+	}{
+		{name: "added", updated: httpProbe("/ready"), wantUpdates: 1},
+		{name: "modified", initial: httpProbe("/ready"), updated: httpProbe("/healthz"), wantUpdates: 1},
+		{name: "removed", initial: httpProbe("/ready"), wantUpdates: 1},
+		{name: "unchanged", initial: httpProbe("/ready"), updated: httpProbe("/ready")},
+		{name: "never set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.ReadinessProbe = tt.initial
+			})})
+			deploy := env.reconcileUntilCreated(t, "web")
+			if got := deploy.Spec.Template.Spec.Containers[0].ReadinessProbe; !equality.Semantic.DeepEqual(got, tt.initial) {
+				t.Fatalf("after create: readinessProbe = %v, want %v", got, tt.initial)
+			}
+
+			env.writes.reset()
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.ReadinessProbe = tt.updated
+			})
+			env.reconcile(t, "web")
+			if got := env.deployment(t, "web").Spec.Template.Spec.Containers[0].ReadinessProbe; !equality.Semantic.DeepEqual(got, tt.updated) {
+				t.Fatalf("after update: readinessProbe = %v, want %v", got, tt.updated)
+			}
+			if got := env.writes.get("update/Deployment"); got != tt.wantUpdates {
+				t.Fatalf("Deployment updates = %d, want %d", got, tt.wantUpdates)
+			}
+		})
+	}
+}
+
+func TestReconcileProbeServerDefaults(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+		cd.Spec.ReadinessProbe = httpProbe("/ready")
+	})})
+	deploy := env.reconcileUntilCreated(t, "web")
+
+	// 模拟 API Server 填充探针的默认值
+	container := &deploy.Spec.Template.Spec.Containers[0]
+	container.ReadinessProbe = probeWithDefaults(container.ReadinessProbe)
+	deploy.Generation++
+	if err := env.c.Update(context.Background(), deploy); err != nil {
+		t.Fatal(err)
+	}
+
+	env.writes.reset()
+	env.reconcile(t, "web")
+	if got := env.writes.get("update/Deployment"); got != 0 {
+		t.Fatalf("Deployment updates = %d, want none for server-defaulted probe fields", got)
+	}
+}
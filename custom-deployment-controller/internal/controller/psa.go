@@ -0,0 +1,51 @@
+package controller
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/utils/ptr"
+)
+
+// applyPSADefaults 写入满足 restricted Pod Security Standard 的 securityContext：
+// 以非 root 运行、seccomp 使用 RuntimeDefault、禁止提权并丢弃全部 capability。
+// 镜像本身需要支持以非 root 用户运行，否则 Pod 会启动失败。spec.template 中已经设置的 securityContext 保持不变
+func applyPSADefaults(deploy *appsv1.Deployment) {
+	pod := &deploy.Spec.Template.Spec
+	if pod.SecurityContext == nil {
+		pod.SecurityContext = &corev1.PodSecurityContext{
+			RunAsNonRoot:   ptr.To(true),
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		}
+	}
+	for i := range pod.Containers {
+		if pod.Containers[i].SecurityContext != nil {
+			continue
+		}
+		pod.Containers[i].SecurityContext = &corev1.SecurityContext{
+			RunAsNonRoot:             ptr.To(true),
+			AllowPrivilegeEscalation: ptr.To(false),
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		}
+	}
+}
+
+// syncSecurityContext 同步 Pod 和受管容器的 securityContext，返回是否有变化。
+// API Server 会把未设置的 Pod securityContext 默认为空对象，按空对象比较避免反复更新
+func syncSecurityContext(live, desired *appsv1.Deployment) bool {
+	updated := false
+	livePod, desiredPod := &live.Spec.Template.Spec, &desired.Spec.Template.Spec
+	if !equality.Semantic.DeepEqual(ptr.Deref(livePod.SecurityContext, corev1.PodSecurityContext{}),
+		ptr.Deref(desiredPod.SecurityContext, corev1.PodSecurityContext{})) {
+		livePod.SecurityContext = desiredPod.SecurityContext
+		updated = true
+	}
+	liveContainer, desiredContainer := managedContainers(live, desired)
+	if liveContainer != nil && desiredContainer != nil &&
+		!equality.Semantic.DeepEqual(liveContainer.SecurityContext, desiredContainer.SecurityContext) {
+		liveContainer.SecurityContext = desiredContainer.SecurityContext
+		updated = true
+	}
+	return updated
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcilePSADefaults(t *testing.T) {
+	restrictedPod := &corev1.PodSecurityContext{
+		RunAsNonRoot:   ptr.To(true),
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+	restrictedContainer := &corev1.SecurityContext{
+		RunAsNonRoot:             ptr.To(true),
+		AllowPrivilegeEscalation: ptr.To(false),
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+	custom := &corev1.PodSecurityContext{RunAsUser: ptr.To[int64](1000)}
+	tests := []struct {
+		name          string
+		enabled       bool
+		podContext    *corev1.PodSecurityContext
+		wantPod       *corev1.PodSecurityContext
+		wantContainer *corev1.SecurityContext
+	}{
+		{name: "disabled"},
+		{name: "enabled", enabled: true, wantPod: restrictedPod, wantContainer: restrictedContainer},
+		// CR 通过 spec.template 设置的 securityContext 优先
+		{name: "template securityContext kept", enabled: true, podContext: custom, wantPod: custom, wantContainer: restrictedContainer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				if tt.podContext != nil {
+					cd.Spec.Template = &corev1.PodTemplateSpec{Spec: corev1.PodSpec{SecurityContext: tt.podContext}}
+				}
+			})})
+			env.c.PSADefaults = tt.enabled
+			pod := env.reconcileUntilCreated(t, "web").Spec.Template.Spec
+
+			if !equality.Semantic.DeepEqual(pod.SecurityContext, tt.wantPod) {
+				t.Fatalf("pod securityContext = %+v, want %+v", pod.SecurityContext, tt.wantPod)
+			}
+			if got := pod.Containers[0].SecurityContext; !equality.Semantic.DeepEqual(got, tt.wantContainer) {
+				t.Fatalf("container securityContext = %+v, want %+v", got, tt.wantContainer)
+			}
+		})
+	}
+}
+
+func TestReconcilePSADefaultsEnabledLater(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+	env.reconcileUntilCreated(t, "web")
+
+	// 打开开关后已有的 Deployment 也会补上默认值
+	env.c.PSADefaults = true
+	env.writes.reset()
+	env.reconcile(t, "web")
+	pod := env.deployment(t, "web").Spec.Template.Spec
+	if pod.SecurityContext == nil || !ptr.Deref(pod.SecurityContext.RunAsNonRoot, false) || pod.Containers[0].SecurityContext == nil {
+		t.Fatalf("securityContext = %+v / %+v, want the restricted defaults", pod.SecurityContext, pod.Containers[0].SecurityContext)
+	}
+	if got := env.writes.get("update/Deployment"); got != 1 {
+		t.Fatalf("Deployment updates = %d, want 1", got)
+	}
+
+	// 默认值已经写入后不再更新
+	env.writes.reset()
+	env.reconcile(t, "web")
+	if got := env.writes.get("update/Deployment"); got != 0 {
+		t.Fatalf("Deployment updates on a no-op reconcile = %d, want 0", got)
+	}
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// status.reconcileCount 的写入节流：累计到 reconcileCountFlushEvery 次或距上次写入超过
+// reconcileCountFlushInterval 时才写入，其余时候随其他状态变化一起写入
+const (
+	reconcileCountFlushEvery    = 10
+	reconcileCountFlushInterval = time.Minute
+)
+
+type objectReconcileCount struct {
+	count     int64
+	flushedAt time.Time
+}
+
+// reconcileCounter 在内存中累计每个对象的调谐次数
+type reconcileCounter struct {
+	mu     sync.Mutex
+	counts map[types.NamespacedName]*objectReconcileCount
+}
+
+// observe 记录一次调谐；persisted 是状态中已有的计数，控制器重启后从它继续累计
+func (r *reconcileCounter) observe(key types.NamespacedName, persisted int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.counts == nil {
+		r.counts = map[types.NamespacedName]*objectReconcileCount{}
+	}
+	c, ok := r.counts[key]
+	if !ok {
+		c = &objectReconcileCount{flushedAt: time.Now()}
+		r.counts[key] = c
+	}
+	if persisted > c.count {
+		c.count = persisted
+	}
+	c.count++
+}
+
+// statusValue 返回应该写入状态的计数：满足节流条件时返回最新计数，否则保持 persisted 不变
+func (r *reconcileCounter) statusValue(key types.NamespacedName, persisted int64, now time.Time) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.counts[key]
+	if !ok || c.count <= persisted {
+		return persisted
+	}
+	if c.count-persisted < reconcileCountFlushEvery && now.Sub(c.flushedAt) < reconcileCountFlushInterval {
+		return persisted
+	}
+	c.flushedAt = now
+	return c.count
+}
+
+// forget 在对象被删除后清理计数
+func (r *reconcileCounter) forget(key types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.counts, key)
+}
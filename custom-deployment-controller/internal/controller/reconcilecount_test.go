@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileCountPersistedWithThrottling(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+	env.reconcileUntilCreated(t, "web")
+	env.reconcile(t, "web")
+
+	env.writes.reset()
+	const reconciles = 25
+	for i := 0; i < reconciles; i++ {
+		env.reconcile(t, "web")
+	}
+
+	// 28 次调谐，每累计 10 次写入一次：持久化的计数落后不超过一个批次
+	count := env.customDeployment(t, "web").Status.ReconcileCount
+	if total := int64(reconciles + 3); count < total-reconcileCountFlushEvery || count > total {
+		t.Fatalf("status.reconcileCount = %d after %d reconciles", count, total)
+	}
+	if writes := env.writes.get("status/CustomDeployment"); writes > reconciles/reconcileCountFlushEvery+1 {
+		t.Fatalf("%d reconciles produced %d status writes", reconciles, writes)
+	}
+}
+
+func TestReconcileCounterStatusValue(t *testing.T) {
+	key := types.NamespacedName{Namespace: testNamespace, Name: "web"}
+	start := time.Now()
+	tests := []struct {
+		name      string
+		observed  int
+		persisted int64
+		now       time.Time
+		want      int64
+	}{
+		{"below both thresholds", 3, 0, start, 0},
+		{"count threshold reached", reconcileCountFlushEvery, 0, start, reconcileCountFlushEvery},
+		{"interval elapsed", 3, 0, start.Add(reconcileCountFlushInterval + time.Second), 3},
+		{"continues from persisted value after restart", 1, 40, start.Add(reconcileCountFlushInterval + time.Second), 41},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r reconcileCounter
+			for i := 0; i < tt.observed; i++ {
+				r.observe(key, tt.persisted)
+			}
+			if got := r.statusValue(key, tt.persisted, tt.now); got != tt.want {
+				t.Fatalf("statusValue = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
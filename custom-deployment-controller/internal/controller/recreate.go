@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// allowRecreateAnnotation 设置为 true 时，Deployment 因不可变字段（如 selector）无法更新时会被删除重建
+const allowRecreateAnnotation = "apps.myorg.io/allow-recreate"
+
+func allowRecreate(cd *appsv1alpha1.CustomDeployment) bool {
+	return cd.Annotations[allowRecreateAnnotation] == "true"
+}
+
+// isImmutableFieldError 判断 Update 是否因为修改了不可变字段而被 API Server 拒绝
+func isImmutableFieldError(err error) bool {
+	return errors.IsInvalid(err) && strings.Contains(err.Error(), "field is immutable")
+}
+
+// recreateDeployment 删除无法原地更新的 Deployment，删除完成后下一次调谐会重新创建。
+// 使用前台删除，旧 Pod 全部退出后 Deployment 才会消失，避免新旧 Pod 同时挂载同一个卷；
+// 挂载了 PVC 的 Deployment 不会被重建，需要人工处理。
+func (c *CustomDeploymentController) recreateDeployment(ctx context.Context, deploy *appsv1.Deployment) error {
+	logger := log.FromContext(ctx)
+
+	for _, v := range deploy.Spec.Template.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil {
+			return fmt.Errorf("deployment %s mounts PersistentVolumeClaim %q, refusing to recreate it automatically", deploy.Name, v.PersistentVolumeClaim.ClaimName)
+		}
+	}
+
+	err := c.Delete(ctx, deploy,
+		client.PropagationPolicy(metav1.DeletePropagationForeground),
+		client.Preconditions{UID: &deploy.UID})
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to delete Deployment for recreation")
+		return err
+	}
+	logger.Info("Deployment has immutable field changes, deleted for recreation", "name", deploy.Name)
+	return nil
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// immutableUpdates 让 Deployment 的 Update 像修改了 selector 一样被拒绝
+func immutableUpdates() interceptor.Funcs {
+	return interceptor.Funcs{
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if _, ok := obj.(*appsv1.Deployment); ok {
+				return apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, obj.GetName(), field.ErrorList{
+					field.Invalid(field.NewPath("spec", "selector"), nil, "field is immutable"),
+				})
+			}
+			return c.Update(ctx, obj, opts...)
+		},
+	}
+}
+
+func TestReconcileRecreatesOnImmutableFieldChange(t *testing.T) {
+	tests := []struct {
+		name         string
+		allow        bool
+		pvc          bool
+		wantErr      bool
+		wantRecreate bool
+	}{
+		{name: "recreate allowed", allow: true, wantRecreate: true},
+		{name: "recreate not allowed", wantErr: true},
+		{name: "PVC mounted", allow: true, pvc: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				if tt.allow {
+					cd.Annotations = map[string]string{allowRecreateAnnotation: "true"}
+				}
+				if tt.pvc {
+					cd.Spec.Template = &corev1.PodTemplateSpec{Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+						Name:         "data",
+						VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+					}}}}
+				}
+			})}, withInterceptor(immutableUpdates()))
+			env.reconcileUntilCreated(t, "web")
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.Image = "registry.example.com/app:v2"
+			})
+
+			_, err := env.c.Reconcile(context.Background(), requestFor("web"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reconcile error = %v, want error %v", err, tt.wantErr)
+			}
+			deleted := apierrors.IsNotFound(env.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "web"}, &appsv1.Deployment{}))
+			if deleted != tt.wantRecreate {
+				t.Fatalf("Deployment deleted = %v, want %v", deleted, tt.wantRecreate)
+			}
+			if !tt.wantRecreate {
+				return
+			}
+
+			env.reconcile(t, "web")
+			if got := env.deployment(t, "web").Spec.Template.Spec.Containers[0].Image; got != "registry.example.com/app:v2" {
+				t.Fatalf("recreated Deployment image = %q, want the new image", got)
+			}
+		})
+	}
+}
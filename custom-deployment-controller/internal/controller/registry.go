@@ -0,0 +1,292 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultRegistry 是镜像引用不带仓库地址时使用的默认仓库
+const defaultRegistry = "docker.io"
+
+// dockerHubHost 是 docker.io 镜像实际使用的 Registry API 地址
+const dockerHubHost = "registry-1.docker.io"
+
+// DefaultDigestCacheTTL 是 RegistryResolver 缓存解析结果的默认时间。
+// 调谐很频繁，缓存避免每次都访问仓库；tag 指向新 digest 后最多延迟这么久被记录
+const DefaultDigestCacheTTL = 5 * time.Minute
+
+// DefaultRegistryTimeout 是一次解析（包括获取 token）的默认超时时间，解析在调谐中同步进行，不能无限等待仓库响应
+const DefaultRegistryTimeout = 10 * time.Second
+
+// DefaultTokenRealmHosts 是默认允许的 token 服务地址，Docker Hub 的 token 不由仓库本身签发
+var DefaultTokenRealmHosts = []string{"auth.docker.io"}
+
+// manifestMediaTypes 是解析 digest 时接受的 manifest 类型，优先返回多架构的 index
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// RegistryResolver 通过 OCI Distribution（Docker Registry v2）API 解析镜像 digest：
+// 对 manifest 发 HEAD 请求并读取 Docker-Content-Digest 响应头。
+// 支持匿名的 Bearer token 认证（Docker Hub、GHCR 等公开镜像），不支持需要凭据的私有仓库
+type RegistryResolver struct {
+	// HTTPClient 可选，为空时使用带 DefaultRegistryTimeout 超时的 client
+	HTTPClient *http.Client
+	// CacheTTL 是解析结果的缓存时间，为 0 时使用 DefaultDigestCacheTTL
+	CacheTTL time.Duration
+	// Timeout 是一次解析的超时时间，为 0 时使用 DefaultRegistryTimeout
+	Timeout time.Duration
+	// TokenRealmHosts 是 WWW-Authenticate 中 realm 允许指向的其他主机，与仓库相同的主机总是允许。
+	// 为 nil 时使用 DefaultTokenRealmHosts；realm 由仓库返回，不校验会让控制器请求任意地址
+	TokenRealmHosts []string
+
+	mu    sync.Mutex
+	cache map[string]cachedDigest
+}
+
+type cachedDigest struct {
+	digest    string
+	expiresAt time.Time
+}
+
+// Resolve 返回镜像当前的 digest；镜像引用本身带 digest 时直接返回
+func (r *RegistryResolver) Resolve(ctx context.Context, image string) (string, error) {
+	if _, digest, ok := strings.Cut(image, "@"); ok {
+		return digest, nil
+	}
+	if digest, ok := r.cached(image); ok {
+		return digest, nil
+	}
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRegistryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	host, repository, tag := parseImageReference(image)
+	digest, err := r.headManifest(ctx, host, repository, tag)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", image, err)
+	}
+	r.store(image, digest)
+	return digest, nil
+}
+
+func (r *RegistryResolver) cached(image string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.cache[image]
+	if !ok || time.Now().After(c.expiresAt) {
+		return "", false
+	}
+	return c.digest, true
+}
+
+func (r *RegistryResolver) store(image, digest string) {
+	ttl := r.CacheTTL
+	if ttl <= 0 {
+		ttl = DefaultDigestCacheTTL
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cache == nil {
+		r.cache = map[string]cachedDigest{}
+	}
+	r.cache[image] = cachedDigest{digest: digest, expiresAt: time.Now().Add(ttl)}
+}
+
+func (r *RegistryResolver) client() *http.Client {
+	if r.HTTPClient != nil {
+		return r.HTTPClient
+	}
+	return defaultRegistryClient
+}
+
+var defaultRegistryClient = &http.Client{Timeout: DefaultRegistryTimeout}
+
+// imageRegistry 解析镜像引用中的仓库地址，支持 tag（nginx:1.25）和 digest（nginx@sha256:...）两种形式。
+// 第一段包含 "." 或 ":"，或者为 localhost 时视为仓库地址，否则是 Docker Hub 上的镜像。
+func imageRegistry(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	first, _, found := strings.Cut(name, "/")
+	if !found {
+		return defaultRegistry
+	}
+	if strings.ContainsAny(first, ".:") || first == "localhost" {
+		return strings.ToLower(first)
+	}
+	return defaultRegistry
+}
+
+// parseImageReference 把 tag 形式的镜像引用拆成 Registry API 地址、仓库路径和 tag，没有 tag 时使用 latest
+func parseImageReference(image string) (host, repository, tag string) {
+	name, tag := image, "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name, tag = image[:i], image[i+1:]
+	}
+
+	host = imageRegistry(name)
+	repository = name
+	if first, rest, found := strings.Cut(name, "/"); found && strings.ToLower(first) == host {
+		repository = rest
+	}
+	if host == defaultRegistry {
+		host = dockerHubHost
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+	return host, repository, tag
+}
+
+// headManifest 请求 manifest 的 digest，收到 401 时按 WWW-Authenticate 匿名获取 token 后重试一次
+func (r *RegistryResolver) headManifest(ctx context.Context, host, repository, tag string) (string, error) {
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repository, tag)
+	resp, err := r.do(ctx, http.MethodHead, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := r.token(ctx, host, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", err
+		}
+		if resp, err = r.do(ctx, http.MethodHead, manifestURL, token); err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HEAD %s: unexpected status %s", manifestURL, resp.Status)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("HEAD %s: response has no Docker-Content-Digest header", manifestURL)
+	}
+	return digest, nil
+}
+
+func (r *RegistryResolver) do(ctx context.Context, method, rawURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r.client().Do(req)
+}
+
+// token 按 Bearer 质询中的 realm、service 和 scope 匿名获取 token，realm 必须是 https 且指向仓库本身或允许的主机
+func (r *RegistryResolver) token(ctx context.Context, registryHost, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported registry authentication %q", challenge)
+	}
+	values := parseChallengeParams(params)
+	realm := values["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry authentication challenge has no realm: %q", challenge)
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %w", realm, err)
+	}
+	if !r.allowedRealm(registryHost, tokenURL) {
+		return "", fmt.Errorf("token realm %q is not allowed for registry %s", realm, registryHost)
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if v := values[key]; v != "" {
+			query.Set(key, v)
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: unexpected status %s", tokenURL.Redacted(), resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("registry token response has no token")
+}
+
+// allowedRealm 判断 token 地址是否可以访问：只允许 https，主机与仓库相同或在 TokenRealmHosts 中
+func (r *RegistryResolver) allowedRealm(registryHost string, realm *url.URL) bool {
+	if realm.Scheme != "https" {
+		return false
+	}
+	if strings.EqualFold(realm.Host, registryHost) {
+		return true
+	}
+	hosts := r.TokenRealmHosts
+	if hosts == nil {
+		hosts = DefaultTokenRealmHosts
+	}
+	for _, h := range hosts {
+		if strings.EqualFold(realm.Host, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseChallengeParams 解析 key="value" 形式、逗号分隔的质询参数，值中可以包含逗号
+func parseChallengeParams(s string) map[string]string {
+	params := map[string]string{}
+	for s = strings.TrimSpace(s); s != ""; {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			rest = "," + rest
+		}
+		params[key] = value
+		s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), ","))
+	}
+	return params
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		image                     string
+		host, repository, wantTag string
+	}{
+		{"nginx", dockerHubHost, "library/nginx", "latest"},
+		{"nginx:1.25", dockerHubHost, "library/nginx", "1.25"},
+		{"bitnami/redis:7", dockerHubHost, "bitnami/redis", "7"},
+		{"docker.io/library/nginx:1.25", dockerHubHost, "library/nginx", "1.25"},
+		{"ghcr.io/org/app:v1", "ghcr.io", "org/app", "v1"},
+		{"registry.local:5000/app", "registry.local:5000", "app", "latest"},
+		{"registry.local:5000/team/app:v2", "registry.local:5000", "team/app", "v2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			host, repository, tag := parseImageReference(tt.image)
+			if host != tt.host || repository != tt.repository || tag != tt.wantTag {
+				t.Fatalf("parseImageReference(%q) = %q, %q, %q; want %q, %q, %q",
+					tt.image, host, repository, tag, tt.host, tt.repository, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestParseChallengeParams(t *testing.T) {
+	got := parseChallengeParams(`realm="https://auth.example.com/token",service="registry.example.com",scope="repository:org/app:pull,push"`)
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:org/app:pull,push",
+	}
+	if !maps.Equal(got, want) {
+		t.Fatalf("parseChallengeParams = %v, want %v", got, want)
+	}
+}
+
+// fakeRegistry 模拟需要匿名 Bearer token 的仓库，digest 可以在测试中修改
+type fakeRegistry struct {
+	digest        string
+	manifestHeads int
+	// realm 不为空时替代质询中的 token 地址
+	realm string
+}
+
+func (f *fakeRegistry) start(t *testing.T) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:org/app:pull" {
+			http.Error(w, "bad scope", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"token":"anonymous"}`)
+	})
+	mux.HandleFunc("/v2/org/app/manifests/v1", func(w http.ResponseWriter, r *http.Request) {
+		f.manifestHeads++
+		if r.Header.Get("Authorization") != "Bearer anonymous" {
+			realm := srv.URL + "/token"
+			if f.realm != "" {
+				realm = f.realm
+			}
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s",service="test",scope="repository:org/app:pull"`, realm))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+			http.Error(w, "missing Accept", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", f.digest)
+	})
+	srv = httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRegistryResolver(t *testing.T) {
+	registry := &fakeRegistry{digest: "sha256:aaaa"}
+	srv := registry.start(t)
+	host := strings.TrimPrefix(srv.URL, "https://")
+	resolver := &RegistryResolver{HTTPClient: srv.Client()}
+
+	tests := []struct {
+		name    string
+		image   string
+		want    string
+		wantErr bool
+	}{
+		{"tag with token auth", host + "/org/app:v1", "sha256:aaaa", false},
+		{"digest reference", host + "/org/app@sha256:bbbb", "sha256:bbbb", false},
+		{"unknown tag", host + "/org/app:missing", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.Resolve(context.Background(), tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve(%q) error = %v, wantErr %v", tt.image, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Resolve(%q) = %q, want %q", tt.image, got, tt.want)
+			}
+		})
+	}
+
+	// 缓存期内不再访问仓库
+	heads := registry.manifestHeads
+	if _, err := resolver.Resolve(context.Background(), host+"/org/app:v1"); err != nil {
+		t.Fatal(err)
+	}
+	if registry.manifestHeads != heads {
+		t.Fatalf("expected a cache hit, registry was queried %d more times", registry.manifestHeads-heads)
+	}
+}
+
+func TestRegistryResolverRejectsForeignRealm(t *testing.T) {
+	var tokenRequests int
+	other := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		fmt.Fprint(w, `{"token":"anonymous"}`)
+	}))
+	t.Cleanup(other.Close)
+	otherHost := strings.TrimPrefix(other.URL, "https://")
+
+	tests := []struct {
+		name        string
+		realm       string
+		realmHosts  []string
+		wantErr     bool
+		wantFetches int
+	}{
+		{"foreign host", other.URL + "/token", nil, true, 0},
+		{"plain http", "http://" + otherHost + "/token", []string{otherHost}, true, 0},
+		{"allowed host", other.URL + "/token", []string{otherHost}, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenRequests = 0
+			registry := &fakeRegistry{digest: "sha256:aaaa", realm: tt.realm}
+			srv := registry.start(t)
+			resolver := &RegistryResolver{HTTPClient: srv.Client(), TokenRealmHosts: tt.realmHosts}
+
+			image := strings.TrimPrefix(srv.URL, "https://") + "/org/app:v1"
+			_, err := resolver.Resolve(context.Background(), image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve(%q) error = %v, wantErr %v", image, err, tt.wantErr)
+			}
+			if tokenRequests != tt.wantFetches {
+				t.Fatalf("token endpoint was requested %d times, want %d", tokenRequests, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestRegistryResolverTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	resolver := &RegistryResolver{HTTPClient: srv.Client(), Timeout: 50 * time.Millisecond}
+	image := strings.TrimPrefix(srv.URL, "https://") + "/org/app:v1"
+	start := time.Now()
+	if _, err := resolver.Resolve(context.Background(), image); err == nil {
+		t.Fatal("expected a timeout error from an unresponsive registry")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Resolve took %s, want it bounded by the timeout", elapsed)
+	}
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"fmt"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// containerResources 返回容器的资源需求：CR（或 spec.template 中的受管容器）设置了 resources 时原样使用，
+// 否则使用控制器的默认 requests，保证在要求设置 requests 的 ResourceQuota 下也能创建 Pod
+func (c *CustomDeploymentController) containerResources(cd *appsv1alpha1.CustomDeployment) corev1.ResourceRequirements {
+	resources := specOrTemplateResources(cd)
+	if len(resources.Requests) == 0 && len(resources.Limits) == 0 && len(c.DefaultRequests) > 0 {
+		resources.Requests = c.DefaultRequests.DeepCopy()
+	}
+	// 只设置了 limits 的资源，API Server 会把 requests 默认为同样的值，提前补上避免每次调谐都认为有差异
+	for name, limit := range resources.Limits {
+		if _, ok := resources.Requests[name]; ok {
+			continue
+		}
+		if resources.Requests == nil {
+			resources.Requests = corev1.ResourceList{}
+		}
+		resources.Requests[name] = limit.DeepCopy()
+	}
+	return resources
+}
+
+// syncContainerResources 同步受管容器的资源需求，返回是否有变化
+func syncContainerResources(live, desired *appsv1.Deployment) bool {
+	liveContainer, desiredContainer := managedContainers(live, desired)
+	if liveContainer == nil || desiredContainer == nil {
+		return false
+	}
+	if equality.Semantic.DeepEqual(liveContainer.Resources, desiredContainer.Resources) {
+		return false
+	}
+	liveContainer.Resources = desiredContainer.Resources
+	return true
+}
+
+func findContainer(deploy *appsv1.Deployment, name string) *corev1.Container {
+	for i := range deploy.Spec.Template.Spec.Containers {
+		if deploy.Spec.Template.Spec.Containers[i].Name == name {
+			return &deploy.Spec.Template.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+// ParseResourceRequests 解析 cpu 和 memory 的默认 requests，为空的值不设置
+func ParseResourceRequests(cpu, memory string) (corev1.ResourceList, error) {
+	requests := corev1.ResourceList{}
+	for name, value := range map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    cpu,
+		corev1.ResourceMemory: memory,
+	} {
+		if value == "" {
+			continue
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default %s request %q: %w", name, value, err)
+		}
+		requests[name] = q
+	}
+	return requests, nil
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileDefaultRequests(t *testing.T) {
+	defaults := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+		corev1.ResourceMemory: resource.MustParse("128Mi"),
+	}
+	custom := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}
+	tests := []struct {
+		name      string
+		defaults  corev1.ResourceList
+		resources *corev1.ResourceRequirements
+		want      corev1.ResourceRequirements
+	}{
+		{name: "resources omitted", defaults: defaults, want: corev1.ResourceRequirements{Requests: defaults}},
+		{name: "per-CR requests override", defaults: defaults, resources: &corev1.ResourceRequirements{Requests: custom}, want: corev1.ResourceRequirements{Requests: custom}},
+		{
+			name:      "per-CR limits override and default requests",
+			defaults:  defaults,
+			resources: &corev1.ResourceRequirements{Limits: custom},
+			want:      corev1.ResourceRequirements{Requests: custom, Limits: custom},
+		},
+		{name: "no controller defaults", want: corev1.ResourceRequirements{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				if tt.resources != nil {
+					cd.Spec.Resources = *tt.resources
+				}
+			})})
+			env.c.DefaultRequests = tt.defaults
+			deploy := env.reconcileUntilCreated(t, "web")
+
+			if got := deploy.Spec.Template.Spec.Containers[0].Resources; !equality.Semantic.DeepEqual(got, tt.want) {
+				t.Fatalf("resources = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileResourcesUpdate(t *testing.T) {
+	requests := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")}
+	limits := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}
+	steps := []struct {
+		name        string
+		resources   corev1.ResourceRequirements
+		wantUpdates int
+	}{
+		{"requests only", corev1.ResourceRequirements{Requests: requests}, 1},
+		{"limits added", corev1.ResourceRequirements{Requests: requests, Limits: limits}, 1},
+		// 同一数量的不同写法不应触发更新
+		{"equivalent quantities", corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("0.25")},
+			Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1000m")},
+		}, 0},
+	}
+
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+	env.reconcileUntilCreated(t, "web")
+	for _, step := range steps {
+		env.writes.reset()
+		env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+			cd.Spec.Resources = step.resources
+		})
+		env.reconcile(t, "web")
+		env.reconcile(t, "web")
+
+		if got := env.deployment(t, "web").Spec.Template.Spec.Containers[0].Resources; !equality.Semantic.DeepEqual(got, step.resources) {
+			t.Fatalf("%s: resources = %v, want %v", step.name, got, step.resources)
+		}
+		if got := env.writes.get("update/Deployment"); got != step.wantUpdates {
+			t.Fatalf("%s: Deployment updates = %d, want %d", step.name, got, step.wantUpdates)
+		}
+	}
+}
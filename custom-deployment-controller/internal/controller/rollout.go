@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// rolloutConditionTypes 把 Deployment 的条件映射到 CR 上的条件类型，加上 Deployment 前缀避免与 CR 自身的条件冲突
+var rolloutConditionTypes = []struct {
+	deployment appsv1.DeploymentConditionType
+	cr         string
+}{
+	{appsv1.DeploymentProgressing, "DeploymentProgressing"},
+	{appsv1.DeploymentAvailable, "DeploymentAvailable"},
+	{appsv1.DeploymentReplicaFailure, "DeploymentReplicaFailure"},
+}
+
+func findDeploymentCondition(deploy *appsv1.Deployment, t appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i := range deploy.Status.Conditions {
+		if deploy.Status.Conditions[i].Type == t {
+			return &deploy.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setRolloutConditions 把 Deployment 的 Progressing/Available/ReplicaFailure 条件同步到 CR，
+// 用户不用查看 Deployment 就能看到 ProgressDeadlineExceeded 等发布诊断信息。
+// Deployment 上不存在的条件会从 CR 上移除。
+func setRolloutConditions(cd *appsv1alpha1.CustomDeployment, deploy *appsv1.Deployment) {
+	for _, t := range rolloutConditionTypes {
+		cond := findDeploymentCondition(deploy, t.deployment)
+		if cond == nil {
+			meta.RemoveStatusCondition(&cd.Status.Conditions, t.cr)
+			continue
+		}
+		meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+			Type:               t.cr,
+			Status:             metav1.ConditionStatus(cond.Status),
+			Reason:             cond.Reason,
+			Message:            cond.Message,
+			LastTransitionTime: cond.LastTransitionTime,
+			ObservedGeneration: cd.Generation,
+		})
+	}
+}
+
+// rolloutConditionsInSync 判断 CR 上的发布条件是否与 Deployment 一致
+func rolloutConditionsInSync(cd *appsv1alpha1.CustomDeployment, deploy *appsv1.Deployment) bool {
+	for _, t := range rolloutConditionTypes {
+		cond := findDeploymentCondition(deploy, t.deployment)
+		existing := meta.FindStatusCondition(cd.Status.Conditions, t.cr)
+		if cond == nil || existing == nil {
+			if cond != nil || existing != nil {
+				return false
+			}
+			continue
+		}
+		if existing.Status != metav1.ConditionStatus(cond.Status) || existing.Reason != cond.Reason || existing.Message != cond.Message {
+			return false
+		}
+	}
+	return true
+}
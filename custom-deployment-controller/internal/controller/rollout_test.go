@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileRolloutConditions(t *testing.T) {
+	replicaFailure := appsv1.DeploymentCondition{
+		Type:    appsv1.DeploymentReplicaFailure,
+		Status:  corev1.ConditionTrue,
+		Reason:  "FailedCreate",
+		Message: `pods "web-abc" is forbidden: exceeded quota`,
+	}
+	progressing := appsv1.DeploymentCondition{
+		Type:    appsv1.DeploymentProgressing,
+		Status:  corev1.ConditionFalse,
+		Reason:  "ProgressDeadlineExceeded",
+		Message: `ReplicaSet "web-abc" has timed out progressing.`,
+	}
+	tests := []struct {
+		name       string
+		conditions []appsv1.DeploymentCondition
+		want       map[string]appsv1.DeploymentCondition
+		wantAbsent []string
+	}{
+		{
+			name:       "replica failure",
+			conditions: []appsv1.DeploymentCondition{replicaFailure},
+			want:       map[string]appsv1.DeploymentCondition{"DeploymentReplicaFailure": replicaFailure},
+			wantAbsent: []string{"DeploymentProgressing", "DeploymentAvailable"},
+		},
+		{
+			name:       "progress deadline exceeded",
+			conditions: []appsv1.DeploymentCondition{progressing, replicaFailure},
+			want: map[string]appsv1.DeploymentCondition{
+				"DeploymentProgressing":    progressing,
+				"DeploymentReplicaFailure": replicaFailure,
+			},
+			wantAbsent: []string{"DeploymentAvailable"},
+		},
+		{
+			name:       "no conditions",
+			wantAbsent: []string{"DeploymentProgressing", "DeploymentAvailable", "DeploymentReplicaFailure"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+			env.reconcileUntilCreated(t, "web")
+			env.setDeploymentStatus(t, "web", func(s *appsv1.DeploymentStatus) {
+				s.ObservedGeneration = 1
+				s.Conditions = tt.conditions
+			})
+			env.reconcile(t, "web")
+
+			conditions := env.customDeployment(t, "web").Status.Conditions
+			for crType, want := range tt.want {
+				got := meta.FindStatusCondition(conditions, crType)
+				if got == nil {
+					t.Fatalf("condition %s missing, got %v", crType, conditions)
+				}
+				if string(got.Status) != string(want.Status) || got.Reason != want.Reason || got.Message != want.Message {
+					t.Fatalf("condition %s = %+v, want status %s reason %s message %q", crType, got, want.Status, want.Reason, want.Message)
+				}
+			}
+			for _, crType := range tt.wantAbsent {
+				if meta.FindStatusCondition(conditions, crType) != nil {
+					t.Fatalf("unexpected condition %s", crType)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileRolloutConditionRemovedWhenCleared(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+	env.reconcileUntilCreated(t, "web")
+	env.setDeploymentStatus(t, "web", func(s *appsv1.DeploymentStatus) {
+		s.ObservedGeneration = 1
+		s.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentReplicaFailure, Status: corev1.ConditionTrue, Reason: "FailedCreate"}}
+	})
+	env.reconcile(t, "web")
+	env.setDeploymentStatus(t, "web", func(s *appsv1.DeploymentStatus) { s.Conditions = nil })
+	env.reconcile(t, "web")
+
+	if cond := meta.FindStatusCondition(env.customDeployment(t, "web").Status.Conditions, "DeploymentReplicaFailure"); cond != nil {
+		t.Fatalf("expected DeploymentReplicaFailure to be removed, got %+v", cond)
+	}
+}
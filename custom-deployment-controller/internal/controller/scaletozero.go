@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConditionScaleToZeroIncompatible 表示 spec.schedule 的缩容到 0 与 Deployment 的发布策略或 PDB 配合不好。
+// 只是提醒，不影响调谐
+const ConditionScaleToZeroIncompatible = "ScaleToZeroIncompatible"
+
+// scaleToZeroProblems 检查按计划缩容到 0 时可能卡住或产生误报的配置
+func (c *CustomDeploymentController) scaleToZeroProblems(ctx context.Context, cd *appsv1alpha1.CustomDeployment, deploy *appsv1.Deployment) ([]string, error) {
+	if cd.Spec.Schedule == nil {
+		return nil, nil
+	}
+
+	var problems []string
+	// Recreate 会先删除所有旧 Pod；窗口外修改的模板会在窗口开始时与扩容一起生效，启动阶段没有可用副本
+	if deploy.Spec.Strategy.Type == appsv1.RecreateDeploymentStrategyType {
+		problems = append(problems, "Deployment uses the Recreate strategy, template changes made outside the schedule window roll out together with the scale-up at window start; use RollingUpdate")
+	}
+
+	// minAvailable 大于 0 的 PDB 在缩容到 0 后永远无法满足，会持续触发 PDB 告警
+	pdbs := &policyv1.PodDisruptionBudgetList{}
+	if err := c.List(ctx, pdbs, client.InNamespace(cd.Namespace)); err != nil {
+		return nil, fmt.Errorf("list PodDisruptionBudgets: %w", err)
+	}
+	podLabels := labels.Set(deploy.Spec.Template.Labels)
+	for _, pdb := range pdbs.Items {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(podLabels) {
+			continue
+		}
+		if minAvailable := pdb.Spec.MinAvailable; minAvailable != nil && minAvailable.String() != "0" && minAvailable.String() != "0%" {
+			problems = append(problems, fmt.Sprintf("PodDisruptionBudget %s requires minAvailable=%s, it can never be satisfied while scaled to zero; use maxUnavailable instead", pdb.Name, minAvailable.String()))
+		}
+	}
+	return problems, nil
+}
+
+// setScaleToZeroCondition 根据检查结果设置 ScaleToZeroIncompatible 条件，新出现问题时记录 Warning 事件
+func (c *CustomDeploymentController) setScaleToZeroCondition(cd *appsv1alpha1.CustomDeployment, problems []string) {
+	if len(problems) > 0 {
+		message := strings.Join(problems, "; ")
+		existing := meta.FindStatusCondition(cd.Status.Conditions, ConditionScaleToZeroIncompatible)
+		if existing == nil || existing.Status != metav1.ConditionTrue || existing.Message != message {
+			c.Recorder.Event(cd, corev1.EventTypeWarning, ConditionScaleToZeroIncompatible, message)
+		}
+		meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+			Type:               ConditionScaleToZeroIncompatible,
+			Status:             metav1.ConditionTrue,
+			Reason:             "IncompatibleConfiguration",
+			Message:            message,
+			ObservedGeneration: cd.Generation,
+		})
+		return
+	}
+	if meta.FindStatusCondition(cd.Status.Conditions, ConditionScaleToZeroIncompatible) != nil {
+		meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+			Type:               ConditionScaleToZeroIncompatible,
+			Status:             metav1.ConditionFalse,
+			Reason:             "Compatible",
+			Message:            "Schedule is compatible with the Deployment",
+			ObservedGeneration: cd.Generation,
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileScaleToZeroIncompatible(t *testing.T) {
+	pdb := func(budget policyv1.PodDisruptionBudgetSpec) *policyv1.PodDisruptionBudget {
+		budget.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+		return &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: testNamespace}, Spec: budget}
+	}
+	minAvailable := intstr.FromInt32(1)
+	maxUnavailable := intstr.FromInt32(1)
+	tests := []struct {
+		name       string
+		noSchedule bool
+		recreate   bool
+		pdb        *policyv1.PodDisruptionBudget
+		want       string
+	}{
+		{name: "rolling update without PDB"},
+		{name: "Recreate strategy", recreate: true, want: "Recreate"},
+		{name: "minAvailable PDB", pdb: pdb(policyv1.PodDisruptionBudgetSpec{MinAvailable: &minAvailable}), want: "minAvailable=1"},
+		{name: "maxUnavailable PDB", pdb: pdb(policyv1.PodDisruptionBudgetSpec{MaxUnavailable: &maxUnavailable})},
+		// 没有 schedule 时不会缩容到 0，不做检查
+		{name: "no schedule", noSchedule: true, recreate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				if !tt.noSchedule {
+					cd.Spec.Schedule = &appsv1alpha1.ScheduleSpec{Start: "0 8 * * *", Stop: "0 20 * * *"}
+				}
+			})}
+			if tt.pdb != nil {
+				objs = append(objs, tt.pdb)
+			}
+			env := newTestEnv(t, objs)
+			deploy := env.reconcileUntilCreated(t, "web")
+			if tt.recreate {
+				// 发布策略由用户直接在 Deployment 上设置
+				deploy.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+				deploy.Generation++
+				if err := env.c.Update(context.Background(), deploy); err != nil {
+					t.Fatal(err)
+				}
+			}
+			env.reconcile(t, "web")
+
+			cond := meta.FindStatusCondition(env.customDeployment(t, "web").Status.Conditions, ConditionScaleToZeroIncompatible)
+			if tt.want == "" {
+				if cond != nil && cond.Status == metav1.ConditionTrue {
+					t.Fatalf("unexpected %s condition %+v", ConditionScaleToZeroIncompatible, cond)
+				}
+				return
+			}
+			if cond == nil || cond.Status != metav1.ConditionTrue || !strings.Contains(cond.Message, tt.want) {
+				t.Fatalf("%s condition = %+v, want one mentioning %q", ConditionScaleToZeroIncompatible, cond, tt.want)
+			}
+			// 问题不变时重复调谐不会重复记录事件
+			warnings := 0
+			for _, ev := range env.events() {
+				if strings.Contains(ev, ConditionScaleToZeroIncompatible) {
+					warnings++
+				}
+			}
+			if warnings != 1 {
+				t.Fatalf("%s events = %d, want 1", ConditionScaleToZeroIncompatible, warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"github.com/robfig/cron/v3"
+)
+
+// scheduleLookback 是查找 cron 最近一次触发时间的回溯范围，覆盖按周配置的时间窗口
+const scheduleLookback = 8 * 24 * time.Hour
+
+// now 返回当前时间，测试时可以通过 Clock 注入
+func (c *CustomDeploymentController) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock.Now()
+	}
+	return time.Now()
+}
+
+// evaluateSchedule 判断 now 是否处于运行窗口内，并返回下一个窗口边界。
+// 回溯范围内 Start 和 Stop 都没有触发过时视为运行中，避免误缩容
+func evaluateSchedule(s *appsv1alpha1.ScheduleSpec, now time.Time) (active bool, next time.Time, err error) {
+	loc := time.UTC
+	if s.TimeZone != "" {
+		if loc, err = time.LoadLocation(s.TimeZone); err != nil {
+			return false, time.Time{}, fmt.Errorf("invalid schedule time zone %q: %w", s.TimeZone, err)
+		}
+	}
+	start, err := cron.ParseStandard(s.Start)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid schedule start %q: %w", s.Start, err)
+	}
+	stop, err := cron.ParseStandard(s.Stop)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid schedule stop %q: %w", s.Stop, err)
+	}
+
+	now = now.In(loc)
+	active = !lastFire(stop, now).After(lastFire(start, now))
+
+	next = start.Next(now)
+	if nextStop := stop.Next(now); next.IsZero() || (!nextStop.IsZero() && nextStop.Before(next)) {
+		next = nextStop
+	}
+	return active, next, nil
+}
+
+// lastFire 返回 now 之前（含）最近一次触发时间，回溯范围内没有触发时返回零值
+func lastFire(s cron.Schedule, now time.Time) time.Time {
+	var last time.Time
+	for t := s.Next(now.Add(-scheduleLookback)); !t.IsZero() && !t.After(now); t = s.Next(t) {
+		last = t
+	}
+	return last
+}
+
+// scheduleRequeueAfter 返回距离下一个窗口边界的时间，没有配置 schedule 时返回 0
+func (c *CustomDeploymentController) scheduleRequeueAfter(cd *appsv1alpha1.CustomDeployment) time.Duration {
+	if cd.Spec.Schedule == nil {
+		return 0
+	}
+	now := c.now()
+	_, next, err := evaluateSchedule(cd.Spec.Schedule, now)
+	if err != nil || next.IsZero() {
+		return 0
+	}
+	return next.Sub(now)
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileSchedule(t *testing.T) {
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		start       time.Time
+		later       time.Time
+		want        int32
+		wantLater   int32
+		wantRequeue time.Duration
+	}{
+		{
+			name:        "scales to zero after the window closes",
+			start:       day.Add(10 * time.Hour),
+			later:       day.Add(21 * time.Hour),
+			want:        2,
+			wantLater:   0,
+			wantRequeue: 11 * time.Hour,
+		},
+		{
+			name:        "restores replicas when the window opens",
+			start:       day.Add(6 * time.Hour),
+			later:       day.Add(9 * time.Hour),
+			want:        0,
+			wantLater:   2,
+			wantRequeue: 11 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.Schedule = &appsv1alpha1.ScheduleSpec{Start: "0 8 * * *", Stop: "0 20 * * *"}
+			})})
+			clock := testingclock.NewFakePassiveClock(tt.start)
+			env.c.Clock = clock
+
+			if got := ptr.Deref(env.reconcileUntilCreated(t, "web").Spec.Replicas, -1); got != tt.want {
+				t.Fatalf("replicas at %s = %d, want %d", tt.start.Format(time.Kitchen), got, tt.want)
+			}
+
+			clock.SetTime(tt.later)
+			result := env.reconcile(t, "web")
+			if got := ptr.Deref(env.deployment(t, "web").Spec.Replicas, -1); got != tt.wantLater {
+				t.Fatalf("replicas at %s = %d, want %d", tt.later.Format(time.Kitchen), got, tt.wantLater)
+			}
+			// 在下一个窗口边界重新调谐
+			if result.RequeueAfter != tt.wantRequeue {
+				t.Fatalf("RequeueAfter = %v, want %v", result.RequeueAfter, tt.wantRequeue)
+			}
+		})
+	}
+}
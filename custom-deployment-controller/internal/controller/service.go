@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// validateService 校验 spec.exposeService 需要的 spec.servicePort
+func validateService(cd *appsv1alpha1.CustomDeployment) error {
+	if !cd.Spec.ExposeService {
+		return nil
+	}
+	if cd.Spec.ServicePort < 1 || cd.Spec.ServicePort > 65535 {
+		return fmt.Errorf("exposeService requires servicePort between 1 and 65535, got %d", cd.Spec.ServicePort)
+	}
+	return nil
+}
+
+// desiredService 返回指向工作负载 Pod 的 ClusterIP Service。设置了 spec.containerPort 时转发到该端口，
+// 否则转发到与 servicePort 相同的容器端口。端口名使用 spec.portName，供 ServiceMonitor 引用
+func desiredService(cd *appsv1alpha1.CustomDeployment, labels map[string]string) *corev1.Service {
+	targetPort := cd.Spec.ServicePort
+	if cd.Spec.ContainerPort != 0 {
+		targetPort = cd.Spec.ContainerPort
+	}
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cd.Name,
+			Namespace: cd.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: labels,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       cd.Spec.PortName,
+					Protocol:   corev1.ProtocolTCP,
+					Port:       cd.Spec.ServicePort,
+					TargetPort: intstr.FromInt32(targetPort),
+				},
+			},
+		},
+	}
+}
+
+// reconcileService 按 spec.exposeService 创建、更新或删除工作负载的 Service。
+// 只同步类型、selector 和端口，API Server 分配的 clusterIP 等字段保持不变
+func (c *CustomDeploymentController) reconcileService(ctx context.Context, cd *appsv1alpha1.CustomDeployment) error {
+	logger := log.FromContext(ctx)
+
+	existing := &corev1.Service{}
+	err := c.Get(ctx, types.NamespacedName{Name: cd.Name, Namespace: cd.Namespace}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get Service")
+		return err
+	}
+	found := err == nil
+
+	if !cd.Spec.ExposeService {
+		if found && metav1.IsControlledBy(existing, cd) {
+			if err := c.Delete(ctx, existing); err != nil && !errors.IsNotFound(err) {
+				logger.Error(err, "Failed to delete Service")
+				return err
+			}
+			logger.Info("Service deleted", "name", existing.Name)
+		}
+		return nil
+	}
+	// spec 无效时已经记录了 InvalidSpec 条件，等待用户修改
+	if validateService(cd) != nil {
+		return nil
+	}
+
+	desired := desiredService(cd, c.selectorLabels(cd))
+	if !found {
+		if err := c.setOwner(cd, desired); err != nil {
+			logger.Error(err, "Failed to set owner reference")
+			return err
+		}
+		if err := c.Create(ctx, desired); err != nil {
+			logger.Error(err, "Failed to create Service")
+			return err
+		}
+		logger.Info("Service created successfully", "name", desired.Name)
+		return nil
+	}
+
+	if existing.Spec.Type == desired.Spec.Type &&
+		equality.Semantic.DeepEqual(existing.Spec.Selector, desired.Spec.Selector) &&
+		servicePortsEqual(existing.Spec.Ports, desired.Spec.Ports) {
+		return nil
+	}
+	existing.Spec.Type = desired.Spec.Type
+	existing.Spec.Selector = desired.Spec.Selector
+	existing.Spec.Ports = desired.Spec.Ports
+	if err := c.Update(ctx, existing); err != nil {
+		logger.Error(err, "Failed to update Service")
+		return err
+	}
+	logger.Info("Service updated successfully", "name", existing.Name)
+	return nil
+}
+
+// servicePortsEqual 比较控制器声明的端口字段，忽略 API Server 填充的 nodePort 等
+func servicePortsEqual(live, desired []corev1.ServicePort) bool {
+	if len(live) != len(desired) {
+		return false
+	}
+	for i := range desired {
+		if live[i].Name != desired[i].Name || live[i].Protocol != desired[i].Protocol ||
+			live[i].Port != desired[i].Port || live[i].TargetPort != desired[i].TargetPort {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileService(t *testing.T) {
+	tests := []struct {
+		name       string
+		expose     bool
+		port       int32
+		unowned    bool
+		wantPort   int32
+		wantExists bool
+	}{
+		{name: "port changed", expose: true, port: 8080, wantPort: 8080, wantExists: true},
+		{name: "unchanged", expose: true, port: 80, wantPort: 80, wantExists: true},
+		{name: "torn down", expose: false, wantExists: false},
+		// 不是控制器创建的 Service 不会被删除
+		{name: "unowned Service kept", expose: false, unowned: true, wantPort: 80, wantExists: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cd := newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.UID = "web-uid"
+				cd.Spec.ExposeService = !tt.unowned
+				cd.Spec.ServicePort = 80
+			})
+			objs := []client.Object{cd}
+			if tt.unowned {
+				objs = append(objs, &corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: testNamespace},
+					Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+				})
+			}
+			env := newTestEnv(t, objs)
+			env.reconcileUntilCreated(t, "web")
+
+			svc := &corev1.Service{}
+			key := types.NamespacedName{Namespace: testNamespace, Name: "web"}
+			if err := env.c.Get(context.Background(), key, svc); err != nil {
+				t.Fatalf("get Service after create: %v", err)
+			}
+			if !tt.unowned {
+				if svc.Spec.Type != corev1.ServiceTypeClusterIP || svc.Spec.Selector["app"] != "web" || !metav1.IsControlledBy(svc, cd) {
+					t.Fatalf("Service = %+v, want a controlled ClusterIP Service selecting app=web", svc)
+				}
+			}
+
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.ExposeService = tt.expose
+				cd.Spec.ServicePort = tt.port
+			})
+			env.reconcile(t, "web")
+
+			err := env.c.Get(context.Background(), key, svc)
+			if !tt.wantExists {
+				if !apierrors.IsNotFound(err) {
+					t.Fatalf("get Service after teardown: %v, want NotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("get Service after update: %v", err)
+			}
+			if len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Port != tt.wantPort {
+				t.Fatalf("Service ports = %v, want port %d", svc.Spec.Ports, tt.wantPort)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Prometheus Operator 不一定安装，ServiceMonitor 用 unstructured 表示，避免引入它的 Go 依赖
+var serviceMonitorGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "ServiceMonitor",
+}
+
+// validateServiceMonitor 校验 spec.serviceMonitor 依赖的 Service：ServiceMonitor 按标签选择
+// spec.exposeService 创建的 Service，没有 Service 时采集不到任何目标
+func validateServiceMonitor(cd *appsv1alpha1.CustomDeployment) error {
+	if cd.Spec.ServiceMonitor != nil && !cd.Spec.ExposeService {
+		return fmt.Errorf("serviceMonitor requires exposeService=true, it scrapes the Service created for the workload")
+	}
+	return nil
+}
+
+func newServiceMonitor() *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(serviceMonitorGVK)
+	return u
+}
+
+func desiredServiceMonitor(cd *appsv1alpha1.CustomDeployment, labels map[string]string) *unstructured.Unstructured {
+	sm := cd.Spec.ServiceMonitor
+	endpoint := map[string]interface{}{
+		"port": sm.Port,
+		"path": "/metrics",
+	}
+	if sm.Path != "" {
+		endpoint["path"] = sm.Path
+	}
+	if sm.Interval != "" {
+		endpoint["interval"] = sm.Interval
+	}
+
+	matchLabels := make(map[string]interface{}, len(labels))
+	for k, v := range labels {
+		matchLabels[k] = v
+	}
+
+	u := newServiceMonitor()
+	u.SetName(cd.Name)
+	u.SetNamespace(cd.Namespace)
+	u.SetLabels(labels)
+	u.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": matchLabels,
+		},
+		"endpoints": []interface{}{endpoint},
+	}
+	return u
+}
+
+// reconcileServiceMonitor 按 spec.serviceMonitor 创建、更新或删除 ServiceMonitor。
+// 集群中没有安装 ServiceMonitor CRD 时直接跳过。
+func (c *CustomDeploymentController) reconcileServiceMonitor(ctx context.Context, cd *appsv1alpha1.CustomDeployment) error {
+	logger := log.FromContext(ctx)
+
+	existing := newServiceMonitor()
+	err := c.Get(ctx, types.NamespacedName{Name: cd.Name, Namespace: cd.Namespace}, existing)
+	if meta.IsNoMatchError(err) {
+		if cd.Spec.ServiceMonitor != nil {
+			logger.Info("ServiceMonitor CRD is not installed, skipping", "name", cd.Name)
+		}
+		return nil
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to get ServiceMonitor")
+		return err
+	}
+	found := err == nil
+
+	if cd.Spec.ServiceMonitor == nil {
+		if found && metav1.IsControlledBy(existing, cd) {
+			if err := c.Delete(ctx, existing); err != nil && !errors.IsNotFound(err) {
+				logger.Error(err, "Failed to delete ServiceMonitor")
+				return err
+			}
+			logger.Info("ServiceMonitor deleted", "name", existing.GetName())
+		}
+		return nil
+	}
+	// spec 无效时已经记录了 InvalidSpec 条件，等待用户修改
+	if validateServiceMonitor(cd) != nil {
+		return nil
+	}
+
+	desired := desiredServiceMonitor(cd, c.selectorLabels(cd))
+	if !found {
+		if err := c.setOwner(cd, desired); err != nil {
+			logger.Error(err, "Failed to set owner reference")
+			return err
+		}
+		if err := c.Create(ctx, desired); err != nil {
+			logger.Error(err, "Failed to create ServiceMonitor")
+			return err
+		}
+		logger.Info("ServiceMonitor created successfully", "name", desired.GetName())
+		return nil
+	}
+
+	if equality.Semantic.DeepEqual(existing.Object["spec"], desired.Object["spec"]) {
+		return nil
+	}
+	existing.Object["spec"] = desired.Object["spec"]
+	if err := c.Update(ctx, existing); err != nil {
+		logger.Error(err, "Failed to update ServiceMonitor")
+		return err
+	}
+	logger.Info("ServiceMonitor updated successfully", "name", existing.GetName())
+	return nil
+}
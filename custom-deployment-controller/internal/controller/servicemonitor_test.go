@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileServiceMonitor(t *testing.T) {
+	tests := []struct {
+		name          string
+		exposeService bool
+		wantMonitor   bool
+	}{
+		{"selects the managed Service", true, true},
+		{"refused without a Service", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.ExposeService = tt.exposeService
+				cd.Spec.ServicePort = 8080
+				cd.Spec.PortName = "http"
+				cd.Spec.ServiceMonitor = &appsv1alpha1.ServiceMonitorSpec{Port: "http", Interval: "30s"}
+			})}, withServiceMonitorCRD())
+			env.reconcile(t, "web")
+			env.reconcile(t, "web")
+
+			sm := newServiceMonitor()
+			err := env.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: "web"}, sm)
+			if !tt.wantMonitor {
+				if !errors.IsNotFound(err) {
+					t.Fatalf("expected no ServiceMonitor, got err=%v", err)
+				}
+				if !meta.IsStatusConditionTrue(env.customDeployment(t, "web").Status.Conditions, ConditionInvalidSpec) {
+					t.Fatalf("expected InvalidSpec condition")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("get ServiceMonitor: %v", err)
+			}
+			selector, _, _ := unstructured.NestedStringMap(sm.Object, "spec", "selector", "matchLabels")
+			if !maps.Equal(selector, map[string]string{"app": "web"}) {
+				t.Fatalf("ServiceMonitor selector = %v, want app=web", selector)
+			}
+			endpoints, _, _ := unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+			if len(endpoints) != 1 || endpoints[0].(map[string]interface{})["port"] != "http" {
+				t.Fatalf("ServiceMonitor endpoints = %v, want port http", endpoints)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// ConditionInvalidSpec 表示 CR 的 spec 无法被控制器解析（如未知的 size），Deployment 不会被写入
+const ConditionInvalidSpec = "InvalidSpec"
+
+// ConditionReplicasAdjusted 表示副本数按 spec.replicaStep 向上取整，Deployment 的副本数与 spec 不同
+const ConditionReplicasAdjusted = "ReplicasAdjusted"
+
+// requestedReplicas 返回用户要求的副本数：设置了 spec.size 时使用规格对应的副本数
+func (c *CustomDeploymentController) requestedReplicas(cd *appsv1alpha1.CustomDeployment) (int32, error) {
+	replicas := ptr.Deref(cd.Spec.Replicas, 0)
+	if cd.Spec.Size != "" {
+		var ok bool
+		if replicas, ok = c.Sizes[cd.Spec.Size]; !ok {
+			return 0, fmt.Errorf("unknown size %q, configured sizes are %v", cd.Spec.Size, c.Sizes)
+		}
+	}
+	if replicas < 0 {
+		return 0, fmt.Errorf("replicas must not be negative, got %d", replicas)
+	}
+	if cd.Spec.ReplicaStep < 0 {
+		return 0, fmt.Errorf("replicaStep must not be negative, got %d", cd.Spec.ReplicaStep)
+	}
+	return replicas, nil
+}
+
+// roundUpToStep 把副本数向上取整到 step 的倍数，step 为 0 或 1 时不调整
+func roundUpToStep(replicas, step int32) int32 {
+	if step <= 1 || replicas%step == 0 {
+		return replicas
+	}
+	return (replicas/step + 1) * step
+}
+
+// desiredReplicas 返回 CR 期望的副本数：在 requestedReplicas 的基础上按 spec.replicaStep 向上取整，
+// 设置了 replica-override 注解时直接使用注解的值（不取整），设置了 spec.schedule 且不在运行窗口内时为 0。
+// 副本数为负或超过 MaxReplicas 时返回错误
+func (c *CustomDeploymentController) desiredReplicas(cd *appsv1alpha1.CustomDeployment) (int32, error) {
+	replicas, err := c.requestedReplicas(cd)
+	if err != nil {
+		return 0, err
+	}
+	override, overridden, err := replicaOverride(cd)
+	if err != nil {
+		return 0, err
+	}
+	if overridden {
+		replicas = override
+	} else {
+		replicas = roundUpToStep(replicas, cd.Spec.ReplicaStep)
+	}
+	if c.MaxReplicas > 0 && replicas > c.MaxReplicas {
+		return 0, fmt.Errorf("replicas %d exceeds the maximum of %d allowed by the controller", replicas, c.MaxReplicas)
+	}
+	if cd.Spec.Schedule != nil {
+		active, _, err := evaluateSchedule(cd.Spec.Schedule, c.now())
+		if err != nil {
+			return 0, err
+		}
+		if !active {
+			return 0, nil
+		}
+	}
+	return replicas, nil
+}
+
+// setInvalidSpecCondition 根据 spec 校验结果设置 InvalidSpec 条件
+func setInvalidSpecCondition(cd *appsv1alpha1.CustomDeployment, specErr error) {
+	if specErr != nil {
+		meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+			Type:               ConditionInvalidSpec,
+			Status:             metav1.ConditionTrue,
+			Reason:             "InvalidSpec",
+			Message:            specErr.Error(),
+			ObservedGeneration: cd.Generation,
+		})
+		return
+	}
+	if meta.FindStatusCondition(cd.Status.Conditions, ConditionInvalidSpec) != nil {
+		meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+			Type:               ConditionInvalidSpec,
+			Status:             metav1.ConditionFalse,
+			Reason:             "Valid",
+			Message:            "Spec is valid",
+			ObservedGeneration: cd.Generation,
+		})
+	}
+}
+
+// setReplicasAdjustedCondition 记录副本数是否按 spec.replicaStep 调整过；replica-override 注解生效时副本数不取整
+func setReplicasAdjustedCondition(cd *appsv1alpha1.CustomDeployment, requested int32) {
+	_, overridden, _ := replicaOverride(cd)
+	if adjusted := roundUpToStep(requested, cd.Spec.ReplicaStep); adjusted != requested && !overridden {
+		meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+			Type:               ConditionReplicasAdjusted,
+			Status:             metav1.ConditionTrue,
+			Reason:             "RoundedUpToStep",
+			Message:            fmt.Sprintf("Requested %d replicas, rounded up to %d to be a multiple of replicaStep %d", requested, adjusted, cd.Spec.ReplicaStep),
+			ObservedGeneration: cd.Generation,
+		})
+		return
+	}
+	if meta.FindStatusCondition(cd.Status.Conditions, ConditionReplicasAdjusted) != nil {
+		reason, message := "NotAdjusted", "Requested replicas are already a multiple of replicaStep"
+		if overridden {
+			reason, message = "ReplicaOverride", "Replicas are set by the "+replicaOverrideAnnotation+" annotation and not rounded to replicaStep"
+		}
+		meta.SetStatusCondition(&cd.Status.Conditions, metav1.Condition{
+			Type:               ConditionReplicasAdjusted,
+			Status:             metav1.ConditionFalse,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: cd.Generation,
+		})
+	}
+}
+
+// ParseSizes 解析 "small=1,medium=3,large=5" 形式的规格列表
+func ParseSizes(s string) (map[string]int32, error) {
+	sizes := map[string]int32{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid size %q, expected name=replicas", item)
+		}
+		replicas, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil || replicas < 0 {
+			return nil, fmt.Errorf("invalid replicas for size %q: %q", name, value)
+		}
+		sizes[strings.TrimSpace(name)] = int32(replicas)
+	}
+	return sizes, nil
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        string
+		want        int32
+		wantInvalid bool
+	}{
+		{name: "medium", size: "medium", want: 3},
+		{name: "large", size: "large", want: 5},
+		{name: "no size uses replicas", want: 2},
+		{name: "unknown size", size: "huge", wantInvalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.Size = tt.size
+			})})
+			env.c.Sizes = map[string]int32{"small": 1, "medium": 3, "large": 5}
+			// 第一次调谐添加 finalizer；spec 无效时第二次调谐可能返回错误，只检查条件
+			env.reconcile(t, "web")
+			_, _ = env.c.Reconcile(context.Background(), requestFor("web"))
+
+			cond := meta.FindStatusCondition(env.customDeployment(t, "web").Status.Conditions, ConditionInvalidSpec)
+			if tt.wantInvalid {
+				if cond == nil || cond.Status != metav1.ConditionTrue {
+					t.Fatalf("InvalidSpec condition = %+v, want True", cond)
+				}
+				return
+			}
+			if cond != nil && cond.Status == metav1.ConditionTrue {
+				t.Fatalf("unexpected InvalidSpec condition: %s", cond.Message)
+			}
+			if got := ptr.Deref(env.deployment(t, "web").Spec.Replicas, 0); got != tt.want {
+				t.Fatalf("replicas = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSizes(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[string]int32
+		wantErr bool
+	}{
+		{value: "small=1, medium=3 ,large=5", want: map[string]int32{"small": 1, "medium": 3, "large": 5}},
+		{value: "", want: map[string]int32{}},
+		{value: "medium", wantErr: true},
+		{value: "medium=-1", wantErr: true},
+		{value: "medium=three", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSizes(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Fatalf("sizes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileReplicasValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		replicas    int32
+		maxReplicas int32
+		want        int32
+		wantInvalid string
+	}{
+		{name: "negative", replicas: -1, wantInvalid: "must not be negative"},
+		{name: "zero", replicas: 0, want: 0},
+		{name: "normal", replicas: 3, want: 3},
+		{name: "at the maximum", replicas: 5, maxReplicas: 5, want: 5},
+		{name: "above the maximum", replicas: 6, maxReplicas: 5, wantInvalid: "exceeds the maximum"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.Replicas = ptr.To(tt.replicas)
+			})})
+			env.c.MaxReplicas = tt.maxReplicas
+			env.reconcile(t, "web")
+			env.reconcile(t, "web")
+
+			if tt.wantInvalid == "" {
+				if got := ptr.Deref(env.deployment(t, "web").Spec.Replicas, -1); got != tt.want {
+					t.Fatalf("replicas = %d, want %d", got, tt.want)
+				}
+				return
+			}
+			cond := meta.FindStatusCondition(env.customDeployment(t, "web").Status.Conditions, ConditionInvalidSpec)
+			if cond == nil || cond.Status != metav1.ConditionTrue || !strings.Contains(cond.Message, tt.wantInvalid) {
+				t.Fatalf("InvalidSpec condition = %+v, want one mentioning %q", cond, tt.wantInvalid)
+			}
+			// 无效的 spec 不重试，也不会再写入任何对象
+			env.writes.reset()
+			if result := env.reconcile(t, "web"); !result.IsZero() {
+				t.Fatalf("result = %+v, want no requeue", result)
+			}
+			if n := env.writes.total(); n != 0 {
+				t.Fatalf("writes = %d, want none", n)
+			}
+			if err := env.c.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: "web"}, &appsv1.Deployment{}); !apierrors.IsNotFound(err) {
+				t.Fatalf("get Deployment: %v, want NotFound", err)
+			}
+		})
+	}
+}
+
+func TestReconcileReplicaStep(t *testing.T) {
+	tests := []struct {
+		name         string
+		replicas     int32
+		step         int32
+		want         int32
+		wantAdjusted bool
+	}{
+		{name: "rounded up", replicas: 4, step: 3, want: 6, wantAdjusted: true},
+		{name: "already a multiple", replicas: 6, step: 3, want: 6},
+		{name: "step of one", replicas: 4, step: 1, want: 4},
+		{name: "no step", replicas: 4, want: 4},
+		{name: "zero replicas", replicas: 0, step: 3, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.Replicas = ptr.To(tt.replicas)
+				cd.Spec.ReplicaStep = tt.step
+			})})
+			if got := ptr.Deref(env.reconcileUntilCreated(t, "web").Spec.Replicas, -1); got != tt.want {
+				t.Fatalf("replicas = %d, want %d", got, tt.want)
+			}
+
+			conditions := env.customDeployment(t, "web").Status.Conditions
+			if got := meta.IsStatusConditionTrue(conditions, ConditionReplicasAdjusted); got != tt.wantAdjusted {
+				t.Fatalf("%s = %v, want %v", ConditionReplicasAdjusted, got, tt.wantAdjusted)
+			}
+			if cond := meta.FindStatusCondition(conditions, ConditionReplicasAdjusted); tt.wantAdjusted && !strings.Contains(cond.Message, "Requested 4 replicas, rounded up to 6") {
+				t.Fatalf("%s message = %q, want the requested and applied replicas", ConditionReplicasAdjusted, cond.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// spreadTopologyKey 是分散副本使用的拓扑 key
+const spreadTopologyKey = "kubernetes.io/hostname"
+
+// spreadWeight 是 preferred 反亲和的权重，取最大值，仍然不会阻止节点不足时调度
+const spreadWeight = 100
+
+// applySpreadAcrossNodes 在 spec.spreadAcrossNodes 为 true 时追加按 selector 标签、跨节点的 preferred 反亲和。
+// 已有的 affinity（如 namespace 默认策略）会被保留，在副本上追加，不修改原对象
+func applySpreadAcrossNodes(cd *appsv1alpha1.CustomDeployment, deploy *appsv1.Deployment, selector map[string]string) {
+	if !cd.Spec.SpreadAcrossNodes {
+		return
+	}
+	pod := &deploy.Spec.Template.Spec
+	affinity := pod.Affinity.DeepCopy()
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	}
+	if affinity.PodAntiAffinity == nil {
+		affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+		affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		corev1.WeightedPodAffinityTerm{
+			Weight: spreadWeight,
+			PodAffinityTerm: corev1.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: selector},
+				TopologyKey:   spreadTopologyKey,
+			},
+		},
+	)
+	pod.Affinity = affinity
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"maps"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// spreadTerms 返回 Deployment 上 preferred 的 Pod 反亲和的数量
+func spreadTerms(deploy *appsv1.Deployment) int {
+	affinity := deploy.Spec.Template.Spec.Affinity
+	if affinity == nil || affinity.PodAntiAffinity == nil {
+		return 0
+	}
+	return len(affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+}
+
+func TestReconcileSpreadAcrossNodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial bool
+		updated bool
+	}{
+		{"disabled", false, false},
+		{"enabled", true, true},
+		{"enabled later", false, true},
+		{"disabled later", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.SpreadAcrossNodes = tt.initial
+			})})
+			deploy := env.reconcileUntilCreated(t, "web")
+			checkSpread(t, "after create", deploy, tt.initial)
+
+			env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Spec.SpreadAcrossNodes = tt.updated
+			})
+			env.reconcile(t, "web")
+			checkSpread(t, "after update", env.deployment(t, "web"), tt.updated)
+		})
+	}
+}
+
+// checkSpread 检查分散副本的反亲和是否存在以及它的权重、拓扑 key 和 selector
+func checkSpread(t *testing.T, when string, deploy *appsv1.Deployment, want bool) {
+	t.Helper()
+	if !want {
+		if n := spreadTerms(deploy); n != 0 {
+			t.Fatalf("%s: %d anti-affinity terms, want none", when, n)
+		}
+		return
+	}
+	if n := spreadTerms(deploy); n != 1 {
+		t.Fatalf("%s: %d anti-affinity terms, want 1", when, n)
+	}
+	term := deploy.Spec.Template.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0]
+	if term.Weight != spreadWeight || term.PodAffinityTerm.TopologyKey != spreadTopologyKey {
+		t.Fatalf("%s: anti-affinity term = %+v, want weight %d across %s", when, term, spreadWeight, spreadTopologyKey)
+	}
+	if !maps.Equal(term.PodAffinityTerm.LabelSelector.MatchLabels, map[string]string{"app": "web"}) {
+		t.Fatalf("%s: anti-affinity selector = %v, want app=web", when, term.PodAffinityTerm.LabelSelector.MatchLabels)
+	}
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileStatusBeforeDeploymentReports(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           func(*appsv1.DeploymentStatus)
+		wantStatusWrites int
+		wantConditions   bool
+	}{
+		// 刚创建的 Deployment 状态全为零值，不应写入 CR
+		{name: "not yet reported", wantStatusWrites: 0},
+		{
+			name:             "reported zero available",
+			status:           func(s *appsv1.DeploymentStatus) { s.ObservedGeneration, s.Replicas = 1, 2 },
+			wantStatusWrites: 1,
+			wantConditions:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+			env.reconcileUntilCreated(t, "web")
+			if tt.status != nil {
+				env.setDeploymentStatus(t, "web", tt.status)
+			}
+			env.writes.reset()
+			env.reconcile(t, "web")
+
+			if got := env.writes.get("status/CustomDeployment"); got != tt.wantStatusWrites {
+				t.Fatalf("status writes = %d, want %d", got, tt.wantStatusWrites)
+			}
+			cd := env.customDeployment(t, "web")
+			if got := meta.FindStatusCondition(cd.Status.Conditions, ConditionReady) != nil; got != tt.wantConditions {
+				t.Fatalf("Ready condition present = %v, want %v", got, tt.wantConditions)
+			}
+			if tt.status != nil && cd.Status.Replicas != 2 {
+				t.Fatalf("status.replicas = %d, want 2", cd.Status.Replicas)
+			}
+		})
+	}
+}
+
+func TestReconcileObservedGeneration(t *testing.T) {
+	tests := []struct {
+		name             string
+		bump             bool
+		wantStatusWrites int
+	}{
+		{name: "spec bumped", bump: true, wantStatusWrites: 1},
+		// generation 不变时不写入 status，避免更新循环
+		{name: "spec unchanged", wantStatusWrites: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web", func(cd *appsv1alpha1.CustomDeployment) {
+				cd.Generation = 1
+			})})
+			env.reconcileUntilCreated(t, "web")
+			if got := env.customDeployment(t, "web").Status.ObservedGeneration; got != 1 {
+				t.Fatalf("observedGeneration after create = %d, want 1", got)
+			}
+
+			if tt.bump {
+				env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+					cd.Spec.Replicas = ptrInt32(4)
+				})
+			}
+			want := env.customDeployment(t, "web").Generation
+			env.writes.reset()
+			env.reconcile(t, "web")
+
+			if got := env.customDeployment(t, "web").Status.ObservedGeneration; got != want {
+				t.Fatalf("observedGeneration = %d, want %d", got, want)
+			}
+			if got := env.writes.get("status/CustomDeployment"); got != tt.wantStatusWrites {
+				t.Fatalf("status writes = %d, want %d", got, tt.wantStatusWrites)
+			}
+		})
+	}
+}
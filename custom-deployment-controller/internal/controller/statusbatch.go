@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// statusBatchDrainTimeout 是停止时写入剩余状态的最长时间
+const statusBatchDrainTimeout = 10 * time.Second
+
+// StatusBatcher 把同一个对象在 Window 内的多次状态写入合并为一次，
+// 节点排空等场景下大量 CR 同时变化时减轻 API Server 的压力。
+// 它作为 Runnable 在 Manager 中运行，停止时写入所有未到期的状态；停止后的提交直接写入。
+// 写入失败时重新排队，下一个窗口重试，期间有新的提交则以新状态为准；停止时写入剩余状态失败只记录日志
+type StatusBatcher struct {
+	Client client.Client
+	// Reader 用于写入前重新读取对象，应为 mgr.GetAPIReader()，避免基于缓存中过期的对象写入；为空时使用 Client
+	Reader client.Reader
+	Window time.Duration
+
+	mu      sync.Mutex
+	pending map[types.NamespacedName]*pendingStatus
+	stopped bool
+}
+
+type pendingStatus struct {
+	status *appsv1alpha1.CustomDeploymentStatus
+	due    time.Time
+}
+
+// Enqueue 记录 cd 当前的状态，窗口结束时写入最后一次提交的状态。
+// 返回 false 表示 batcher 已经停止，调用方需要自己写入
+func (b *StatusBatcher) Enqueue(cd *appsv1alpha1.CustomDeployment) bool {
+	key := types.NamespacedName{Namespace: cd.Namespace, Name: cd.Name}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stopped {
+		return false
+	}
+	if b.pending == nil {
+		b.pending = map[types.NamespacedName]*pendingStatus{}
+	}
+	if p, ok := b.pending[key]; ok {
+		p.status = cd.Status.DeepCopy()
+		return true
+	}
+	b.pending[key] = &pendingStatus{status: cd.Status.DeepCopy(), due: time.Now().Add(b.Window)}
+	return true
+}
+
+// Start 按窗口写入到期的状态，ctx 结束时写入剩余的全部状态后返回
+func (b *StatusBatcher) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("status-batcher")
+	ticker := time.NewTicker(b.tickInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.mu.Lock()
+			b.stopped = true
+			b.mu.Unlock()
+			// 调谐的 context 已经取消，用独立的 context 写入剩余状态
+			drainCtx, cancel := context.WithTimeout(log.IntoContext(context.Background(), logger), statusBatchDrainTimeout)
+			defer cancel()
+			b.flushDue(drainCtx, time.Time{})
+			return nil
+		case now := <-ticker.C:
+			b.flushDue(log.IntoContext(ctx, logger), now)
+		}
+	}
+}
+
+// NeedLeaderElection 返回 false：batcher 需要在控制器停止之后才停止，以便写入它们最后提交的状态
+func (b *StatusBatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// tickInterval 是检查到期状态的间隔，取窗口的四分之一，写入最多比窗口晚这么久
+func (b *StatusBatcher) tickInterval() time.Duration {
+	return max(b.Window/4, 10*time.Millisecond)
+}
+
+// flushDue 写入 now 之前到期的状态，now 为零值时写入全部
+func (b *StatusBatcher) flushDue(ctx context.Context, now time.Time) {
+	b.mu.Lock()
+	due := map[types.NamespacedName]*appsv1alpha1.CustomDeploymentStatus{}
+	for key, p := range b.pending {
+		if now.IsZero() || !p.due.After(now) {
+			due[key] = p.status
+			delete(b.pending, key)
+		}
+	}
+	b.mu.Unlock()
+
+	logger := log.FromContext(ctx)
+	for key, status := range due {
+		if err := b.flush(ctx, key, status); err != nil {
+			logger.Error(err, "Failed to write batched CustomDeployment status", "customdeployment", key)
+			b.requeue(key, status, now)
+		}
+	}
+}
+
+// requeue 把写入失败的状态放回队列，在下一个窗口重试；期间已有新的提交时保留新状态。
+// 停止时（now 为零值）不再重试
+func (b *StatusBatcher) requeue(key types.NamespacedName, status *appsv1alpha1.CustomDeploymentStatus, now time.Time) {
+	if now.IsZero() {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.pending[key]; ok {
+		return
+	}
+	b.pending[key] = &pendingStatus{status: status, due: now.Add(b.Window)}
+}
+
+// flush 基于最新读取的对象写入状态，对象已被删除时忽略
+func (b *StatusBatcher) flush(ctx context.Context, key types.NamespacedName, status *appsv1alpha1.CustomDeploymentStatus) error {
+	reader := b.Reader
+	if reader == nil {
+		reader = b.Client
+	}
+	cd := &appsv1alpha1.CustomDeployment{}
+	if err := reader.Get(ctx, key, cd); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	patch := client.MergeFrom(cd.DeepCopy())
+	cd.Status = *status
+	return client.IgnoreNotFound(b.Client.Status().Patch(ctx, cd, patch))
+}
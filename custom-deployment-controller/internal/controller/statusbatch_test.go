@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// countingReader 统计 Get 次数，用于确认写入前重新读取了对象
+type countingReader struct {
+	client.Reader
+	gets int
+}
+
+func (r *countingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	r.gets++
+	return r.Reader.Get(ctx, key, obj, opts...)
+}
+
+func runBatcher(t *testing.T, b *StatusBatcher) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := b.Start(ctx); err != nil {
+			t.Errorf("Start: %v", err)
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestStatusBatcherCoalescesWrites(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+	reader := &countingReader{Reader: env.c.Client}
+	b := &StatusBatcher{Client: env.c.Client, Reader: reader, Window: 50 * time.Millisecond}
+	stop := runBatcher(t, b)
+	defer stop()
+
+	cd := env.customDeployment(t, "web")
+	const updates = 10
+	for i := 1; i <= updates; i++ {
+		cd.Status.AvailableReplicas = int32(i)
+		if !b.Enqueue(cd) {
+			t.Fatal("Enqueue refused while running")
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for env.writes.get("status/CustomDeployment") == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// 再等一个窗口，确认没有额外的写入
+	time.Sleep(100 * time.Millisecond)
+
+	if n := env.writes.get("status/CustomDeployment"); n != 1 {
+		t.Fatalf("%d status updates produced %d writes, want 1", updates, n)
+	}
+	if reader.gets != 1 {
+		t.Fatalf("expected the object to be re-read once before patching, got %d reads", reader.gets)
+	}
+	if got := env.customDeployment(t, "web").Status.AvailableReplicas; got != updates {
+		t.Fatalf("status.availableReplicas = %d, want the last enqueued value %d", got, updates)
+	}
+}
+
+func TestStatusBatcherDrainsOnStop(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+	b := &StatusBatcher{Client: env.c.Client, Window: time.Hour}
+	stop := runBatcher(t, b)
+
+	cd := env.customDeployment(t, "web")
+	cd.Status.AvailableReplicas = 3
+	b.Enqueue(cd)
+	stop()
+
+	if got := env.customDeployment(t, "web").Status.AvailableReplicas; got != 3 {
+		t.Fatalf("pending status was not written on stop, status.availableReplicas = %d", got)
+	}
+	if b.Enqueue(cd) {
+		t.Fatal("Enqueue accepted a status after the batcher stopped")
+	}
+}
+
+func TestUpdateStatusFallsBackAfterBatcherStopped(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web")})
+	env.c.StatusBatcher = &StatusBatcher{Client: env.c.Client, Window: time.Hour}
+	runBatcher(t, env.c.StatusBatcher)()
+
+	cd := env.customDeployment(t, "web")
+	original := cd.Status.DeepCopy()
+	cd.Status.AvailableReplicas = 5
+	if err := env.c.updateStatus(context.Background(), cd, original); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.customDeployment(t, "web").Status; got.AvailableReplicas != 5 {
+		t.Fatalf("status was not written directly after the batcher stopped: %+v", got)
+	}
+}
+
+func TestStatusBatcherRetriesFailedWrite(t *testing.T) {
+	failures := 1
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web")}, withInterceptor(interceptor.Funcs{
+		SubResourcePatch: func(ctx context.Context, c client.Client, sub string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			if failures > 0 {
+				failures--
+				return errors.New("apiserver unavailable")
+			}
+			return c.SubResource(sub).Patch(ctx, obj, patch, opts...)
+		},
+	}))
+	b := &StatusBatcher{Client: env.c.Client, Window: time.Minute}
+
+	cd := env.customDeployment(t, "web")
+	cd.Status.AvailableReplicas = 3
+	b.Enqueue(cd)
+
+	now := time.Now().Add(b.Window)
+	b.flushDue(context.Background(), now)
+	if got := env.customDeployment(t, "web").Status.AvailableReplicas; got != 0 {
+		t.Fatalf("status.availableReplicas = %d after a failed write, want 0", got)
+	}
+	// 失败的状态在下一个窗口重试
+	b.flushDue(context.Background(), now)
+	if got := env.customDeployment(t, "web").Status.AvailableReplicas; got != 0 {
+		t.Fatalf("retry was written before its window, status.availableReplicas = %d", got)
+	}
+	b.flushDue(context.Background(), now.Add(b.Window))
+	if got := env.customDeployment(t, "web").Status.AvailableReplicas; got != 3 {
+		t.Fatalf("failed write was not retried, status.availableReplicas = %d", got)
+	}
+}
@@ -0,0 +1,294 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const testNamespace = "default"
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		appsv1alpha1.AddToScheme, appsv1.AddToScheme, corev1.AddToScheme, networkingv1.AddToScheme, policyv1.AddToScheme,
+	} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return scheme
+}
+
+// writeCounter 按操作统计 fake client 上的写入次数
+type writeCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (w *writeCounter) add(op string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.counts == nil {
+		w.counts = map[string]int{}
+	}
+	w.counts[op]++
+}
+
+func (w *writeCounter) get(op string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.counts[op]
+}
+
+func (w *writeCounter) total() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := 0
+	for _, c := range w.counts {
+		n += c
+	}
+	return n
+}
+
+func (w *writeCounter) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.counts = nil
+}
+
+// countingFuncs 在写入前计数，kind 取对象的 Go 类型名，如 "create/Deployment"
+func countingFuncs(w *writeCounter, scheme *runtime.Scheme) interceptor.Funcs {
+	kind := func(obj client.Object) string {
+		if gvk := obj.GetObjectKind().GroupVersionKind(); gvk.Kind != "" {
+			return gvk.Kind
+		}
+		gvks, _, _ := scheme.ObjectKinds(obj)
+		if len(gvks) == 0 {
+			return "Unknown"
+		}
+		return gvks[0].Kind
+	}
+	return interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			w.add("create/" + kind(obj))
+			return c.Create(ctx, obj, opts...)
+		},
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			w.add("update/" + kind(obj))
+			return c.Update(ctx, obj, opts...)
+		},
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			w.add("patch/" + kind(obj))
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+		Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+			w.add("delete/" + kind(obj))
+			return c.Delete(ctx, obj, opts...)
+		},
+		SubResourceUpdate: func(ctx context.Context, c client.Client, sub string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			w.add(sub + "/" + kind(obj))
+			return c.SubResource(sub).Update(ctx, obj, opts...)
+		},
+		SubResourcePatch: func(ctx context.Context, c client.Client, sub string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			w.add(sub + "/" + kind(obj))
+			return c.SubResource(sub).Patch(ctx, obj, patch, opts...)
+		},
+	}
+}
+
+// testEnv 是使用 fake client 的控制器及其观察手段
+type testEnv struct {
+	c        *CustomDeploymentController
+	recorder *record.FakeRecorder
+	writes   *writeCounter
+}
+
+// envConfig 是 newTestEnv 的可选配置
+type envConfig struct {
+	funcs          *interceptor.Funcs
+	serviceMonitor bool
+}
+
+type envOption func(*envConfig)
+
+// withInterceptor 注入 fake client 的行为（如返回错误），没有设置的操作照常执行并计数
+func withInterceptor(funcs interceptor.Funcs) envOption {
+	return func(c *envConfig) { c.funcs = &funcs }
+}
+
+// withServiceMonitorCRD 模拟集群中安装了 ServiceMonitor CRD
+func withServiceMonitorCRD() envOption {
+	return func(c *envConfig) { c.serviceMonitor = true }
+}
+
+// newTestEnv 创建使用 fake client 的控制器
+func newTestEnv(t *testing.T, objs []client.Object, opts ...envOption) *testEnv {
+	t.Helper()
+	cfg := &envConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	scheme := testScheme(t)
+	writes := &writeCounter{}
+	f := countingFuncs(writes, scheme)
+	if cfg.funcs != nil {
+		f = chainFuncs(*cfg.funcs, f)
+	}
+	builder := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&appsv1alpha1.CustomDeployment{}, &appsv1.Deployment{}).
+		WithIndex(&appsv1alpha1.CustomDeployment{}, configFromIndex, indexConfigFrom).
+		WithInterceptorFuncs(f)
+	if cfg.serviceMonitor {
+		// 自定义 RESTMapper 需要包含测试用到的全部类型，Namespace 以外都按 namespace 级资源处理
+		mapper := meta.NewDefaultRESTMapper(nil)
+		for gvk := range scheme.AllKnownTypes() {
+			scope := meta.RESTScopeNamespace
+			if gvk.Kind == "Namespace" {
+				scope = meta.RESTScopeRoot
+			}
+			mapper.Add(gvk, scope)
+		}
+		mapper.Add(serviceMonitorGVK, meta.RESTScopeNamespace)
+		builder = builder.WithRESTMapper(mapper)
+	}
+	cl := builder.Build()
+	recorder := record.NewFakeRecorder(100)
+	return &testEnv{
+		c:        &CustomDeploymentController{Client: cl, Scheme: scheme, Recorder: recorder},
+		recorder: recorder,
+		writes:   writes,
+	}
+}
+
+// chainFuncs 让 first 中设置的函数优先处理，没有设置的交给 next
+func chainFuncs(first, next interceptor.Funcs) interceptor.Funcs {
+	if first.Create == nil {
+		first.Create = next.Create
+	}
+	if first.Update == nil {
+		first.Update = next.Update
+	}
+	if first.Patch == nil {
+		first.Patch = next.Patch
+	}
+	if first.Delete == nil {
+		first.Delete = next.Delete
+	}
+	if first.SubResourceUpdate == nil {
+		first.SubResourceUpdate = next.SubResourceUpdate
+	}
+	if first.SubResourcePatch == nil {
+		first.SubResourcePatch = next.SubResourcePatch
+	}
+	return first
+}
+
+func newCustomDeployment(name string, mutate ...func(*appsv1alpha1.CustomDeployment)) *appsv1alpha1.CustomDeployment {
+	cd := &appsv1alpha1.CustomDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Generation: 1},
+		Spec: appsv1alpha1.CustomDeploymentSpec{
+			Replicas: ptrInt32(2),
+			Image:    "registry.example.com/app:v1",
+		},
+	}
+	for _, m := range mutate {
+		m(cd)
+	}
+	return cd
+}
+
+func ptrInt32(v int32) *int32 { return &v }
+
+func requestFor(name string) ctrl.Request {
+	return ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}}
+}
+
+// reconcile 调谐一次并在出错时终止测试
+func (e *testEnv) reconcile(t *testing.T, name string) ctrl.Result {
+	t.Helper()
+	result, err := e.c.Reconcile(context.Background(), requestFor(name))
+	if err != nil {
+		t.Fatalf("reconcile %s: %v", name, err)
+	}
+	return result
+}
+
+// reconcileUntilCreated 依次完成添加 finalizer 和创建 Deployment 两次调谐
+func (e *testEnv) reconcileUntilCreated(t *testing.T, name string) *appsv1.Deployment {
+	t.Helper()
+	e.reconcile(t, name)
+	e.reconcile(t, name)
+	return e.deployment(t, name)
+}
+
+func (e *testEnv) customDeployment(t *testing.T, name string) *appsv1alpha1.CustomDeployment {
+	t.Helper()
+	cd := &appsv1alpha1.CustomDeployment{}
+	if err := e.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: name}, cd); err != nil {
+		t.Fatalf("get CustomDeployment %s: %v", name, err)
+	}
+	return cd
+}
+
+func (e *testEnv) deployment(t *testing.T, name string) *appsv1.Deployment {
+	t.Helper()
+	deploy := &appsv1.Deployment{}
+	if err := e.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: name}, deploy); err != nil {
+		t.Fatalf("get Deployment %s: %v", name, err)
+	}
+	return deploy
+}
+
+// setDeploymentStatus 模拟 Deployment 控制器上报状态
+func (e *testEnv) setDeploymentStatus(t *testing.T, name string, mutate func(*appsv1.DeploymentStatus)) {
+	t.Helper()
+	deploy := e.deployment(t, name)
+	mutate(&deploy.Status)
+	if err := e.c.Status().Update(context.Background(), deploy); err != nil {
+		t.Fatalf("update Deployment %s status: %v", name, err)
+	}
+}
+
+// updateSpec 修改 CR 的 spec 并增加 generation，模拟 API Server 的行为
+func (e *testEnv) updateSpec(t *testing.T, name string, mutate func(*appsv1alpha1.CustomDeployment)) {
+	t.Helper()
+	cd := e.customDeployment(t, name)
+	mutate(cd)
+	cd.Generation++
+	if err := e.c.Update(context.Background(), cd); err != nil {
+		t.Fatalf("update CustomDeployment %s: %v", name, err)
+	}
+}
+
+// events 取出 FakeRecorder 中已记录的全部事件
+func (e *testEnv) events() []string {
+	var events []string
+	for {
+		select {
+		case ev := <-e.recorder.Events:
+			events = append(events, ev)
+		default:
+			return events
+		}
+	}
+}
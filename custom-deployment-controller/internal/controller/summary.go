@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ReconcileSummary 是控制器运行期间的调谐统计，退出时输出到日志，供没有 Prometheus 的环境排查问题
+type ReconcileSummary struct {
+	Controller string
+	Total      int64
+	Succeeded  int64
+	Failed     int64
+	// Managed 是退出时控制器正在管理的对象数
+	Managed   int
+	LastError string
+}
+
+// reconcileStats 累计调谐结果，计数使用原子操作，不阻塞并发调谐
+type reconcileStats struct {
+	succeeded atomic.Int64
+	failed    atomic.Int64
+
+	mu        sync.Mutex
+	lastError string
+}
+
+// observe 记录一次调谐的结果
+func (s *reconcileStats) observe(err error) {
+	if err == nil {
+		s.succeeded.Add(1)
+		return
+	}
+	s.failed.Add(1)
+	s.mu.Lock()
+	s.lastError = err.Error()
+	s.mu.Unlock()
+}
+
+// summary 汇总当前的计数
+func (s *reconcileStats) summary(controller string, managed int) ReconcileSummary {
+	succeeded, failed := s.succeeded.Load(), s.failed.Load()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ReconcileSummary{
+		Controller: controller,
+		Total:      succeeded + failed,
+		Succeeded:  succeeded,
+		Failed:     failed,
+		Managed:    managed,
+		LastError:  s.lastError,
+	}
+}
+
+// Summary 返回 CustomDeployment 控制器的调谐统计
+func (c *CustomDeploymentController) Summary() ReconcileSummary {
+	return c.stats.summary("customdeployment", c.generations.len())
+}
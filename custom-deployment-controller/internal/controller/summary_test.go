@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileStatsSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []error
+		managed int
+		want    ReconcileSummary
+	}{
+		{name: "no reconciles", want: ReconcileSummary{Controller: "test"}},
+		{
+			name:    "successes only",
+			results: []error{nil, nil, nil},
+			managed: 2,
+			want:    ReconcileSummary{Controller: "test", Total: 3, Succeeded: 3, Managed: 2},
+		},
+		{
+			// 只保留最后一次错误，之后的成功不会清除它
+			name:    "mixed results",
+			results: []error{errors.New("first"), nil, errors.New("second"), nil},
+			managed: 1,
+			want:    ReconcileSummary{Controller: "test", Total: 4, Succeeded: 2, Failed: 2, Managed: 1, LastError: "second"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &reconcileStats{}
+			for _, err := range tt.results {
+				stats.observe(err)
+			}
+			if got := stats.summary("test", tt.managed); got != tt.want {
+				t.Fatalf("summary = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileStatsConcurrent(t *testing.T) {
+	stats := &reconcileStats{}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); stats.observe(nil) }()
+		go func() { defer wg.Done(); stats.observe(errors.New("failed")) }()
+	}
+	wg.Wait()
+
+	want := ReconcileSummary{Controller: "test", Total: 100, Succeeded: 50, Failed: 50, LastError: "failed"}
+	if got := stats.summary("test", 0); got != want {
+		t.Fatalf("summary = %+v, want %+v", got, want)
+	}
+}
+
+func TestCustomDeploymentControllerSummary(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web"), newCustomDeployment("api")})
+	env.reconcileUntilCreated(t, "web")
+	env.reconcile(t, "api")
+
+	got := env.c.Summary()
+	want := ReconcileSummary{Controller: "customdeployment", Total: 3, Succeeded: 3, Managed: 2}
+	if got != want {
+		t.Fatalf("summary = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// templateHashAnnotation 是 Deployment 上记录 spec.template 内容 hash 的注解。
+// API Server 会为模板中的字段填充默认值，直接比较会反复更新，只在 hash 变化时整体同步 Pod spec
+const templateHashAnnotation = "apps.myorg.io/template-hash"
+
+// templateHash 返回 spec.template 的 hash，没有设置模板时返回空
+func templateHash(cd *appsv1alpha1.CustomDeployment) string {
+	if cd.Spec.Template == nil {
+		return ""
+	}
+	data, err := json.Marshal(cd.Spec.Template)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// basePodTemplate 返回 Pod 模板的基础：spec.template 的副本，没有设置时为空模板。
+// selector 标签覆盖模板中的同名标签，保证 Deployment 的 selector 总能选中自己的 Pod
+func basePodTemplate(cd *appsv1alpha1.CustomDeployment, labels map[string]string) corev1.PodTemplateSpec {
+	template := corev1.PodTemplateSpec{}
+	if cd.Spec.Template != nil {
+		cd.Spec.Template.DeepCopyInto(&template)
+	}
+	merged := make(map[string]string, len(template.Labels)+len(labels))
+	for k, v := range template.Labels {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	template.Labels = merged
+	return template
+}
+
+// takeManagedContainer 从模板中取出与受管容器同名的容器作为基础，其余容器原样保留；没有同名容器时返回只有名称的新容器
+func takeManagedContainer(pod *corev1.PodSpec, name string) (managed corev1.Container, others []corev1.Container) {
+	managed = corev1.Container{Name: name}
+	for _, c := range pod.Containers {
+		if c.Name == name {
+			managed = c
+			continue
+		}
+		others = append(others, c)
+	}
+	return managed, others
+}
+
+// mergeEnv 在模板的环境变量上追加 spec.env，同名的以 spec.env 为准
+func mergeEnv(base, overrides []corev1.EnvVar) []corev1.EnvVar {
+	if len(overrides) == 0 {
+		return base
+	}
+	if len(base) == 0 {
+		return overrides
+	}
+	overridden := make(map[string]bool, len(overrides))
+	for _, e := range overrides {
+		overridden[e.Name] = true
+	}
+	env := make([]corev1.EnvVar, 0, len(base)+len(overrides))
+	for _, e := range base {
+		if !overridden[e.Name] {
+			env = append(env, e)
+		}
+	}
+	return append(env, overrides...)
+}
+
+// specOrTemplateResources 返回受管容器的资源需求：spec.resources 优先，没有设置时使用模板中受管容器的资源需求
+func specOrTemplateResources(cd *appsv1alpha1.CustomDeployment) corev1.ResourceRequirements {
+	if len(cd.Spec.Resources.Requests) > 0 || len(cd.Spec.Resources.Limits) > 0 || cd.Spec.Template == nil {
+		return *cd.Spec.Resources.DeepCopy()
+	}
+	for _, c := range cd.Spec.Template.Spec.Containers {
+		if c.Name == containerName(cd) {
+			return *c.Resources.DeepCopy()
+		}
+	}
+	return corev1.ResourceRequirements{}
+}
+
+// restartedAtAnnotation 由 kubectl rollout restart 写入 Pod 模板，同步模板时保留
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// syncPodTemplate 在 spec.template 变化（包括设置和删除）时把期望的 Pod spec、标签和注解整体写入线上对象，返回是否有变化。
+// 期望的注解已经包含 config-hash 等控制器写入的注解；模板没有变化时由其他 sync 函数逐个字段同步
+func syncPodTemplate(live, desired *appsv1.Deployment) bool {
+	hash := desired.Annotations[templateHashAnnotation]
+	if live.Annotations[templateHashAnnotation] == hash {
+		return false
+	}
+	live.Spec.Template.Spec = desired.Spec.Template.Spec
+	live.Spec.Template.Labels = desired.Spec.Template.Labels
+
+	restartedAt, restarted := live.Spec.Template.Annotations[restartedAtAnnotation]
+	annotations := make(map[string]string, len(desired.Spec.Template.Annotations)+1)
+	for k, v := range desired.Spec.Template.Annotations {
+		annotations[k] = v
+	}
+	if _, ok := annotations[restartedAtAnnotation]; restarted && !ok {
+		annotations[restartedAtAnnotation] = restartedAt
+	}
+	live.Spec.Template.Annotations = annotations
+	if hash == "" {
+		delete(live.Annotations, templateHashAnnotation)
+	} else {
+		if live.Annotations == nil {
+			live.Annotations = map[string]string{}
+		}
+		live.Annotations[templateHashAnnotation] = hash
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func withTemplate(annotations map[string]string) func(*appsv1alpha1.CustomDeployment) {
+	return func(cd *appsv1alpha1.CustomDeployment) {
+		cd.Spec.Template = &corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				// 与 selector 冲突的标签会被覆盖
+				Labels:      map[string]string{"app": "other", "team": "payments"},
+				Annotations: annotations,
+			},
+			Spec: corev1.PodSpec{
+				Volumes:     []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+				Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+				Containers: []corev1.Container{
+					{Name: "sidecar", Image: "registry.example.com/proxy:v1"},
+					{Name: defaultContainerName, Image: "ignored:v0", VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}}},
+				},
+			},
+		}
+	}
+}
+
+func TestDesiredDeploymentPodTemplate(t *testing.T) {
+	tests := []struct {
+		name           string
+		cd             *appsv1alpha1.CustomDeployment
+		wantContainers []string
+		wantLabels     map[string]string
+		wantVolumes    int
+	}{
+		{
+			name:           "default template",
+			cd:             newCustomDeployment("web"),
+			wantContainers: []string{defaultContainerName},
+			wantLabels:     map[string]string{"app": "web"},
+		},
+		{
+			name:           "template override",
+			cd:             newCustomDeployment("web", withTemplate(nil)),
+			wantContainers: []string{defaultContainerName, "sidecar"},
+			wantLabels:     map[string]string{"app": "web", "team": "payments"},
+			wantVolumes:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deploy := desiredDeployment(tt.cd, map[string]string{"app": tt.cd.Name})
+			pod := deploy.Spec.Template.Spec
+
+			var names []string
+			for _, c := range pod.Containers {
+				names = append(names, c.Name)
+			}
+			if !equalStrings(names, tt.wantContainers) {
+				t.Fatalf("containers = %v, want %v", names, tt.wantContainers)
+			}
+			if got := pod.Containers[0].Image; got != tt.cd.Spec.Image {
+				t.Errorf("managed container image = %q, want %q", got, tt.cd.Spec.Image)
+			}
+			if got := *deploy.Spec.Replicas; got != *tt.cd.Spec.Replicas {
+				t.Errorf("replicas = %d, want %d", got, *tt.cd.Spec.Replicas)
+			}
+			if !maps.Equal(deploy.Spec.Template.Labels, tt.wantLabels) {
+				t.Errorf("pod labels = %v, want %v", deploy.Spec.Template.Labels, tt.wantLabels)
+			}
+			if len(pod.Volumes) != tt.wantVolumes {
+				t.Errorf("volumes = %d, want %d", len(pod.Volumes), tt.wantVolumes)
+			}
+			if tt.cd.Spec.Template != nil && len(pod.Containers[0].VolumeMounts) != 1 {
+				t.Errorf("managed container lost its volume mounts from the template")
+			}
+		})
+	}
+}
+
+func TestReconcileTemplateUpdate(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newCustomDeployment("web", withTemplate(map[string]string{"example.com/version": "1"}))})
+	deploy := env.reconcileUntilCreated(t, "web")
+	if got := deploy.Spec.Template.Annotations["example.com/version"]; got != "1" {
+		t.Fatalf("template annotation = %q, want 1", got)
+	}
+
+	// 模拟 kubectl rollout restart 写入的注解，更新模板时应保留
+	deploy.Spec.Template.Annotations[restartedAtAnnotation] = "2026-01-01T00:00:00Z"
+	if err := env.c.Update(context.Background(), deploy); err != nil {
+		t.Fatal(err)
+	}
+
+	env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) {
+		cd.Spec.Template.Annotations = map[string]string{"example.com/version": "2"}
+		cd.Spec.Template.Spec.Tolerations = nil
+	})
+	env.reconcile(t, "web")
+
+	deploy = env.deployment(t, "web")
+	if got := deploy.Spec.Template.Annotations["example.com/version"]; got != "2" {
+		t.Errorf("template annotation = %q, want 2", got)
+	}
+	if _, ok := deploy.Spec.Template.Annotations[restartedAtAnnotation]; !ok {
+		t.Errorf("restartedAt annotation was dropped")
+	}
+	if len(deploy.Spec.Template.Spec.Tolerations) != 0 {
+		t.Errorf("tolerations removed from the template are still set: %v", deploy.Spec.Template.Spec.Tolerations)
+	}
+
+	// 删除模板后回到默认的 Pod 模板
+	env.updateSpec(t, "web", func(cd *appsv1alpha1.CustomDeployment) { cd.Spec.Template = nil })
+	env.reconcile(t, "web")
+	deploy = env.deployment(t, "web")
+	if n := len(deploy.Spec.Template.Spec.Containers); n != 1 {
+		t.Errorf("containers after removing the template = %d, want 1", n)
+	}
+	if _, ok := deploy.Annotations[templateHashAnnotation]; ok {
+		t.Errorf("template hash annotation left behind")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
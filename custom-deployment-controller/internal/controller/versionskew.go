@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// versionSkewRequeueAfter 是 CRD 版本迁移期间重试的间隔
+const versionSkewRequeueAfter = 30 * time.Second
+
+// isVersionSkewError 判断错误是否由 CRD 升级期间的版本不一致引起：
+// 请求的版本暂时不被 API Server 提供（NoKindMatch），或者版本之间的转换失败。
+// 这类错误会在迁移完成后自行消失，按固定间隔重试，而不是指数退避或计入失败
+func isVersionSkewError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if meta.IsNoMatchError(err) || runtime.IsNotRegisteredError(err) {
+		return true
+	}
+	// 转换 webhook 失败时 API Server 返回 500，原因只体现在消息中
+	return errors.IsInternalError(err) && strings.Contains(err.Error(), "conversion")
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestReconcileVersionSkewRequeues(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantRequeue bool
+	}{
+		{
+			name:        "conversion webhook failure",
+			err:         apierrors.NewInternalError(errors.New("conversion webhook for apps.myorg.io/v1beta1, Kind=CustomDeployment failed")),
+			wantRequeue: true,
+		},
+		{
+			name:        "version not served",
+			err:         &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "apps.myorg.io", Kind: "CustomDeployment"}, SearchedVersions: []string{"v1alpha1"}},
+			wantRequeue: true,
+		},
+		{name: "other internal error", err: apierrors.NewInternalError(errors.New("etcdserver: request timed out"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newCustomDeployment("web")}, withInterceptor(interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					if _, ok := obj.(*appsv1alpha1.CustomDeployment); ok {
+						return tt.err
+					}
+					return c.Get(ctx, key, obj, opts...)
+				},
+			}))
+
+			result, err := env.c.Reconcile(context.Background(), requestFor("web"))
+			if tt.wantRequeue {
+				if err != nil {
+					t.Fatalf("Reconcile error = %v, want a clean requeue", err)
+				}
+				if result.RequeueAfter != versionSkewRequeueAfter {
+					t.Fatalf("RequeueAfter = %v, want %v", result.RequeueAfter, versionSkewRequeueAfter)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected other errors to be returned for backoff")
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/mutate-apps-myorg-io-v1alpha1-customdeployment,mutating=true,failurePolicy=fail,sideEffects=None,groups=apps.myorg.io,resources=customdeployments,verbs=create;update,versions=v1alpha1,name=mcustomdeployment.apps.myorg.io,admissionReviewVersions=v1
+
+// defaultReplicas 是没有设置 spec.replicas 时的副本数
+const defaultReplicas int32 = 1
+
+// CustomDeploymentDefaulter 为没有设置的字段填入默认值。只填空字段，重复提交同一个对象不会产生变化
+type CustomDeploymentDefaulter struct {
+	// Image 是没有设置 spec.image 时使用的镜像
+	Image string
+}
+
+var _ admission.CustomDefaulter = &CustomDeploymentDefaulter{}
+
+// Default 设置 spec.replicas 和 spec.image 的默认值。副本数只在创建时默认，
+// 已有的没有设置副本数的 CR 一直按 0 运行，更新时（包括控制器增删 finalizer）不会被悄悄扩容
+func (d *CustomDeploymentDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	cd, ok := obj.(*appsv1alpha1.CustomDeployment)
+	if !ok {
+		return fmt.Errorf("expected a CustomDeployment, got %T", obj)
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if cd.Spec.Replicas == nil && req.Operation == admissionv1.Create {
+		cd.Spec.Replicas = ptr.To(defaultReplicas)
+	}
+	if cd.Spec.Image == "" {
+		cd.Spec.Image = d.Image
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-apps-myorg-io-v1alpha1-customdeployment,mutating=false,failurePolicy=fail,sideEffects=None,groups=apps.myorg.io,resources=customdeployments,verbs=create;update,versions=v1alpha1,name=vcustomdeployment.apps.myorg.io,admissionReviewVersions=v1
+
+// CustomDeploymentValidator 在准入阶段拒绝明显无效的 CustomDeployment，避免它们被创建后才在调谐时失败
+type CustomDeploymentValidator struct{}
+
+var _ admission.CustomValidator = &CustomDeploymentValidator{}
+
+// SetupCustomDeploymentWebhookWithManager 把默认值和校验 webhook 注册到 Manager 的 webhook 服务上，
+// defaultImage 是没有设置镜像时填入的镜像
+func SetupCustomDeploymentWebhookWithManager(mgr ctrl.Manager, defaultImage string) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&appsv1alpha1.CustomDeployment{}).
+		WithDefaulter(&CustomDeploymentDefaulter{Image: defaultImage}).
+		WithValidator(&CustomDeploymentValidator{}).
+		Complete()
+}
+
+// ValidateCreate 校验新建的 CustomDeployment
+func (v *CustomDeploymentValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	cd, ok := obj.(*appsv1alpha1.CustomDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected a CustomDeployment, got %T", obj)
+	}
+	return nil, validate(cd)
+}
+
+// ValidateUpdate 校验更新后的 CustomDeployment。spec 没有变化的更新（如控制器增删 finalizer）总是放行，
+// 否则在校验规则生效前创建的对象会因为不满足新规则而无法被删除
+func (v *CustomDeploymentValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldCD, ok := oldObj.(*appsv1alpha1.CustomDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected a CustomDeployment, got %T", oldObj)
+	}
+	cd, ok := newObj.(*appsv1alpha1.CustomDeployment)
+	if !ok {
+		return nil, fmt.Errorf("expected a CustomDeployment, got %T", newObj)
+	}
+	if equality.Semantic.DeepEqual(oldCD.Spec, cd.Spec) {
+		return nil, nil
+	}
+	return nil, validate(cd)
+}
+
+// ValidateDelete 总是允许删除
+func (v *CustomDeploymentValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate 校验 spec，所有问题合并为一个 Invalid 错误返回
+func validate(cd *appsv1alpha1.CustomDeployment) error {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+	if cd.Spec.Replicas != nil && *cd.Spec.Replicas < 0 {
+		errs = append(errs, field.Invalid(spec.Child("replicas"), *cd.Spec.Replicas, "must not be negative"))
+	}
+	if cd.Spec.Image == "" {
+		errs = append(errs, field.Required(spec.Child("image"), "an image must be specified"))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(appsv1alpha1.GroupVersion.WithKind("CustomDeployment").GroupKind(), cd.Name, errs)
+}
@@ -0,0 +1,144 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func newCustomDeployment(replicas *int32, image string) *appsv1alpha1.CustomDeployment {
+	return &appsv1alpha1.CustomDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       appsv1alpha1.CustomDeploymentSpec{Replicas: replicas, Image: image},
+	}
+}
+
+func TestCustomDeploymentValidator(t *testing.T) {
+	valid := newCustomDeployment(ptr.To[int32](2), "registry.example.com/app:v1")
+	// 校验规则生效前创建的无效对象
+	legacy := newCustomDeployment(ptr.To[int32](-1), "")
+	legacy.Finalizers = []string{"apps.myorg.io/finalizer"}
+	tests := []struct {
+		name     string
+		validate func(v *CustomDeploymentValidator) error
+		wantErr  bool
+	}{
+		{
+			name: "create valid",
+			validate: func(v *CustomDeploymentValidator) error {
+				_, err := v.ValidateCreate(context.Background(), valid)
+				return err
+			},
+		},
+		{
+			name: "create with unset replicas",
+			validate: func(v *CustomDeploymentValidator) error {
+				_, err := v.ValidateCreate(context.Background(), newCustomDeployment(nil, "registry.example.com/app:v1"))
+				return err
+			},
+		},
+		{
+			name: "create with negative replicas",
+			validate: func(v *CustomDeploymentValidator) error {
+				_, err := v.ValidateCreate(context.Background(), newCustomDeployment(ptr.To[int32](-1), "registry.example.com/app:v1"))
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "create with empty image",
+			validate: func(v *CustomDeploymentValidator) error {
+				_, err := v.ValidateCreate(context.Background(), newCustomDeployment(ptr.To[int32](2), ""))
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "update to negative replicas",
+			validate: func(v *CustomDeploymentValidator) error {
+				_, err := v.ValidateUpdate(context.Background(), valid, newCustomDeployment(ptr.To[int32](-3), "registry.example.com/app:v1"))
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "metadata-only update of an invalid object",
+			validate: func(v *CustomDeploymentValidator) error {
+				updated := legacy.DeepCopy()
+				updated.Finalizers = nil
+				_, err := v.ValidateUpdate(context.Background(), legacy, updated)
+				return err
+			},
+		},
+		{
+			name: "delete invalid object",
+			validate: func(v *CustomDeploymentValidator) error {
+				_, err := v.ValidateDelete(context.Background(), legacy)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate(&CustomDeploymentValidator{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !apierrors.IsInvalid(err) {
+				t.Fatalf("error = %v, want an Invalid error", err)
+			}
+		})
+	}
+}
+
+func TestCustomDeploymentDefaulter(t *testing.T) {
+	const defaultImage = "registry.example.com/default:v1"
+	tests := []struct {
+		name         string
+		operation    admissionv1.Operation
+		replicas     *int32
+		image        string
+		wantReplicas *int32
+		wantImage    string
+	}{
+		{name: "empty fields defaulted", operation: admissionv1.Create, wantReplicas: ptr.To[int32](1), wantImage: defaultImage},
+		{name: "set fields kept", operation: admissionv1.Create, replicas: ptr.To[int32](3), image: "registry.example.com/app:v1", wantReplicas: ptr.To[int32](3), wantImage: "registry.example.com/app:v1"},
+		// 显式设置的 0 不是空值
+		{name: "zero replicas kept", operation: admissionv1.Create, replicas: ptr.To[int32](0), image: "registry.example.com/app:v1", wantReplicas: ptr.To[int32](0), wantImage: "registry.example.com/app:v1"},
+		// 更新时不默认副本数，已有的 CR 不会被悄悄扩容
+		{name: "replicas not defaulted on update", operation: admissionv1.Update, wantImage: defaultImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &CustomDeploymentDefaulter{Image: defaultImage}
+			ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{Operation: tt.operation},
+			})
+			cd := newCustomDeployment(tt.replicas, tt.image)
+			if err := d.Default(ctx, cd); err != nil {
+				t.Fatal(err)
+			}
+			if !ptr.Equal(cd.Spec.Replicas, tt.wantReplicas) || cd.Spec.Image != tt.wantImage {
+				t.Fatalf("spec = replicas %v image %q, want replicas %v image %q",
+					ptr.Deref(cd.Spec.Replicas, -1), cd.Spec.Image, ptr.Deref(tt.wantReplicas, -1), tt.wantImage)
+			}
+
+			// 重复默认不产生变化
+			again := cd.DeepCopy()
+			if err := d.Default(ctx, again); err != nil {
+				t.Fatal(err)
+			}
+			if !equality.Semantic.DeepEqual(again, cd) {
+				t.Fatalf("second Default changed the object: %+v -> %+v", cd.Spec, again.Spec)
+			}
+		})
+	}
+}
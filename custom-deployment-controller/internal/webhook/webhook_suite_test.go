@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"custom-deployment-controller/api/appsv1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// testDefaultImage 是测试中 defaulting webhook 填入的镜像
+const testDefaultImage = "registry.example.com/default:v1"
+
+// startWebhookEnv 启动 envtest 的 API Server，安装 config/ 下的 CRD 和 webhook 配置，
+// 并在本地运行注册了 webhook 的 Manager。没有设置 KUBEBUILDER_ASSETS（setup-envtest 下载的二进制目录）时跳过
+func startWebhookEnv(t *testing.T) client.Client {
+	t.Helper()
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set, skipping envtest")
+	}
+
+	env := &envtest.Environment{
+		CRDInstallOptions: envtest.CRDInstallOptions{
+			Paths:              []string{filepath.Join("..", "..", "config", "crd", "customdeployments.yaml")},
+			ErrorIfPathMissing: true,
+		},
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join("..", "..", "config", "webhook")},
+		},
+	}
+	cfg, err := env.Start()
+	if err != nil {
+		t.Fatalf("start envtest: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := env.Stop(); err != nil {
+			t.Errorf("stop envtest: %v", err)
+		}
+	})
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := appsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := env.WebhookInstallOptions
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    opts.LocalServingHost,
+			Port:    opts.LocalServingPort,
+			CertDir: opts.LocalServingCertDir,
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetupCustomDeploymentWebhookWithManager(mgr, testDefaultImage); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := mgr.Start(ctx); err != nil {
+			t.Errorf("manager: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// API Server 调用 webhook 之前，webhook 服务必须已经在监听
+	addr := net.JoinHostPort(opts.LocalServingHost, fmt.Sprint(opts.LocalServingPort))
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp", addr, &tls.Config{InsecureSkipVerify: true})
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("webhook server at %s did not become ready: %v", addr, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCustomDeploymentWebhookAdmission(t *testing.T) {
+	c := startWebhookEnv(t)
+	ctx := context.Background()
+
+	// 创建时填入默认的副本数和镜像
+	cd := newCustomDeployment(nil, "")
+	if err := c.Create(ctx, cd); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if got := ptr.Deref(cd.Spec.Replicas, -1); got != defaultReplicas {
+		t.Fatalf("defaulted replicas = %d, want %d", got, defaultReplicas)
+	}
+	if cd.Spec.Image != testDefaultImage {
+		t.Fatalf("defaulted image = %q, want %q", cd.Spec.Image, testDefaultImage)
+	}
+
+	// 更新为无效的 spec 被拒绝
+	invalid := cd.DeepCopy()
+	invalid.Spec.Replicas = ptr.To[int32](-1)
+	if err := c.Update(ctx, invalid); !apierrors.IsInvalid(err) {
+		t.Fatalf("update with negative replicas: err = %v, want Invalid", err)
+	}
+
+	// 有效的更新被放行，清空副本数不会在更新时被默认
+	cd.Spec.Replicas = nil
+	if err := c.Update(ctx, cd); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if cd.Spec.Replicas != nil {
+		t.Fatalf("replicas defaulted on update to %d", *cd.Spec.Replicas)
+	}
+
+	// 创建无效的对象被拒绝
+	bad := newCustomDeployment(ptr.To[int32](-1), "registry.example.com/app:v1")
+	bad.Name = "bad"
+	if err := c.Create(ctx, bad); !apierrors.IsInvalid(err) {
+		t.Fatalf("create with negative replicas: err = %v, want Invalid", err)
+	}
+}
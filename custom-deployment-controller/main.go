@@ -1,20 +1,110 @@
 package main
 
 import (
+	"context"
 	"custom-deployment-controller/api/appsv1alpha1"
 	"custom-deployment-controller/internal/controller"
+	"custom-deployment-controller/internal/webhook"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
+// logShutdownSummaries 在退出前按控制器输出调谐统计
+func logShutdownSummaries(logger logr.Logger, summaries []func() controller.ReconcileSummary) {
+	for _, summary := range summaries {
+		s := summary()
+		logger.Info("Reconcile summary", "controller", s.Controller, "total", s.Total,
+			"succeeded", s.Succeeded, "failed", s.Failed, "managed", s.Managed, "lastError", s.LastError)
+	}
+}
+
+// adminServer 返回在 Manager 中运行的管理接口 HTTP 服务，Manager 停止时关闭
+func adminServer(addr string, handler http.Handler) manager.Runnable {
+	return manager.RunnableFunc(func(ctx context.Context) error {
+		srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			_ = srv.Shutdown(context.Background())
+		}()
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	})
+}
+
 func main() {
 	// 这里是 main 函数的入口，通常会在这里设置 Manager 和 Controller
+	var allowedRegistries string
+	var noBlockOwnerDeletion bool
+	var deadLetterConfigMap, deadLetterNamespace string
+	var deadLetterAfter, deadLetterMaxEntries int
+	var selectorLabelKey string
+	var sizes string
+	var maxReplicas int
+	var statusBatchWindow time.Duration
+	var defaultCPURequest, defaultMemoryRequest string
+	var resolveImageDigests bool
+	var registryTokenHosts string
+	var adminAddr string
+	var finalizerTimeout time.Duration
+	var enableWebhook bool
+	var psaDefaults bool
+	var backlogAlarmThreshold int
+	var backlogAlarmWindow time.Duration
+	flag.StringVar(&allowedRegistries, "allowed-registries", "", "Comma-separated list of image registries CustomDeployments may use (empty = any registry); an entry without a port, e.g. registry.local, allows every port of that host, an entry with a port, e.g. registry.local:5000, allows only that port")
+	flag.BoolVar(&noBlockOwnerDeletion, "no-block-owner-deletion", false, "Set blockOwnerDeletion=false on owner references of managed objects")
+	flag.StringVar(&deadLetterConfigMap, "dead-letter-configmap", "", "Name of the ConfigMap recording persistently failing objects (empty = disabled)")
+	flag.StringVar(&deadLetterNamespace, "dead-letter-namespace", "", "Namespace of the dead-letter ConfigMap (default: the controller's namespace)")
+	flag.IntVar(&deadLetterAfter, "dead-letter-after", 5, "Consecutive reconcile failures before an object is recorded in the dead-letter ConfigMap")
+	flag.IntVar(&deadLetterMaxEntries, "dead-letter-max-entries", 100, "Maximum number of records kept in the dead-letter ConfigMap")
+	flag.StringVar(&selectorLabelKey, "selector-label-key", controller.DefaultSelectorLabelKey, "Label key used for Deployment selectors, pod labels and ServiceMonitor selectors; changing it requires recreating existing Deployments because selectors are immutable")
+	flag.StringVar(&sizes, "sizes", "small=1,medium=3,large=5", "Replica counts for spec.size, as comma-separated name=replicas pairs")
+	flag.IntVar(&maxReplicas, "max-replicas", 0, "Reject CustomDeployments asking for more replicas than this (0 = no limit)")
+	flag.DurationVar(&statusBatchWindow, "status-batch-window", 0, "Coalesce status writes of each CustomDeployment over this window (0 = write immediately)")
+	flag.StringVar(&defaultCPURequest, "default-cpu-request", "", "CPU request for containers of CustomDeployments that set no resources, e.g. 100m (empty = none)")
+	flag.StringVar(&defaultMemoryRequest, "default-memory-request", "", "Memory request for containers of CustomDeployments that set no resources, e.g. 128Mi (empty = none)")
+	flag.BoolVar(&resolveImageDigests, "resolve-image-digests", false, "Resolve image tags through the registry API and record the digest in the apps.myorg.io/resolved-image-digest annotation; only anonymous (public) registry access is supported")
+	flag.StringVar(&registryTokenHosts, "registry-token-hosts", strings.Join(controller.DefaultTokenRealmHosts, ","), "Comma-separated list of hosts, besides the registry itself, that registry token realms may point to when resolving image digests (empty = only the registry itself)")
+	flag.StringVar(&adminAddr, "admin-addr", "", "Address of the read-only admin endpoints such as /debug/object (empty = disabled)")
+	flag.DurationVar(&finalizerTimeout, "finalizer-timeout", 0, "Force-remove the finalizer of a CustomDeployment whose cleanup has not finished this long after deletion (0 = wait forever); it is always removed when the namespace is terminating")
+	flag.IntVar(&backlogAlarmThreshold, "backlog-alarm-threshold", 0, "Set reconcile_backlog_exceeded to 1 when the work queue depth stays above this for -backlog-alarm-window (0 = disabled)")
+	flag.DurationVar(&backlogAlarmWindow, "backlog-alarm-window", 5*time.Minute, "How long the work queue depth must stay above -backlog-alarm-threshold before the alarm fires")
+	flag.BoolVar(&enableWebhook, "enable-webhook", false, "Serve the CustomDeployment defaulting and validating webhooks; needs serving certificates in /tmp/k8s-webhook-server/serving-certs")
+	flag.BoolVar(&psaDefaults, "psa-defaults", false, "Give managed pods a securityContext compliant with the restricted Pod Security Standard (runAsNonRoot, seccomp RuntimeDefault, drop ALL capabilities); images must run as a non-root user")
+	flag.Parse()
 
 	logger := ctrl.Log.WithName("setup")
+	sizeReplicas, err := controller.ParseSizes(sizes)
+	if err != nil {
+		logger.Error(err, "Invalid -sizes")
+		os.Exit(1)
+	}
+	if maxReplicas < 0 || maxReplicas > math.MaxInt32 {
+		logger.Error(fmt.Errorf("must be between 0 and %d, got %d", math.MaxInt32, maxReplicas), "Invalid -max-replicas")
+		os.Exit(1)
+	}
+	defaultRequests, err := controller.ParseResourceRequests(defaultCPURequest, defaultMemoryRequest)
+	if err != nil {
+		logger.Error(err, "Invalid default resource requests")
+		os.Exit(1)
+	}
 	scheme := runtime.NewScheme()
 	if err := appsv1alpha1.AddToScheme(scheme); err != nil {
 		logger.Error(err, "Failed to add appsv1alpha1 to scheme")
@@ -28,6 +118,14 @@ func main() {
 		logger.Error(err, "Failed to add core/v1 to scheme")
 		os.Exit(1)
 	}
+	if err := networkingv1.AddToScheme(scheme); err != nil {
+		logger.Error(err, "Failed to add networking/v1 to scheme")
+		os.Exit(1)
+	}
+	if err := policyv1.AddToScheme(scheme); err != nil {
+		logger.Error(err, "Failed to add policy/v1 to scheme")
+		os.Exit(1)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
@@ -38,20 +136,94 @@ func main() {
 	}
 
 	reconciler := &controller.CustomDeploymentController{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("customdeployment-controller"),
+
+		AllowedRegistries:    controller.ParseRegistries(allowedRegistries),
+		NoBlockOwnerDeletion: noBlockOwnerDeletion,
+		SelectorLabelKey:     selectorLabelKey,
+		Sizes:                sizeReplicas,
+		DefaultRequests:      defaultRequests,
+		MaxReplicas:          int32(maxReplicas),
+		FinalizerTimeout:     finalizerTimeout,
+		PSADefaults:          psaDefaults,
+	}
+	if resolveImageDigests {
+		// 空列表表示只允许仓库本身签发 token，不能退回默认值
+		tokenHosts := controller.ParseRegistries(registryTokenHosts)
+		if tokenHosts == nil {
+			tokenHosts = []string{}
+		}
+		reconciler.Resolver = &controller.RegistryResolver{TokenRealmHosts: tokenHosts}
+	}
+	if statusBatchWindow > 0 {
+		reconciler.StatusBatcher = &controller.StatusBatcher{
+			Client: mgr.GetClient(),
+			Reader: mgr.GetAPIReader(),
+			Window: statusBatchWindow,
+		}
+		if err := mgr.Add(reconciler.StatusBatcher); err != nil {
+			logger.Error(err, "Unable to create status batcher")
+			os.Exit(1)
+		}
+	}
+	if backlogAlarmThreshold > 0 {
+		reconciler.BacklogAlarm = &controller.BacklogAlarm{
+			Threshold: backlogAlarmThreshold,
+			Window:    backlogAlarmWindow,
+		}
+	}
+	if deadLetterConfigMap != "" {
+		if deadLetterNamespace == "" {
+			// 集群内使用 Pod 所在的 namespace，集群外使用 kubeconfig 当前 context 的 namespace
+			deadLetterNamespace, _, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+				clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{}).Namespace()
+			if err != nil {
+				logger.Error(err, "Unable to determine the controller namespace, set -dead-letter-namespace")
+				os.Exit(1)
+			}
+		}
+		reconciler.DeadLetter = &controller.DeadLetterRecorder{
+			Client:     mgr.GetClient(),
+			Namespace:  deadLetterNamespace,
+			Name:       deadLetterConfigMap,
+			After:      deadLetterAfter,
+			MaxEntries: deadLetterMaxEntries,
+		}
 	}
 
-	if err := ctrl.NewControllerManagedBy(mgr).
-		For(&appsv1alpha1.CustomDeployment{}).
-		Owns(&appsv1.Deployment{}).
-		Complete(reconciler); err != nil {
+	if err := reconciler.SetupWithManager(mgr); err != nil {
 		logger.Error(err, "Unable to create controller")
 		os.Exit(1)
 	}
+	// 收到 SIGUSR1 时把受管对象清单输出到日志
+	if err := mgr.Add(reconciler.DumpInventoryOnSignal(mgr.GetAPIReader())); err != nil {
+		logger.Error(err, "Unable to create inventory dump")
+		os.Exit(1)
+	}
+	if adminAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/object", reconciler.DebugHandler())
+		if err := mgr.Add(adminServer(adminAddr, mux)); err != nil {
+			logger.Error(err, "Unable to create admin server")
+			os.Exit(1)
+		}
+	}
+	// webhook 服务需要证书，默认不启用，避免没有证书的部署启动失败
+	if enableWebhook {
+		if err := webhook.SetupCustomDeploymentWebhookWithManager(mgr, controller.DefaultImage); err != nil {
+			logger.Error(err, "Unable to create webhook", "webhook", "CustomDeployment")
+			os.Exit(1)
+		}
+		logger.Info("Defaulting and validating webhooks enabled")
+	}
 
 	logger.Info("Starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	// Start 在收到 SIGTERM 并停止所有控制器后返回，此时的统计是完整的
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	logShutdownSummaries(logger, []func() controller.ReconcileSummary{reconciler.Summary})
+	if err != nil {
 		logger.Error(err, "Problem running manager")
 		os.Exit(1)
 	}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// annotationPrefix 是控制器识别的注解前缀
+const annotationPrefix = "simple-controller/"
+
+// knownAnnotations 是 ConfigMap 上允许出现的控制器注解，新增注解时需要在这里登记
+var knownAnnotations = map[string]bool{
+	defaultSyncAnnotation:             true,
+	ownerModeAnnotation:               true,
+	targetNamespaceSelectorAnnotation: true,
+	targetNamespacesAnnotation:        true,
+	checksumOnlyAnnotation:            true,
+	createNamespaceAnnotation:         true,
+	nameHashAnnotation:                true,
+	validationJobAnnotation:           true,
+	additionalSourcesAnnotation:       true,
+	syncKeysAnnotation:                true,
+	secretNameAnnotation:              true,
+	decodeBase64Annotation:            true,
+	secretTypeAnnotation:              true,
+	allowRecreateAnnotation:           true,
+	propagateLabelsAnnotation:         true,
+	consumerSelectorAnnotation:        true,
+	syncErrorAnnotation:               true,
+}
+
+// booleanAnnotations 的值只能是 true 或 false
+var booleanAnnotations = []string{
+	checksumOnlyAnnotation,
+	createNamespaceAnnotation,
+	nameHashAnnotation,
+	decodeBase64Annotation,
+	allowRecreateAnnotation,
+	propagateLabelsAnnotation,
+}
+
+// annotationConflict 描述两个不能同时启用的注解
+type annotationConflict struct {
+	a, b   string
+	reason string
+}
+
+// annotationConflicts 是互相矛盾的注解组合，新增注解时在这里登记与已有注解的冲突
+var annotationConflicts = []annotationConflict{
+	{syncKeysAnnotation, checksumOnlyAnnotation, "checksum-only does not copy any keys"},
+	{secretNameAnnotation, nameHashAnnotation, "name-hash would change the chosen Secret name"},
+}
+
+// annotationEnabled 判断注解是否启用：存在且值不是 false
+func annotationEnabled(cm *corev1.ConfigMap, key string) bool {
+	v, ok := cm.Annotations[key]
+	return ok && v != "false"
+}
+
+// validateAnnotations 集中校验 ConfigMap 上的控制器注解：取值是否合法、组合是否冲突。
+// 返回的 unknown 是未登记的控制器注解（通常是拼写错误），只需要提醒用户，不影响同步
+func validateAnnotations(cm *corev1.ConfigMap) (unknown []string, err error) {
+	for key := range cm.Annotations {
+		if strings.HasPrefix(key, annotationPrefix) && !knownAnnotations[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	if _, err := ownerMode(cm); err != nil {
+		return unknown, err
+	}
+	if _, err := targetNamespaceSelector(cm); err != nil {
+		return unknown, err
+	}
+	if _, err := targetNamespaceList(cm); err != nil {
+		return unknown, err
+	}
+	if _, err := consumerSelector(cm); err != nil {
+		return unknown, err
+	}
+	if _, err := additionalSources(cm); err != nil {
+		return unknown, err
+	}
+	if _, _, err := syncKeys(cm); err != nil {
+		return unknown, err
+	}
+	if _, err := secretType(cm); err != nil {
+		return unknown, err
+	}
+	if name, ok := cm.Annotations[secretNameAnnotation]; ok {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return unknown, fmt.Errorf("invalid %s %q: %s", secretNameAnnotation, name, strings.Join(errs, "; "))
+		}
+	}
+	for _, key := range booleanAnnotations {
+		if v, ok := cm.Annotations[key]; ok && v != "true" && v != "false" {
+			return unknown, fmt.Errorf("invalid %s %q: must be true or false", key, v)
+		}
+	}
+	for _, c := range annotationConflicts {
+		if annotationEnabled(cm, c.a) && annotationEnabled(cm, c.b) {
+			return unknown, fmt.Errorf("annotations %s and %s cannot be used together: %s", c.a, c.b, c.reason)
+		}
+	}
+	return unknown, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantErr     string
+		wantUnknown []string
+	}{
+		{
+			name:        "sync-keys with checksum-only",
+			annotations: map[string]string{syncKeysAnnotation: "password", checksumOnlyAnnotation: "true"},
+			wantErr:     "cannot be used together",
+		},
+		{
+			name:        "secret-name with name-hash",
+			annotations: map[string]string{secretNameAnnotation: "db-creds", nameHashAnnotation: "true"},
+			wantErr:     "cannot be used together",
+		},
+		{
+			name:        "sync-keys with checksum-only disabled",
+			annotations: map[string]string{syncKeysAnnotation: "password", checksumOnlyAnnotation: "false"},
+		},
+		{
+			name:        "secret-name with name-hash disabled",
+			annotations: map[string]string{secretNameAnnotation: "db-creds", nameHashAnnotation: "false"},
+		},
+		{
+			name:        "checksum-only with name-hash",
+			annotations: map[string]string{checksumOnlyAnnotation: "true", nameHashAnnotation: "true"},
+		},
+		{
+			name:        "invalid boolean",
+			annotations: map[string]string{checksumOnlyAnnotation: "yes"},
+			wantErr:     "must be true or false",
+		},
+		{
+			name:        "unknown annotation only warns",
+			annotations: map[string]string{annotationPrefix + "sync-key": "password"},
+			wantUnknown: []string{annotationPrefix + "sync-key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newConfigMap("app", func(cm *corev1.ConfigMap) {
+				for k, v := range tt.annotations {
+					cm.Annotations[k] = v
+				}
+			})
+			unknown, err := validateAnnotations(cm)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if !slices.Equal(unknown, tt.wantUnknown) {
+				t.Fatalf("unknown = %v, want %v", unknown, tt.wantUnknown)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// 注解：设置为 true 时只同步内容哈希，不复制数据，适用于只需要在内容变化时触发重启的场景
+const checksumOnlyAnnotation = "simple-controller/checksum-only"
+
+// 注解：写在 Secret 上的 ConfigMap 内容哈希，可以复制到 Pod 模板上触发滚动更新
+const contentHashAnnotation = "simple-controller/content-hash"
+
+// checksumKey 是 checksum-only 模式下 Secret 中唯一的 key
+const checksumKey = "checksum"
+
+func checksumOnly(cm *corev1.ConfigMap) bool {
+	return cm.Annotations[checksumOnlyAnnotation] == "true"
+}
+
+// contentHash 按 key 排序后计算 ConfigMap 数据的 sha256
+func contentHash(cm *corev1.ConfigMap) string {
+	return hashData(cm.Data)
+}
+
+func hashData(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(data[k]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// secretMatchesHash 判断 Secret 的数据是否与它的 content-hash 注解一致，
+// 一致说明是控制器自己写入的结果，不需要再次调谐来源 ConfigMap
+func secretMatchesHash(s *corev1.Secret) bool {
+	hash := s.Annotations[contentHashAnnotation]
+	if hash == "" {
+		return false
+	}
+	data := decodedSecrets.Data(s)
+	if len(data) == 1 && data[checksumKey] == hash {
+		return true
+	}
+	return hashData(data) == hash
+}
+
+// secretDrifted 判断 Secret 是否在控制器之外被修改过：content-hash 注解与期望一致，
+// 说明来源 ConfigMap 自上次同步以来没有变化，此时数据仍与期望不同只能是手工修改
+func secretDrifted(s *corev1.Secret, hash string, want map[string]string) bool {
+	if s.Annotations[contentHashAnnotation] != hash {
+		return false
+	}
+	got := decodedSecrets.Data(s)
+	if len(got) == 0 && len(want) == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(got, want)
+}
+
+// secretData 返回写入 Secret 的数据：默认是 ConfigMap 中所有合法的 key，checksum-only 模式下只有内容哈希
+func secretData(cm *corev1.ConfigMap, hash string) map[string]string {
+	if checksumOnly(cm) {
+		return map[string]string{checksumKey: hash}
+	}
+	return withoutKeys(cm.Data, invalidSecretKeys(cm))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileChecksumOnly(t *testing.T) {
+	data := map[string]string{"password": "s3cret", "username": "admin"}
+	tests := []struct {
+		name         string
+		checksumOnly bool
+		wantKeys     []string
+	}{
+		{"full copy", false, []string{"password", "username"}},
+		{"checksum only", true, []string{checksumKey}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Data = data
+				if tt.checksumOnly {
+					cm.Annotations[checksumOnlyAnnotation] = "true"
+				}
+			})})
+			env.reconcile(t, "app")
+
+			secret := env.secret(t, testNamespace, "app-synced")
+			hash := hashData(data)
+			if got := secret.Annotations[contentHashAnnotation]; got != hash {
+				t.Fatalf("content hash = %q, want %q", got, hash)
+			}
+			var keys []string
+			for k := range secret.Data {
+				keys = append(keys, k)
+			}
+			if !equalSorted(keys, tt.wantKeys) {
+				t.Fatalf("Secret keys = %v, want %v", keys, tt.wantKeys)
+			}
+			if !tt.checksumOnly {
+				return
+			}
+			if got := string(secret.Data[checksumKey]); got != hash {
+				t.Fatalf("checksum = %q, want %q", got, hash)
+			}
+			for k, v := range data {
+				for _, stored := range secret.Data {
+					if strings.Contains(string(stored), v) {
+						t.Fatalf("source value of %s leaked into the Secret", k)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileChecksumOnlyTracksChanges(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+		cm.Annotations[checksumOnlyAnnotation] = "true"
+	})})
+	env.reconcile(t, "app")
+	before := string(env.secret(t, testNamespace, "app-synced").Data[checksumKey])
+
+	env.updateConfigMap(t, "app", func(cm *corev1.ConfigMap) { cm.Data["password"] = "rotated" })
+	env.reconcile(t, "app")
+	after := string(env.secret(t, testNamespace, "app-synced").Data[checksumKey])
+	if after == before {
+		t.Fatal("expected the checksum to change with the ConfigMap data")
+	}
+}
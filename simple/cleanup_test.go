@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileSecretDeleteGrace(t *testing.T) {
+	tests := []struct {
+		name       string
+		elapsed    bool
+		recreate   bool
+		wantSecret bool
+	}{
+		{name: "within the grace window", wantSecret: true},
+		{name: "after the grace window", elapsed: true, wantSecret: false},
+		{name: "ConfigMap recreated within the window", recreate: true, wantSecret: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app")}, withReconciler(func(r *ConfigMapReconciler) {
+				r.SecretDeleteGrace = time.Hour
+			}))
+			env.reconcile(t, "app")
+			env.deleteConfigMap(t, "app")
+
+			result := env.reconcile(t, "app")
+			if result.RequeueAfter <= 0 || result.RequeueAfter > time.Hour {
+				t.Fatalf("RequeueAfter = %v, want within the grace period", result.RequeueAfter)
+			}
+			secret := env.secret(t, testNamespace, "app-synced")
+			if secret.Annotations[deleteAfterAnnotation] == "" {
+				t.Fatalf("expected %s on the Secret", deleteAfterAnnotation)
+			}
+			if len(secret.OwnerReferences) != 0 {
+				t.Fatalf("owner references = %v, want none so GC keeps the Secret", secret.OwnerReferences)
+			}
+
+			if tt.elapsed {
+				secret.Annotations[deleteAfterAnnotation] = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+				if err := env.c.Update(context.Background(), secret); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.recreate {
+				if err := env.c.Create(context.Background(), newConfigMap("app")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			env.reconcile(t, "app")
+
+			if got := env.secretExists(t, testNamespace, "app-synced"); got != tt.wantSecret {
+				t.Fatalf("Secret exists = %v, want %v", got, tt.wantSecret)
+			}
+			if tt.recreate {
+				if _, scheduled := env.secret(t, testNamespace, "app-synced").Annotations[deleteAfterAnnotation]; scheduled {
+					t.Fatal("expected the scheduled deletion to be cancelled")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// 注解：Deployment 标签选择器，匹配的是目标 namespace 中使用该 Secret 的工作负载。
+// 设置后，只要有匹配的 Deployment 正在滚动更新或不健康，就推迟更新已有的 Secret，避免在不稳定时叠加配置变更
+const consumerSelectorAnnotation = "simple-controller/consumer-selector"
+
+// consumerGatePollInterval 是推迟同步后重新检查使用方状态的间隔
+const consumerGatePollInterval = 30 * time.Second
+
+// consumerSelector 解析 consumer-selector 注解，未设置时返回 nil
+func consumerSelector(cm *corev1.ConfigMap) (labels.Selector, error) {
+	raw, ok := cm.Annotations[consumerSelectorAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	sel, err := labels.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", consumerSelectorAnnotation, raw, err)
+	}
+	return sel, nil
+}
+
+// deploymentHealthy 判断 Deployment 是否已经完成滚动更新且所有副本可用
+func deploymentHealthy(d *appsv1.Deployment) bool {
+	replicas := ptr.Deref(d.Spec.Replicas, 1)
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.Replicas == replicas &&
+		d.Status.AvailableReplicas >= replicas
+}
+
+// unhealthyConsumers 检查内容将要变化的 Secret 的使用方，返回推迟同步的原因，没有需要推迟的返回空。
+// 新建 Secret 和内容没有变化的 Secret 不受影响。Deployment 不在缓存中，通过 APIReader 直接读取
+func (r *ConfigMapReconciler) unhealthyConsumers(ctx context.Context, source *corev1.ConfigMap, targets []string) (string, error) {
+	sel, err := consumerSelector(source)
+	if err != nil || sel == nil {
+		return "", err
+	}
+	name := secretName(source)
+	hash := contentHash(source)
+	for _, ns := range targets {
+		existing := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: ns, Name: name}, existing); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		if existing.Annotations[contentHashAnnotation] == hash {
+			continue
+		}
+		list := &appsv1.DeploymentList{}
+		if err := r.APIReader.List(ctx, list, client.InNamespace(ns), client.MatchingLabelsSelector{Selector: sel}); err != nil {
+			return "", err
+		}
+		for i := range list.Items {
+			if d := &list.Items[i]; !deploymentHealthy(d) {
+				return fmt.Sprintf("consumer Deployment %s/%s is rolling out or unhealthy (%d/%d replicas available, %d updated)",
+					d.Namespace, d.Name, d.Status.AvailableReplicas, ptr.Deref(d.Spec.Replicas, 1), d.Status.UpdatedReplicas), nil
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileDefersSyncForUnhealthyConsumers(t *testing.T) {
+	healthy := appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}
+	tests := []struct {
+		name      string
+		status    appsv1.DeploymentStatus
+		labels    map[string]string
+		wantDefer bool
+	}{
+		{name: "consumer healthy", status: healthy, labels: map[string]string{"app": "web"}},
+		{
+			name:      "consumer mid-rollout",
+			status:    appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2},
+			labels:    map[string]string{"app": "web"},
+			wantDefer: true,
+		},
+		{
+			name:      "consumer has not observed the new generation",
+			status:    appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			labels:    map[string]string{"app": "web"},
+			wantDefer: true,
+		},
+		{
+			name:   "unhealthy Deployment not selected",
+			status: appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 1},
+			labels: map[string]string{"app": "other"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deploy := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "web", Generation: 1, Labels: tt.labels},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](2)},
+				Status:     tt.status,
+			}
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[consumerSelectorAnnotation] = "app=web"
+			}), deploy})
+
+			// 新建 Secret 不受使用方状态影响
+			env.reconcile(t, "app")
+			if !env.secretExists(t, testNamespace, "app-synced") {
+				t.Fatal("expected the first sync to create the Secret")
+			}
+
+			env.updateConfigMap(t, "app", func(cm *corev1.ConfigMap) { cm.Data["password"] = "rotated" })
+			result := env.reconcile(t, "app")
+
+			got := string(env.secret(t, testNamespace, "app-synced").Data["password"])
+			if deferred := got == "s3cret"; deferred != tt.wantDefer {
+				t.Fatalf("password = %q, want deferred %v", got, tt.wantDefer)
+			}
+			if tt.wantDefer && result.RequeueAfter != consumerGatePollInterval {
+				t.Fatalf("RequeueAfter = %v, want %v", result.RequeueAfter, consumerGatePollInterval)
+			}
+		})
+	}
+}
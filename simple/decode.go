@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/base64"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// 注解：设置为 true 时 ConfigMap 的值已经是 base64 编码，解码后再写入 Secret，避免被重复编码
+const decodeBase64Annotation = "simple-controller/decode-base64"
+
+// withDecodedBase64 返回把每个值按 base64 解码后的 ConfigMap 副本，以及无法解码而被跳过的 key。
+// 解码结果可能不是合法的 UTF-8，Go 字符串可以原样保存任意字节，写入 Secret 时再转换回 []byte。
+// 未启用 decode-base64 时原样返回
+func withDecodedBase64(cm *corev1.ConfigMap) (*corev1.ConfigMap, []string) {
+	if cm.Annotations[decodeBase64Annotation] != "true" {
+		return cm, nil
+	}
+	decoded := cm.DeepCopy()
+	decoded.Data = make(map[string]string, len(cm.Data))
+	var failed []string
+	for k, v := range cm.Data {
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+		if err != nil {
+			failed = append(failed, k)
+			continue
+		}
+		decoded.Data[k] = string(b)
+	}
+	sort.Strings(failed)
+	return decoded, failed
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileDecodeBase64(t *testing.T) {
+	tests := []struct {
+		name      string
+		decode    string
+		data      map[string]string
+		wantData  map[string]string
+		wantEvent bool
+	}{
+		{
+			name:     "valid base64 decoded",
+			decode:   "true",
+			data:     map[string]string{"password": "czNjcmV0", "cert": " AAEC/w==\n"},
+			wantData: map[string]string{"password": "s3cret", "cert": "\x00\x01\x02\xff"},
+		},
+		{
+			name:      "invalid base64 skipped",
+			decode:    "true",
+			data:      map[string]string{"password": "czNjcmV0", "token": "not base64!"},
+			wantData:  map[string]string{"password": "s3cret"},
+			wantEvent: true,
+		},
+		{
+			name:     "copied verbatim when disabled",
+			decode:   "false",
+			data:     map[string]string{"password": "czNjcmV0"},
+			wantData: map[string]string{"password": "czNjcmV0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[decodeBase64Annotation] = tt.decode
+				cm.Data = tt.data
+			})})
+			env.reconcile(t, "app")
+
+			got := map[string]string{}
+			for k, v := range env.secret(t, testNamespace, "app-synced").Data {
+				got[k] = string(v)
+			}
+			if !reflect.DeepEqual(got, tt.wantData) {
+				t.Fatalf("Secret data = %q, want %q", got, tt.wantData)
+			}
+			if got := containsEvent(env.events(), "InvalidBase64", "token"); got != tt.wantEvent {
+				t.Fatalf("InvalidBase64 event recorded = %v, want %v", got, tt.wantEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// dryRunClient 只记录写操作而不真正调用 API Server，读操作照常进行。
+// 写操作总是返回成功，调谐不会因此重试；由于对象没有真正变化，同一个 ConfigMap 每次调谐都会记录相同的操作
+type dryRunClient struct {
+	client.Client
+
+	// redact 对日志中的 key 名称脱敏
+	redact func([]string) []string
+}
+
+func (c *dryRunClient) Create(ctx context.Context, obj client.Object, _ ...client.CreateOption) error {
+	c.logWrite(ctx, "Create", obj)
+	return nil
+}
+
+func (c *dryRunClient) Update(ctx context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	c.logWrite(ctx, "Update", obj)
+	return nil
+}
+
+func (c *dryRunClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, _ ...client.PatchOption) error {
+	c.logWrite(ctx, "Patch ("+string(patch.Type())+")", obj)
+	return nil
+}
+
+func (c *dryRunClient) Delete(ctx context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	c.logWrite(ctx, "Delete", obj)
+	return nil
+}
+
+func (c *dryRunClient) DeleteAllOf(ctx context.Context, obj client.Object, _ ...client.DeleteAllOfOption) error {
+	c.logWrite(ctx, "DeleteAllOf", obj)
+	return nil
+}
+
+// logWrite 记录一个被跳过的写操作；写 Secret 时附带与线上 Secret 相比变化的 key
+func (c *dryRunClient) logWrite(ctx context.Context, op string, obj client.Object) {
+	kv := []any{"operation", op, "namespace", obj.GetNamespace(), "name", obj.GetName()}
+	if gvk, err := c.GroupVersionKindFor(obj); err == nil {
+		kv = append(kv, "kind", gvk.Kind)
+	}
+	if secret, ok := obj.(*corev1.Secret); ok {
+		existing := &corev1.Secret{}
+		if err := c.Client.Get(ctx, client.ObjectKeyFromObject(secret), existing); err != nil {
+			existing = &corev1.Secret{}
+		}
+		want := map[string]string{}
+		if op != "Delete" {
+			for k, v := range secret.Data {
+				want[k] = string(v)
+			}
+		}
+		kv = append(kv, "changedKeys", c.redact(changedKeys(decodedSecrets.Data(existing), want)))
+	}
+	log.FromContext(ctx).Info("Dry run, skipping write", kv...)
+}
+
+// dryRunRecorder 把事件写入日志而不创建 Event 对象，dry-run 时不向集群写入任何东西
+type dryRunRecorder struct {
+	logger logr.Logger
+}
+
+func (r *dryRunRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	kv := []any{"type", eventtype, "reason", reason, "message", message}
+	if obj, ok := object.(client.Object); ok {
+		kv = append(kv, "namespace", obj.GetNamespace(), "name", obj.GetName())
+	}
+	r.logger.Info("Dry run, skipping event", kv...)
+}
+
+func (r *dryRunRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...any) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *dryRunRecorder) AnnotatedEventf(object runtime.Object, _ map[string]string, eventtype, reason, messageFmt string, args ...any) {
+	r.Eventf(object, eventtype, reason, messageFmt, args...)
+}
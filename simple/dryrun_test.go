@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDryRunDoesNotWrite(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		objs        []client.Object
+	}{
+		{
+			name:        "plain sync",
+			annotations: map[string]string{defaultSyncAnnotation: "true"},
+		},
+		{
+			name: "validation job treated as passed",
+			annotations: map[string]string{
+				defaultSyncAnnotation:   "true",
+				validationJobAnnotation: "validate",
+			},
+			objs: []client.Object{&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "validate"},
+				Spec:       batchv1.JobSpec{Suspend: ptr.To(true)},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "default",
+					Name:        "app",
+					UID:         "cm-uid",
+					Labels:      map[string]string{managedByLabel: managedByValue},
+					Annotations: tt.annotations,
+				},
+				Data: map[string]string{"password": "secret"},
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(tt.objs, cm)...).Build()
+			r := &ConfigMapReconciler{
+				Client:    &dryRunClient{Client: c, redact: func(keys []string) []string { return keys }},
+				Scheme:    scheme,
+				APIReader: c,
+				Recorder:  &dryRunRecorder{logger: logr.Discard()},
+				DryRun:    true,
+			}
+
+			before := testutil.ToFloat64(syncTotal.WithLabelValues(syncResultCreated))
+			result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "app"}})
+			if err != nil {
+				t.Fatalf("Reconcile: %v", err)
+			}
+			if result.RequeueAfter != 0 {
+				t.Errorf("RequeueAfter = %v, want 0", result.RequeueAfter)
+			}
+
+			secrets := &corev1.SecretList{}
+			if err := c.List(context.Background(), secrets); err != nil {
+				t.Fatal(err)
+			}
+			if len(secrets.Items) != 0 {
+				t.Errorf("got %d Secrets, want none", len(secrets.Items))
+			}
+			jobs := &batchv1.JobList{}
+			if err := c.List(context.Background(), jobs); err != nil {
+				t.Fatal(err)
+			}
+			if len(jobs.Items) != len(tt.objs) {
+				t.Errorf("got %d Jobs, want %d", len(jobs.Items), len(tt.objs))
+			}
+			got := &corev1.ConfigMap{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(cm), got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Finalizers) != 0 {
+				t.Errorf("finalizers = %v, want none", got.Finalizers)
+			}
+			if after := testutil.ToFloat64(syncTotal.WithLabelValues(syncResultCreated)); after != before {
+				t.Errorf("configmap_sync_total{result=created} changed from %v to %v", before, after)
+			}
+		})
+	}
+}
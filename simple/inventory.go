@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// reconcileResult 是某个 ConfigMap 最近一次调谐的结果
+type reconcileResult struct {
+	Time  time.Time
+	Error string
+}
+
+// reconcileResults 记录每个 ConfigMap 最近一次调谐的结果，供清单导出使用
+type reconcileResults struct {
+	mu      sync.Mutex
+	results map[types.NamespacedName]reconcileResult
+}
+
+func (r *reconcileResults) record(key types.NamespacedName, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.results == nil {
+		r.results = map[types.NamespacedName]reconcileResult{}
+	}
+	result := reconcileResult{Time: time.Now()}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	r.results[key] = result
+}
+
+func (r *reconcileResults) get(key types.NamespacedName) (reconcileResult, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result, ok := r.results[key]
+	return result, ok
+}
+
+// inventoryEntry 是清单中的一个受管对象
+type inventoryEntry struct {
+	Kind            string     `json:"kind"`
+	Namespace       string     `json:"namespace"`
+	Name            string     `json:"name"`
+	Source          string     `json:"source,omitempty"`
+	LastReconcile   *time.Time `json:"lastReconcile,omitempty"`
+	LastReconcileOK *bool      `json:"lastReconcileOK,omitempty"`
+	LastError       string     `json:"lastError,omitempty"`
+}
+
+// dumpInventory 以 JSON 导出控制器当前管理的 Secret 及其来源 ConfigMap 最近一次调谐的结果
+func (r *ConfigMapReconciler) dumpInventory(ctx context.Context) ([]byte, error) {
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, client.MatchingLabels{managedByLabel: managedByValue}); err != nil {
+		return nil, err
+	}
+
+	entries := make([]inventoryEntry, 0, len(secrets.Items))
+	for _, s := range secrets.Items {
+		entry := inventoryEntry{Kind: "Secret", Namespace: s.Namespace, Name: s.Name}
+		if source := s.Labels[sourceLabel]; source != "" {
+			// 旧版本写入的 Secret 没有 source-namespace 标签，来源与 Secret 在同一个 namespace
+			sourceNamespace := s.Labels[sourceNamespaceLabel]
+			if sourceNamespace == "" {
+				sourceNamespace = s.Namespace
+			}
+			key := types.NamespacedName{Namespace: sourceNamespace, Name: source}
+			entry.Source = key.String()
+			if result, ok := r.results.get(key); ok {
+				ok := result.Error == ""
+				entry.LastReconcile = &result.Time
+				entry.LastReconcileOK = &ok
+				entry.LastError = result.Error
+			}
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Namespace != entries[j].Namespace {
+			return entries[i].Namespace < entries[j].Namespace
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return json.Marshal(entries)
+}
+
+// dumpInventoryOnSignal 每次收到 SIGUSR1 时把受管对象清单写到日志，
+// 用于没有暴露端口的受限环境中在线排查：kill -USR1 <pid>
+func (r *ConfigMapReconciler) dumpInventoryOnSignal(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("inventory")
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-signals:
+			inventory, err := r.dumpInventory(ctx)
+			if err != nil {
+				logger.Error(err, "Failed to dump managed object inventory")
+				continue
+			}
+			logger.Info("Managed object inventory", "inventory", string(inventory))
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// 注解：控制器拒绝同步 ConfigMap 的原因，同步成功后会被移除
+const syncErrorAnnotation = "simple-controller/sync-error"
+
+// setSyncError 在 ConfigMap 上记录（msg 为空时清除）拒绝同步的原因，没有变化时不写入
+func (r *ConfigMapReconciler) setSyncError(ctx context.Context, cm *corev1.ConfigMap, msg string) error {
+	if cm.Annotations[syncErrorAnnotation] == msg {
+		return nil
+	}
+	if msg == "" {
+		delete(cm.Annotations, syncErrorAnnotation)
+	} else {
+		if cm.Annotations == nil {
+			cm.Annotations = map[string]string{}
+		}
+		cm.Annotations[syncErrorAnnotation] = msg
+	}
+	return r.Update(ctx, cm)
+}
+
+// invalidSecretKeys 返回不能作为 Secret key 的 ConfigMap key（按字母排序），
+// 直接写入会被 API Server 以难以定位的错误拒绝
+func invalidSecretKeys(cm *corev1.ConfigMap) []string {
+	var invalid []string
+	for k := range cm.Data {
+		if len(validation.IsConfigMapKey(k)) > 0 {
+			invalid = append(invalid, k)
+		}
+	}
+	sort.Strings(invalid)
+	return invalid
+}
+
+// withoutKeys 返回去掉指定 key 之后的数据副本
+func withoutKeys(data map[string]string, keys []string) map[string]string {
+	if len(keys) == 0 {
+		return data
+	}
+	out := make(map[string]string, len(data))
+	for k, v := range data {
+		out[k] = v
+	}
+	for _, k := range keys {
+		delete(out, k)
+	}
+	return out
+}
+
+// 注解：逗号分隔的 key 列表，只把这些 key 复制到 Secret；不存在的 key 会被跳过
+const syncKeysAnnotation = "simple-controller/sync-keys"
+
+// syncKeys 解析 sync-keys 注解，ok 为 false 表示没有设置，复制全部 key
+func syncKeys(cm *corev1.ConfigMap) (keys []string, ok bool, err error) {
+	v, ok := cm.Annotations[syncKeysAnnotation]
+	if !ok {
+		return nil, false, nil
+	}
+	for _, k := range strings.Split(v, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, true, fmt.Errorf("invalid %s: at least one key is required", syncKeysAnnotation)
+	}
+	return keys, true, nil
+}
+
+// withSyncKeys 返回只包含 sync-keys 所列 key 的 ConfigMap 副本，以及 ConfigMap 中不存在的 key。
+// 没有设置 sync-keys 时原样返回
+func withSyncKeys(cm *corev1.ConfigMap) (*corev1.ConfigMap, []string) {
+	keys, ok, err := syncKeys(cm)
+	if !ok || err != nil {
+		return cm, nil
+	}
+	selected := cm.DeepCopy()
+	selected.Data = make(map[string]string, len(keys))
+	var missing []string
+	for _, k := range keys {
+		v, found := cm.Data[k]
+		if !found {
+			missing = append(missing, k)
+			continue
+		}
+		selected.Data[k] = v
+	}
+	return selected, missing
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestInvalidSecretKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]string
+		want []string
+	}{
+		{"all valid", map[string]string{"a.b": "", "A_b-c": ""}, nil},
+		{"space and slash", map[string]string{"ok": "", "bad key": "", "a/b": ""}, []string{"a/b", "bad key"}},
+		{"dot names", map[string]string{".": "", "..": "", ".hidden": ""}, []string{".", ".."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := invalidSecretKeys(&corev1.ConfigMap{Data: tt.data})
+			if !equalSorted(got, tt.want) {
+				t.Fatalf("invalidSecretKeys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileInvalidSecretKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		failOnKeys bool
+		wantSecret bool
+		wantEvent  string
+	}{
+		{"invalid keys skipped by default", false, true, "Skipping keys [bad key]"},
+		{"invalid keys refused with -fail-on-invalid-keys", true, false, "Not syncing: keys [bad key] are not valid Secret keys"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Data = map[string]string{"password": "s3cret", "bad key": "x"}
+			})}, withReconciler(func(r *ConfigMapReconciler) { r.FailOnInvalidKeys = tt.failOnKeys }))
+			env.reconcile(t, "app")
+
+			if got := env.secretExists(t, testNamespace, "app-synced"); got != tt.wantSecret {
+				t.Fatalf("Secret exists = %v, want %v", got, tt.wantSecret)
+			}
+			if tt.wantSecret {
+				data := env.secret(t, testNamespace, "app-synced").Data
+				if _, ok := data["bad key"]; ok || string(data["password"]) != "s3cret" {
+					t.Fatalf("Secret data = %v, want only the valid key", data)
+				}
+			} else if msg := env.configMap(t, "app").Annotations[syncErrorAnnotation]; !strings.Contains(msg, "bad key") {
+				t.Fatalf("sync error = %q, want it to name the invalid key", msg)
+			}
+			if !containsEvent(env.events(), "InvalidKeys", tt.wantEvent) {
+				t.Fatalf("expected an InvalidKeys event containing %q", tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestReconcileMaxSecretKeys(t *testing.T) {
+	data := map[string]string{"a": "1", "b": "2", "c": "3"}
+	tests := []struct {
+		name         string
+		max          int
+		checksumOnly bool
+		wantSecret   bool
+	}{
+		{name: "unlimited", max: 0, wantSecret: true},
+		{name: "at the limit", max: 3, wantSecret: true},
+		{name: "over the limit", max: 2, wantSecret: false},
+		{name: "checksum-only ignores the limit", max: 2, checksumOnly: true, wantSecret: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Data = data
+				if tt.checksumOnly {
+					cm.Annotations[checksumOnlyAnnotation] = "true"
+				}
+			})}, withReconciler(func(r *ConfigMapReconciler) { r.MaxSecretKeys = tt.max }))
+			env.reconcile(t, "app")
+
+			if got := env.secretExists(t, testNamespace, "app-synced"); got != tt.wantSecret {
+				t.Fatalf("Secret exists = %v, want %v", got, tt.wantSecret)
+			}
+			msg := env.configMap(t, "app").Annotations[syncErrorAnnotation]
+			if tt.wantSecret {
+				if msg != "" {
+					t.Fatalf("unexpected sync error %q", msg)
+				}
+				return
+			}
+			if want := "ConfigMap has 3 keys, more than the allowed 2"; msg != want {
+				t.Fatalf("sync error = %q, want %q", msg, want)
+			}
+			if !containsEvent(env.events(), "TooManyKeys", "more than the allowed 2") {
+				t.Fatal("expected a TooManyKeys event")
+			}
+		})
+	}
+}
+
+func TestReconcileSyncKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		syncKeys string
+		wantKeys []string
+	}{
+		{name: "subset", syncKeys: "password, username", wantKeys: []string{"password", "username"}},
+		{name: "missing key skipped", syncKeys: "password,absent", wantKeys: []string{"password"}},
+		{name: "all listed keys missing", syncKeys: "absent", wantKeys: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[syncKeysAnnotation] = tt.syncKeys
+				cm.Data = map[string]string{"password": "s3cret", "username": "admin", "debug": "true"}
+			})})
+			env.reconcile(t, "app")
+
+			var got []string
+			for k := range env.secret(t, testNamespace, "app-synced").Data {
+				got = append(got, k)
+			}
+			if !equalSorted(got, tt.wantKeys) {
+				t.Fatalf("Secret keys = %v, want %v", got, tt.wantKeys)
+			}
+		})
+	}
+}
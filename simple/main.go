@@ -2,41 +2,144 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"maps"
+	"net"
+	"net/http"
 	"os"
 	"reflect"
 	"slices"
+	"strings"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
-// 注解：当 ConfigMap 有这个 annotation 时，会自动同步到 Secret
-const syncAnnotation = "simple-controller/sync-to-secret"
+// 注解：当 ConfigMap 有这个 annotation 时，会自动同步到 Secret，可以通过 -sync-annotation 修改
+const defaultSyncAnnotation = "simple-controller/sync-to-secret"
 
 const finalizerName = "simple-controller/finalizer"
 
+// 注解：控制同步出的 Secret 与 ConfigMap 的归属关系
+//   - controller（默认）：设置 controller OwnerReference，由 GC 级联删除
+//   - reference：设置非 controller 的 OwnerReference，同样由 GC 级联删除
+//   - none：不设置 OwnerReference，通过 Finalizer 在 ConfigMap 删除时清理
+const ownerModeAnnotation = "simple-controller/owner-mode"
+
+const (
+	ownerModeController = "controller"
+	ownerModeReference  = "reference"
+	ownerModeNone       = "none"
+)
+
+// 控制器写入的标签
+const (
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "simple-controller"
+	sourceLabel    = "app.kubernetes.io/source"
+	// 跨 namespace 的副本无法使用 OwnerReference，依靠这组标签找回来源并清理
+	sourceNamespaceLabel = "simple-controller/source-namespace"
+	// 写入对象的控制器版本，升级后可以找出仍由旧版本管理的对象
+	versionLabel = "app.kubernetes.io/version"
+)
+
+// version 是控制器的构建版本，构建时通过 -ldflags "-X main.version=v1.2.3" 注入
+var version = "dev"
+
+// 注解：ConfigMap 删除后 Secret 的计划删除时间（RFC3339），宽限期内 ConfigMap 重新出现则会被移除
+const deleteAfterAnnotation = "simple-controller/delete-after"
+
+// fieldManager 是 Server-Side Apply 使用的字段管理者名称
+const fieldManager = "simple-controller"
+
+// 注解：生成 Secret 的 ConfigMap resourceVersion，用于排查同步是否滞后
+const sourceResourceVersionAnnotation = "simple-controller/source-resource-version"
+
 // ConfigMapReconciler 监听 ConfigMap 变化
 type ConfigMapReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// SyncAnnotation 是触发同步的注解，为空时使用 defaultSyncAnnotation
+	SyncAnnotation string
+
+	// APIReader 直接读取 API Server，用于读取不在缓存中的对象（如校验 Job 模板）
+	APIReader client.Reader
+
+	// SecretDeleteGrace 是 ConfigMap 删除后保留 Secret 的时间，0 表示立即删除
+	SecretDeleteGrace time.Duration
+
+	// NoBlockOwnerDeletion 为 true 时 OwnerReference 的 blockOwnerDeletion 设为 false
+	NoBlockOwnerDeletion bool
+
+	// Recorder 用于在 ConfigMap 上记录事件
+	Recorder record.EventRecorder
+
+	// FailOnInvalidKeys 为 true 时 ConfigMap 含有不合法的 Secret key 就不同步，默认跳过这些 key
+	FailOnInvalidKeys bool
+
+	// ForceApply 为 true 时 Server-Side Apply 强制接管与其他字段管理者冲突的字段，默认遇到冲突跳过
+	ForceApply bool
+
+	// MaxSecretKeys 是同步出的 Secret 最多允许的 key 数量，0 表示不限制
+	MaxSecretKeys int
+
+	// MaxFanoutBytes 是跨 namespace 同步时所有副本数据大小之和的上限，0 表示不限制
+	MaxFanoutBytes int64
+
+	// ManageSince 非零时只管理在该时间之后创建的 ConfigMap，用于分批接入时限制影响范围
+	ManageSince time.Time
+
+	// FanoutConcurrency 是同时写入 Secret 的目标 namespace 数量上限，0 表示使用 defaultFanoutConcurrency
+	FanoutConcurrency int
+
+	// RedactKeys 是 key 名称的通配符（小写），匹配的 key 在日志和事件中显示为 ***
+	RedactKeys []string
+
+	// Provenance 可选，记录每次写入 Secret 的同步，用于合规审计
+	Provenance *recordStore
+
+	// Tombstones 可选，记录因 ConfigMap 删除而被删除的 Secret
+	Tombstones *recordStore
+
+	// Webhook 可选，把每次调谐的结果推送到外部地址
+	Webhook *eventWebhook
+
+	// DryRun 为 true 时 Client 不真正写入，同步计数不变，校验 Job 视为已通过
+	DryRun bool
+
+	// results 记录每个 ConfigMap 最近一次调谐的结果，SIGUSR1 导出清单时使用
+	results reconcileResults
 }
 
 func makeLabelSelector() labels.Selector {
-	sel, _ := labels.Parse("app.kubernetes.io/managed-by=simple-controller")
+	sel, _ := labels.Parse(managedByLabel + "=" + managedByValue)
 	return sel
 }
 
@@ -50,14 +153,87 @@ func removeFinalizer(list []string, v string) []string {
 	})
 }
 
+// isControllerManaged 判断对象是否由控制器同步生成
+func isControllerManaged(obj metav1.Object) bool {
+	_, hasSource := obj.GetLabels()[sourceLabel]
+	_, hasSourceNamespace := obj.GetLabels()[sourceNamespaceLabel]
+	return hasSource || hasSourceNamespace
+}
+
+// controlAnnotations 返回 ConfigMap 上所有 simple-controller/ 前缀的注解，
+// 这些注解的变化都可能改变同步结果
+func controlAnnotations(cm *corev1.ConfigMap) map[string]string {
+	out := map[string]string{}
+	for k, v := range cm.Annotations {
+		if strings.HasPrefix(k, "simple-controller/") {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// ownerMode 解析 owner-mode 注解，缺省为 controller
+func ownerMode(cm *corev1.ConfigMap) (string, error) {
+	mode, ok := cm.Annotations[ownerModeAnnotation]
+	if !ok || mode == "" {
+		return ownerModeController, nil
+	}
+	switch mode {
+	case ownerModeController, ownerModeReference, ownerModeNone:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid %s %q: must be one of %s, %s, %s",
+		ownerModeAnnotation, mode, ownerModeNone, ownerModeController, ownerModeReference)
+}
+
+// setOwner 按 owner-mode 设置 Secret 的 OwnerReference。
+// 先移除指向该 ConfigMap 的旧引用，以便在模式切换时原地更新。
+func (r *ConfigMapReconciler) setOwner(cm *corev1.ConfigMap, secret *corev1.Secret, mode string) error {
+	secret.OwnerReferences = slices.DeleteFunc(secret.OwnerReferences, func(ref metav1.OwnerReference) bool {
+		return ref.UID == cm.UID
+	})
+	var err error
+	switch mode {
+	case ownerModeController:
+		err = ctrl.SetControllerReference(cm, secret, r.Scheme)
+	case ownerModeReference:
+		err = controllerutil.SetOwnerReference(cm, secret, r.Scheme)
+	}
+	if err != nil {
+		return err
+	}
+
+	// blockOwnerDeletion 需要对 owner 有 finalizers 子资源的权限，受限环境下可以关闭
+	if r.NoBlockOwnerDeletion {
+		for i := range secret.OwnerReferences {
+			if secret.OwnerReferences[i].UID == cm.UID {
+				secret.OwnerReferences[i].BlockOwnerDeletion = ptr.To(false)
+			}
+		}
+	}
+	return nil
+}
+
+// syncAnnotation 返回触发同步的注解
+func (r *ConfigMapReconciler) syncAnnotation() string {
+	if r.SyncAnnotation != "" {
+		return r.SyncAnnotation
+	}
+	return defaultSyncAnnotation
+}
+
 func (r *ConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.ConfigMap{}, additionalSourcesIndex, indexAdditionalSources); err != nil {
+		return err
+	}
+
 	pred := predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
 			cm, ok := e.Object.(*corev1.ConfigMap)
 			if !ok {
 				return false
 			}
-			_, exists := cm.Annotations[syncAnnotation]
+			_, exists := cm.Annotations[r.syncAnnotation()]
 			return exists
 		},
 
@@ -68,16 +244,24 @@ func (r *ConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				return false
 			}
 
-			_, oldExists := oldCm.Annotations[syncAnnotation]
-			_, newExists := newCm.Annotations[syncAnnotation]
+			_, oldExists := oldCm.Annotations[r.syncAnnotation()]
+			_, newExists := newCm.Annotations[r.syncAnnotation()]
 
 			if oldExists != newExists {
 				return true
 			}
 
+			// 开始删除（owner-mode=none 时由 Finalizer 阻塞删除，需要处理清理）
+			if oldCm.DeletionTimestamp.IsZero() && !newCm.DeletionTimestamp.IsZero() {
+				return true
+			}
+
 			if newExists && !reflect.DeepEqual(oldCm.Data, newCm.Data) {
 				return true
 			}
+			if newExists && !reflect.DeepEqual(controlAnnotations(oldCm), controlAnnotations(newCm)) {
+				return true
+			}
 			return false
 		},
 
@@ -86,19 +270,40 @@ func (r *ConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			if !ok {
 				return false
 			}
-			_, exists := cm.Annotations[syncAnnotation]
+			_, exists := cm.Annotations[r.syncAnnotation()]
 			return exists
 		},
 	}
 
+	// Secret 通过标签映射回源 ConfigMap，覆盖没有 OwnerReference 的副本；
+	// Namespace 的创建和标签变化可能改变 target-namespace-selector 的匹配结果
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.ConfigMap{}, builder.WithPredicates(pred)).
-		Owns(&corev1.Secret{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(secretToConfigMap), builder.WithPredicates(secretChangePredicate)).
+		Owns(&batchv1.Job{}).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.namespaceToConfigMaps)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.sourceToConfigMaps)).
 		Complete(r)
 }
 
 // Reconcile 是核心调谐逻辑
 func (r *ConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	start := time.Now()
+	reconcileRate.Observe(start)
+	defer observeReconcileDuration(start)
+
+	result, err := r.reconcile(ctx, req)
+	if err != nil {
+		r.countSync(syncResultError)
+	}
+	r.results.record(req.NamespacedName, err)
+	if r.Webhook != nil {
+		r.Webhook.send(req.NamespacedName, err)
+	}
+	return result, err
+}
+
+func (r *ConfigMapReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
 	// ========== 调试技巧 ==========
@@ -118,95 +323,649 @@ func (r *ConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		if errors.IsNotFound(err) {
 			// ConfigMap 被删除，尝试删除对应的 Secret
 			logger.Info("ConfigMap deleted, cleaning up Secret", "name", req.Name)
-			secret := &corev1.Secret{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      req.Name + "-synced",
-					Namespace: req.Namespace,
-				},
-			}
-			if err := r.Delete(ctx, secret); err != nil && !errors.IsNotFound(err) {
-				return ctrl.Result{}, err
-			}
-			return ctrl.Result{}, nil
+			requeueAfter, err := r.cleanupSecrets(ctx, req.Namespace, req.Name)
+			return ctrl.Result{RequeueAfter: requeueAfter}, err
 		}
 		return ctrl.Result{}, err
 	}
 
+	// ConfigMap 正在删除：由 Finalizer 负责清理 Secret（或开始删除宽限期）
+	if !configMap.DeletionTimestamp.IsZero() {
+		if !containsFinalizer(configMap.Finalizers, finalizerName) {
+			return ctrl.Result{}, nil
+		}
+		logger.Info("ConfigMap is being deleted, cleaning up Secret", "name", configMap.Name)
+		requeueAfter, err := r.cleanupSecrets(ctx, configMap.Namespace, configMap.Name)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		configMap.Finalizers = removeFinalizer(configMap.Finalizers, finalizerName)
+		// 宽限期结束后以 ConfigMap 不存在的状态再次调谐，删除 Secret
+		return ctrl.Result{RequeueAfter: requeueAfter}, r.Update(ctx, configMap)
+	}
+
+	// 接入时间之前创建的 ConfigMap 不归本控制器管理。放在删除处理之后，已经加上的 Finalizer 仍然会被摘掉
+	if !r.ManageSince.IsZero() && configMap.CreationTimestamp.Time.Before(r.ManageSince) {
+		logger.V(1).Info("ConfigMap was created before -manage-since, skipping", "configmap", configMap.Name, "created", configMap.CreationTimestamp.Time)
+		return ctrl.Result{}, nil
+	}
+
 	// 2. 检查是否有同步 annotation
-	if _, exists := configMap.Annotations[syncAnnotation]; !exists {
+	syncValue, exists := configMap.Annotations[r.syncAnnotation()]
+	if !exists {
 		logger.V(1).Info("ConfigMap does not have sync annotation, skipping", "name", configMap.Name)
+		// 取消同步时保留已有 Secret，但不能再阻塞 ConfigMap 的删除
+		if containsFinalizer(configMap.Finalizers, finalizerName) {
+			configMap.Finalizers = removeFinalizer(configMap.Finalizers, finalizerName)
+			return ctrl.Result{}, r.Update(ctx, configMap)
+		}
 		return ctrl.Result{}, nil
 	}
+	// 空值目前等同于同步全部 key，但以后注解可能承载策略值，建议显式写 true
+	if syncValue == "" {
+		logger.V(1).Info("Sync annotation is empty, treating it as sync-all; set it to \"true\" instead", "configmap", configMap.Name)
+	}
+
+	// 控制器自己写出的 ConfigMap（如镜像副本）即使带有同步注解也不处理，避免形成同步循环。
+	// 缓存只包含带 managed-by 标签的对象，所有来源 ConfigMap 都有这个标签，所以按来源标签判断
+	if isControllerManaged(configMap) {
+		logger.Info("ConfigMap is managed by the controller, skipping", "configmap", configMap.Name, "source", configMap.Labels[sourceLabel])
+		return ctrl.Result{}, nil
+	}
+
+	// 注解写错或互相矛盾时重试也无济于事，记录原因后等待用户修改
+	unknown, err := validateAnnotations(configMap)
+	if err != nil {
+		logger.Error(err, "Invalid annotations, skipping", "configmap", configMap.Name)
+		r.Recorder.Eventf(configMap, corev1.EventTypeWarning, "InvalidAnnotations", "Not syncing: %v", err)
+		return ctrl.Result{}, r.setSyncError(ctx, configMap, err.Error())
+	}
+	if len(unknown) > 0 {
+		r.Recorder.Eventf(configMap, corev1.EventTypeWarning, "UnknownAnnotations", "Ignoring unknown annotations %v", unknown)
+	}
+	mode, _ := ownerMode(configMap)
+
+	// owner-mode=none 依赖 Finalizer 清理；配置了删除宽限期时也需要 Finalizer，
+	// 在 GC 级联删除之前摘掉 OwnerReference。其他情况交给 GC，去掉可能残留的 Finalizer
+	needsFinalizer := mode == ownerModeNone || r.SecretDeleteGrace > 0
+	hasFinalizer := containsFinalizer(configMap.Finalizers, finalizerName)
+	if needsFinalizer && !hasFinalizer {
+		configMap.Finalizers = append(configMap.Finalizers, finalizerName)
+		if err := r.Update(ctx, configMap); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if !needsFinalizer && hasFinalizer {
+		configMap.Finalizers = removeFinalizer(configMap.Finalizers, finalizerName)
+		if err := r.Update(ctx, configMap); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// 合并附加来源的数据，后续只用 source 计算 Secret 内容，写回注解等操作仍然针对 configMap
+	source, missing, err := r.mergedSource(ctx, configMap)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if missing != "" {
+		msg := fmt.Sprintf("additional source ConfigMap %s not found (it must be labeled %s=%s)", missing, managedByLabel, managedByValue)
+		r.Recorder.Eventf(configMap, corev1.EventTypeWarning, "SourceNotFound", "Not syncing: %s", msg)
+		logger.Info("Additional source ConfigMap not found, skipping", "configmap", configMap.Name, "source", missing)
+		return ctrl.Result{}, r.setSyncError(ctx, configMap, msg)
+	}
+	// 设置了 sync-keys 时只同步列出的 key，其余 key 不会出现在 Secret 中，也不参与内容 hash
+	source, missingKeys := withSyncKeys(source)
+	if len(missingKeys) > 0 {
+		logger.V(1).Info("Keys listed in sync-keys do not exist, skipping them", "configmap", configMap.Name, "keys", r.redactKeys(missingKeys))
+	}
+	// decode-base64 模式下解码失败的 key 单独跳过，不影响其他 key 的同步
+	source, undecodable := withDecodedBase64(source)
+	if len(undecodable) > 0 {
+		undecodable = r.redactKeys(undecodable)
+		r.Recorder.Eventf(configMap, corev1.EventTypeWarning, "InvalidBase64", "Skipping keys %v, their values are not valid base64", undecodable)
+		logger.Info("Skipping keys with invalid base64 values", "configmap", configMap.Name, "keys", undecodable)
+	}
+
+	// 不合法的 key 会让整个 Secret 写入失败，提前检查并通过事件告知用户
+	if invalid := invalidSecretKeys(source); len(invalid) > 0 && !checksumOnly(configMap) {
+		invalid = r.redactKeys(invalid)
+		if r.FailOnInvalidKeys {
+			msg := fmt.Sprintf("keys %v are not valid Secret keys", invalid)
+			r.Recorder.Eventf(configMap, corev1.EventTypeWarning, "InvalidKeys", "Not syncing: %s", msg)
+			logger.Info("ConfigMap has invalid Secret keys, skipping", "configmap", configMap.Name, "keys", invalid)
+			return ctrl.Result{}, r.setSyncError(ctx, configMap, msg)
+		}
+		r.Recorder.Eventf(configMap, corev1.EventTypeWarning, "InvalidKeys", "Skipping keys %v, they are not valid Secret keys", invalid)
+		logger.Info("Skipping invalid Secret keys", "configmap", configMap.Name, "keys", invalid)
+	}
+
+	// key 过多的 Secret 难以维护，也可能超过对象大小限制，拒绝同步而不是创建巨大的 Secret
+	if r.MaxSecretKeys > 0 && !checksumOnly(configMap) && len(source.Data) > r.MaxSecretKeys {
+		msg := fmt.Sprintf("ConfigMap has %d keys, more than the allowed %d", len(source.Data), r.MaxSecretKeys)
+		r.Recorder.Eventf(configMap, corev1.EventTypeWarning, "TooManyKeys", "Not syncing: %s", msg)
+		logger.Info("ConfigMap has too many keys, skipping", "configmap", configMap.Name, "keys", len(source.Data), "max", r.MaxSecretKeys)
+		return ctrl.Result{}, r.setSyncError(ctx, configMap, msg)
+	}
+
+	// 配置了校验 Job 时，等当前内容的 Job 成功后才写入 Secret
+	if configMap.Annotations[validationJobAnnotation] != "" {
+		result, msg, err := r.runValidationJob(ctx, source)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		switch result {
+		case validationRunning:
+			logger.Info("Waiting for validation Job", "configmap", configMap.Name)
+			return ctrl.Result{RequeueAfter: validationPollInterval}, nil
+		case validationFailed:
+			if configMap.Annotations[syncErrorAnnotation] != msg {
+				r.Recorder.Eventf(configMap, corev1.EventTypeWarning, "ValidationFailed", "Not syncing: %s", msg)
+			}
+			logger.Info("Validation failed, skipping", "configmap", configMap.Name, "reason", msg)
+			return ctrl.Result{}, r.setSyncError(ctx, configMap, msg)
+		}
+	}
+
+	// 3. 计算目标 namespace（默认只有 ConfigMap 所在的 namespace）
+	targets, err := r.targetNamespaces(ctx, configMap)
+	if err != nil {
+		logger.Error(err, "Failed to resolve target namespaces")
+		return ctrl.Result{}, err
+	}
+
+	// Secret 类型不可修改，已有 Secret 的类型与期望不同且不允许重建时，拒绝同步而不是反复失败
+	conflict, err := r.secretTypeConflicts(ctx, configMap, targets)
+	if err != nil {
+		logger.Error(err, "Failed to check existing Secret types")
+		return ctrl.Result{}, err
+	}
+	if conflict != "" {
+		if configMap.Annotations[syncErrorAnnotation] != conflict {
+			r.Recorder.Eventf(configMap, corev1.EventTypeWarning, "SecretTypeImmutable", "Not syncing: %s", conflict)
+		}
+		logger.Info("Secret type change requires recreation, skipping", "configmap", configMap.Name, "reason", conflict)
+		return ctrl.Result{}, r.setSyncError(ctx, configMap, conflict)
+	}
+
+	// 大的 Secret 同步到大量 namespace 会成倍占用 etcd 的存储，超出预算时拒绝整个 fan-out
+	if msg := r.fanoutBudgetExceeded(source, targets); msg != "" {
+		if configMap.Annotations[syncErrorAnnotation] != msg {
+			r.Recorder.Eventf(configMap, corev1.EventTypeWarning, "FanoutTooLarge", "Not syncing: %s", msg)
+		}
+		logger.Info("Fan-out exceeds the size budget, skipping", "configmap", configMap.Name, "reason", msg)
+		return ctrl.Result{}, r.setSyncError(ctx, configMap, msg)
+	}
+
+	// 使用方正在滚动更新或不健康时推迟更新，稍后重新检查
+	if reason, err := r.unhealthyConsumers(ctx, source, targets); err != nil {
+		logger.Error(err, "Failed to check consumer Deployments")
+		return ctrl.Result{}, err
+	} else if reason != "" {
+		r.Recorder.Eventf(configMap, corev1.EventTypeNormal, "SyncDeferred", "Deferring Secret update: %s", reason)
+		logger.Info("Deferring Secret update until consumers are healthy", "configmap", configMap.Name, "reason", reason)
+		return ctrl.Result{RequeueAfter: consumerGatePollInterval}, nil
+	}
+
+	logger.Info("Syncing ConfigMap to Secret", "configmap", configMap.Name, "ownerMode", mode, "targets", targets)
+
+	// 4. 在每个目标 namespace 中创建或更新 Secret，部分失败时其他 namespace 照常写入，整体重试
+	if err := r.syncTargets(ctx, source, targets, mode); err != nil {
+		logger.Error(err, "Failed to sync Secret to some target namespaces")
+		return ctrl.Result{}, err
+	}
+
+	// 5. 删除已不在目标范围内的 Secret
+	if err := r.pruneSecrets(ctx, configMap, targets); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// 同步成功，清除之前记录的拒绝原因
+	return ctrl.Result{}, r.setSyncError(ctx, configMap, "")
+}
+
+// 注解：设置为 true 时 Secret 名称追加来源 namespace/name 的短 hash，避免不同来源的 Secret 重名
+const nameHashAnnotation = "simple-controller/name-hash"
+
+// 注解：自定义同步出的 Secret 名称，必须是合法的 DNS-1123 subdomain
+const secretNameAnnotation = "simple-controller/secret-name"
+
+// secretName 返回 ConfigMap 对应的 Secret 名称。清理时按标签查找 Secret，不依赖名称
+func secretName(cm *corev1.ConfigMap) string {
+	if name := cm.Annotations[secretNameAnnotation]; name != "" {
+		return name
+	}
+	if annotationEnabled(cm, nameHashAnnotation) {
+		return hashedSecretName(cm.Namespace, cm.Name)
+	}
+	return cm.Name + "-synced"
+}
 
-	logger.Info("Syncing ConfigMap to Secret", "configmap", configMap.Name)
+// hashedSecretName 返回带来源 hash 的 Secret 名称，超长时截断 ConfigMap 名称以满足 253 个字符的限制
+func hashedSecretName(namespace, name string) string {
+	sum := sha256.Sum256([]byte(namespace + "/" + name))
+	suffix := "-synced-" + hex.EncodeToString(sum[:])[:8]
+	if maxLen := validation.DNS1123SubdomainMaxLength - len(suffix); len(name) > maxLen {
+		name = strings.TrimRight(name[:maxLen], ".-")
+	}
+	return name + suffix
+}
+
+// syncSecret 在指定 namespace 中创建或更新 ConfigMap 对应的 Secret
+func (r *ConfigMapReconciler) syncSecret(ctx context.Context, configMap *corev1.ConfigMap, namespace, mode string) error {
+	logger := log.FromContext(ctx)
 
-	// 3. 构建对应的 Secret
-	secretName := configMap.Name + "-synced"
+	name := secretName(configMap)
+	hash := contentHash(configMap)
+	desiredType, _ := secretType(configMap)
+	want := secretData(configMap, hash)
+	data := map[string][]byte{}
+	for k, v := range want {
+		data[k] = []byte(v)
+	}
+	// Server-Side Apply 需要完整的 TypeMeta；只声明控制器管理的字段，其他管理者写入的字段保持不变，
+	// 已从 ConfigMap 删除的 key 也会因为不再被声明而被移除
 	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
-			Namespace: configMap.Namespace,
+			Name:      name,
+			Namespace: namespace,
 			Labels: map[string]string{
-				"app.kubernetes.io/managed-by": "simple-controller",
-				"app.kubernetes.io/source":     configMap.Name,
+				managedByLabel:       managedByValue,
+				sourceLabel:          configMap.Name,
+				sourceNamespaceLabel: configMap.Namespace,
+				versionLabel:         version,
+			},
+			Annotations: map[string]string{
+				contentHashAnnotation:           hash,
+				sourceResourceVersionAnnotation: configMap.ResourceVersion,
 			},
 		},
-		StringData: configMap.Data, // 将 ConfigMap 数据复制到 Secret
+		Type: desiredType,
+		Data: data, // 将 ConfigMap 数据复制到 Secret
 	}
 
-	// 设置 OwnerReference，实现级联删除
-	if err := ctrl.SetControllerReference(configMap, secret, r.Scheme); err != nil {
-		return ctrl.Result{}, err
+	// 复制来源的标签和注解，控制器管理的标签和注解优先，避免影响清理和变更检测
+	propagatedLabels, propagatedAnnotations := r.propagatedMetadata(configMap)
+	mergeMissing(secret.Labels, propagatedLabels)
+	mergeMissing(secret.Annotations, propagatedAnnotations)
+
+	// OwnerReference 不能跨 namespace，其他 namespace 中的副本依赖标签清理
+	if namespace != configMap.Namespace {
+		mode = ownerModeNone
+		if configMap.Annotations[createNamespaceAnnotation] == "true" {
+			if err := r.ensureNamespace(ctx, namespace); err != nil {
+				logger.Error(err, "Failed to create target namespace", "namespace", namespace)
+				return err
+			}
+		}
+	}
+
+	// 按 owner-mode 设置 OwnerReference，controller/reference 模式下实现级联删除
+	if err := r.setOwner(configMap, secret, mode); err != nil {
+		return err
 	}
 
-	// 4. 创建或更新 Secret
 	existingSecret := &corev1.Secret{}
-	err := r.Get(ctx, types.NamespacedName{Name: secretName, Namespace: configMap.Namespace}, existingSecret)
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, existingSecret)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	// ConfigMap 在删除宽限期内重新出现，取消计划中的删除。
+	// 该注解由清理逻辑通过 Update 写入，不属于 Apply 声明的字段，需要单独移除
+	if _, scheduled := existingSecret.Annotations[deleteAfterAnnotation]; err == nil && scheduled {
+		patch := client.MergeFrom(existingSecret.DeepCopy())
+		delete(existingSecret.Annotations, deleteAfterAnnotation)
+		if err := r.Patch(ctx, existingSecret, patch); err != nil {
+			logger.Error(err, "Failed to cancel scheduled Secret deletion")
+			return err
+		}
+	}
 
-	if errors.IsNotFound(err) {
-		// Secret 不存在，创建
-		logger.Info("Creating Secret", "name", secretName)
-		if err := r.Create(ctx, secret); err != nil {
-			logger.Error(err, "Failed to create Secret")
-			return ctrl.Result{}, err
+	// Secret 的类型不可修改，类型变化时（已通过 allow-recreate 允许）先删除旧的 Secret，再由下面的 Apply 重新创建
+	if err == nil && existingSecret.Type != desiredType {
+		logger.Info("Secret type changed, recreating Secret", "name", name, "namespace", namespace, "from", existingSecret.Type, "to", desiredType)
+		if err := r.Delete(ctx, existingSecret, client.Preconditions{UID: &existingSecret.UID}); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete Secret for type change")
+			return err
 		}
-		logger.Info("✅ Secret created successfully", "name", secretName)
-	} else if err == nil {
-		// Secret 存在，更新
-		existingSecret.StringData = configMap.Data
-		existingSecret.Labels = secret.Labels
-		logger.Info("Updating Secret", "name", secretName)
-		if err := r.Update(ctx, existingSecret); err != nil {
-			logger.Error(err, "Failed to update Secret")
-			return ctrl.Result{}, err
+		r.Recorder.Eventf(configMap, corev1.EventTypeNormal, "SecretRecreated", "Recreating Secret %s/%s to change its type from %s to %s", namespace, name, existingSecret.Type, desiredType)
+		existingSecret = &corev1.Secret{}
+	}
+
+	// 已经是期望的状态时不再发起 Apply，大量 ConfigMap 重新调谐时减少写请求
+	if err == nil && secretUpToDate(existingSecret, secret) {
+		logger.V(1).Info("Secret is up to date", "name", name, "namespace", namespace)
+		observeSecretSize(data)
+		return nil
+	}
+
+	// 手工修改（如 kubectl edit）会让修改者持有被改动的字段，不强制接管的话 Apply 会因冲突而无法恢复，
+	// 所以恢复漂移时总是强制接管，控制器是这些 key 的唯一来源
+	changed := r.redactKeys(changedKeys(decodedSecrets.Data(existingSecret), want))
+	drifted := err == nil && secretDrifted(existingSecret, hash, want)
+	if drifted {
+		logger.Info("Secret was modified outside the controller, reverting", "name", name, "namespace", namespace, "changedKeys", changed)
+		r.Recorder.Eventf(configMap, corev1.EventTypeNormal, "DriftReverted", "Reverted manual changes to Secret %s/%s", namespace, name)
+	}
+
+	opts := []client.PatchOption{client.FieldOwner(fieldManager)}
+	if r.ForceApply || drifted {
+		opts = append(opts, client.ForceOwnership)
+	}
+	logger.Info("Applying Secret", "name", name, "namespace", namespace, "changedKeys", changed)
+	if err := r.Patch(ctx, secret, client.Apply, opts...); err != nil {
+		if errors.IsConflict(err) && !r.ForceApply && !drifted {
+			// 字段被其他管理者（如另一个 operator）持有，不强行覆盖；错误信息中包含冲突的管理者和字段
+			logger.Info("Secret fields are owned by another field manager, skipping", "name", name, "namespace", namespace, "conflict", err.Error())
+			r.Recorder.Eventf(configMap, corev1.EventTypeWarning, "ApplyConflict", "Not syncing Secret %s/%s: %v", namespace, name, err)
+			return nil
 		}
-		logger.Info("✅ Secret updated successfully", "name", secretName)
-	} else {
-		return ctrl.Result{}, err
+		logger.Error(err, "Failed to apply Secret")
+		return err
+	}
+	if !r.DryRun {
+		observeSecretSize(data)
 	}
+	result := syncResultUpdated
+	if existingSecret.UID == "" {
+		result = syncResultCreated
+	}
+	r.countSync(result)
+	r.recordProvenance(ctx, configMap, secret, result)
+	logger.Info("✅ Secret applied successfully", "name", name, "namespace", namespace)
+	return nil
+}
 
-	return ctrl.Result{}, nil
+// secretUpToDate 判断线上 Secret 是否已经包含 desired 声明的全部内容，数据比较使用解码缓存
+func secretUpToDate(existing, desired *corev1.Secret) bool {
+	if existing.Type != desired.Type {
+		return false
+	}
+	want := make(map[string]string, len(desired.Data))
+	for k, v := range desired.Data {
+		want[k] = string(v)
+	}
+	if !maps.Equal(decodedSecrets.Data(existing), want) {
+		return false
+	}
+	for k, v := range desired.Labels {
+		if existing.Labels[k] != v {
+			return false
+		}
+	}
+	for k, v := range desired.Annotations {
+		if existing.Annotations[k] != v {
+			return false
+		}
+	}
+	// 指向来源 ConfigMap 的 OwnerReference 必须与期望完全一致，owner-mode 切换为 none 时也能发现残留
+	var sourceRefs []metav1.OwnerReference
+	for _, ref := range existing.OwnerReferences {
+		if ref.Kind == "ConfigMap" && ref.Name == desired.Labels[sourceLabel] {
+			sourceRefs = append(sourceRefs, ref)
+		}
+	}
+	if len(sourceRefs) == 0 && len(desired.OwnerReferences) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(sourceRefs, desired.OwnerReferences)
+}
+
+// syncedSecrets 按标签列出由指定 ConfigMap 同步出的所有 Secret（包括其他 namespace 中的副本）
+func (r *ConfigMapReconciler) syncedSecrets(ctx context.Context, namespace, name string) ([]corev1.Secret, error) {
+	list := &corev1.SecretList{}
+	if err := r.List(ctx, list, client.MatchingLabels{
+		managedByLabel:       managedByValue,
+		sourceLabel:          name,
+		sourceNamespaceLabel: namespace,
+	}); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// cleanupSecrets 删除由指定 ConfigMap 同步出的 Secret。
+// 配置了 SecretDeleteGrace 时，先给 Secret 打上 delete-after 注解并摘掉 OwnerReference，
+// 宽限期过后才真正删除；返回值是距离下一次需要检查的时间。
+func (r *ConfigMapReconciler) cleanupSecrets(ctx context.Context, namespace, name string) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+
+	secrets, err := r.syncedSecrets(ctx, namespace, name)
+	if err != nil {
+		return 0, err
+	}
+	source := types.NamespacedName{Namespace: namespace, Name: name}.String()
+
+	if r.SecretDeleteGrace <= 0 {
+		// 旧版本创建的 Secret 没有 source-namespace 标签，按名称兜底删除
+		secrets = append(secrets, corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name + "-synced",
+				Namespace: namespace,
+			},
+		})
+		for i := range secrets {
+			if err := r.deleteSyncedSecret(ctx, &secrets[i], source); err != nil {
+				return 0, err
+			}
+		}
+		return 0, nil
+	}
+
+	now := time.Now()
+	var requeueAfter time.Duration
+	for i := range secrets {
+		secret := &secrets[i]
+		deleteAfter, err := time.Parse(time.RFC3339, secret.Annotations[deleteAfterAnnotation])
+		if err != nil {
+			// 第一次处理：记录删除时间，并摘掉指向 ConfigMap 的 OwnerReference，避免被 GC 立即删除
+			deleteAfter = now.Add(r.SecretDeleteGrace)
+			if secret.Annotations == nil {
+				secret.Annotations = map[string]string{}
+			}
+			secret.Annotations[deleteAfterAnnotation] = deleteAfter.UTC().Format(time.RFC3339)
+			secret.OwnerReferences = slices.DeleteFunc(secret.OwnerReferences, func(ref metav1.OwnerReference) bool {
+				return ref.Kind == "ConfigMap" && ref.Name == name
+			})
+			if err := r.Update(ctx, secret); err != nil {
+				return 0, err
+			}
+			logger.Info("Secret scheduled for deletion", "name", secret.Name, "namespace", secret.Namespace, "deleteAfter", deleteAfter)
+		} else if !now.Before(deleteAfter) {
+			logger.Info("Delete grace period elapsed, deleting Secret", "name", secret.Name, "namespace", secret.Namespace)
+			if err := r.deleteSyncedSecret(ctx, secret, source); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if remaining := deleteAfter.Sub(now); requeueAfter == 0 || remaining < requeueAfter {
+			requeueAfter = remaining
+		}
+	}
+	return requeueAfter, nil
+}
+
+// pruneSecrets 删除不在目标 namespace 列表中的 Secret 副本，以及切换 name-hash 后名称已经改变的旧 Secret
+func (r *ConfigMapReconciler) pruneSecrets(ctx context.Context, configMap *corev1.ConfigMap, targets []string) error {
+	logger := log.FromContext(ctx)
+
+	secrets, err := r.syncedSecrets(ctx, configMap.Namespace, configMap.Name)
+	if err != nil {
+		return err
+	}
+	name := secretName(configMap)
+	for i := range secrets {
+		secret := &secrets[i]
+		if slices.Contains(targets, secret.Namespace) && secret.Name == name {
+			continue
+		}
+		logger.Info("Deleting Secret outside target namespaces or with a stale name", "name", secret.Name, "namespace", secret.Namespace)
+		if err := r.Delete(ctx, secret); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		r.countSync(syncResultDeleted)
+	}
+	return nil
+}
+
+// defaultLeaderElectionID 是 Leader Election 使用的 Lease 名称
+const defaultLeaderElectionID = "simple-controller-leader"
+
+// applyLeaderElection 按参数设置 Leader Election。默认关闭，便于本地开发；多副本部署时必须开启，
+// 否则多个副本会同时写入同一批 Secret。Lease 创建在控制器所在的 namespace，集群外运行时为 default
+func applyLeaderElection(options *ctrl.Options, enabled bool, id string) {
+	options.LeaderElection = enabled
+	if !enabled {
+		return
+	}
+	options.LeaderElectionID = id
+	options.LeaderElectionNamespace = controllerNamespace()
+}
+
+// checkBindAddress 在 Manager 启动前先尝试监听一次地址，
+// 端口被占用时返回带端口号和处理建议的错误，而不是让 Manager 启动时报出难懂的错误。
+// "0" 表示禁用该服务，无需检查；":0" 会由系统分配空闲端口（测试时使用）。
+func checkBindAddress(flagName, addr string) error {
+	if addr == "0" {
+		return nil
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		_, port, splitErr := net.SplitHostPort(addr)
+		if splitErr != nil {
+			port = addr
+		}
+		return fmt.Errorf("-%s=%s: port %s is not available (%w); stop the process holding it, "+
+			"or pass a different port (-%s=:0 picks a free port, -%s=0 disables it)",
+			flagName, addr, port, err, flagName, flagName)
+	}
+	return ln.Close()
+}
+
+// cacheSyncedCheck 返回就绪检查：Manager 的缓存同步完成后才通过，避免在缓存为空时把 Pod 标记为就绪
+func cacheSyncedCheck(mgr ctrl.Manager) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), time.Second)
+		defer cancel()
+		if !mgr.GetCache().WaitForCacheSync(ctx) {
+			return fmt.Errorf("informer caches have not synced yet")
+		}
+		return nil
+	}
 }
 
 func main() {
 	var metricsAddr string
+	var healthProbeAddr string
 	var namespace string
+	var secretDeleteGrace time.Duration
+	var noBlockOwnerDeletion bool
+	var failOnInvalidKeys bool
+	var maxSecretKeys int
+	var forceApply bool
+	var tombstoneConfigMap, tombstoneNamespace string
+	var tombstoneMaxEntries int
+	var provenanceConfigMap, provenanceNamespace string
+	var provenanceMaxEntries int
+	var manageSince string
+	var eventWebhookURL string
+	var eventWebhookBuffer int
+	var syncAnnotation string
+	var redactKeys string
+	var dryRun bool
+	var fanoutConcurrency int
+	var maxFanoutBytes int64
+	var enableLeaderElection bool
+	var leaderElectionID string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&healthProbeAddr, "health-probe-addr", ":8081", "The address the /healthz and /readyz endpoints bind to (0 = disabled).")
 	flag.StringVar(&namespace, "namespace", "", "Namespace to watch (empty = all namespaces)")
+	flag.DurationVar(&secretDeleteGrace, "secret-delete-grace", 0, "How long to keep a synced Secret after its ConfigMap is deleted (0 = delete immediately)")
+	flag.BoolVar(&noBlockOwnerDeletion, "no-block-owner-deletion", false, "Set blockOwnerDeletion=false on owner references of synced Secrets")
+	flag.BoolVar(&failOnInvalidKeys, "fail-on-invalid-keys", false, "Do not sync ConfigMaps containing keys that are not valid Secret keys (default: skip those keys)")
+	flag.IntVar(&maxSecretKeys, "max-secret-keys", 0, "Refuse to sync ConfigMaps with more keys than this (0 = no limit)")
+	flag.BoolVar(&forceApply, "force-apply", false, "Take ownership of Secret fields that conflict with other field managers (default: skip the Secret)")
+	flag.StringVar(&tombstoneConfigMap, "tombstone-configmap", "", "Name of the ConfigMap recording Secrets deleted because their ConfigMap was deleted (empty = log only)")
+	flag.StringVar(&tombstoneNamespace, "tombstone-namespace", "", "Namespace of the tombstone ConfigMap (default: the controller's namespace)")
+	flag.IntVar(&tombstoneMaxEntries, "tombstone-max-entries", 500, "Maximum number of records kept in the tombstone ConfigMap")
+	flag.StringVar(&provenanceConfigMap, "provenance-configmap", "", "Name of the ConfigMap recording every Secret write with its source, target and last ConfigMap modifier (empty = debug log only)")
+	flag.StringVar(&provenanceNamespace, "provenance-namespace", "", "Namespace of the provenance ConfigMap (default: the controller's namespace)")
+	flag.IntVar(&provenanceMaxEntries, "provenance-max-entries", 1000, "Maximum number of records kept in the provenance ConfigMap")
+	flag.StringVar(&manageSince, "manage-since", "", "Only manage ConfigMaps created at or after this RFC3339 time (empty = manage all)")
+	flag.StringVar(&eventWebhookURL, "event-webhook-url", "", "POST every reconcile outcome as JSON to this URL (empty = disabled)")
+	flag.IntVar(&eventWebhookBuffer, "event-webhook-buffer", 1000, "Number of events buffered for the event webhook; newer events are dropped when full")
+	flag.StringVar(&syncAnnotation, "sync-annotation", defaultSyncAnnotation, "Annotation that marks ConfigMaps to sync, e.g. example.com/sync-to-secret")
+	flag.StringVar(&redactKeys, "redact-keys", "", "Comma-separated key name patterns (path.Match syntax, case-insensitive) shown as *** in logs and events, e.g. *token*,*password*")
+	flag.BoolVar(&dryRun, "dry-run", false, "Log intended Create/Update/Patch/Delete calls (with changed Secret keys) instead of performing them; events are logged and sync metrics and the event webhook are disabled")
+	flag.IntVar(&fanoutConcurrency, "fanout-concurrency", defaultFanoutConcurrency, "Maximum number of target namespaces a ConfigMap's Secret is written to concurrently")
+	flag.Int64Var(&maxFanoutBytes, "max-fanout-bytes", 0, "Refuse to sync a ConfigMap to other namespaces when its data size times the number of target namespaces exceeds this (0 = no limit)")
+	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false, "Enable leader election so only one replica syncs at a time; required when running more than one replica")
+	flag.StringVar(&leaderElectionID, "leader-election-id", defaultLeaderElectionID, "Name of the Lease used for leader election")
 	flag.Parse()
 
 	// 设置日志
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 	logger := ctrl.Log.WithName("setup")
+	logger.Info("Starting simple-controller", "version", version)
+
+	// 版本号会写入标签，构建时注入了不合法的值应尽早暴露
+	if errs := validation.IsValidLabelValue(version); len(errs) > 0 {
+		logger.Error(fmt.Errorf("%s", strings.Join(errs, "; ")), "Invalid build version, it must be a valid label value", "version", version)
+		os.Exit(1)
+	}
+
+	// 提前检查 metrics 端口，避免 Manager 启动失败时只看到底层的 listen 错误
+	if err := checkBindAddress("metrics-addr", metricsAddr); err != nil {
+		logger.Error(err, "Metrics address is unavailable")
+		os.Exit(1)
+	}
+	if err := checkBindAddress("health-probe-addr", healthProbeAddr); err != nil {
+		logger.Error(err, "Health probe address is unavailable")
+		os.Exit(1)
+	}
+
+	if errs := validation.IsQualifiedName(syncAnnotation); len(errs) > 0 {
+		logger.Error(fmt.Errorf("%s", strings.Join(errs, "; ")), "Invalid -sync-annotation, it must be a valid annotation key", "annotation", syncAnnotation)
+		os.Exit(1)
+	}
+	// 自定义的同步注解也是控制器认识的注解，不能被当作拼写错误
+	knownAnnotations[syncAnnotation] = true
+
+	redactPatterns, err := parseRedactPatterns(redactKeys)
+	if err != nil {
+		logger.Error(err, "Invalid -redact-keys")
+		os.Exit(1)
+	}
+
+	var manageSinceTime time.Time
+	if manageSince != "" {
+		t, err := time.Parse(time.RFC3339, manageSince)
+		if err != nil {
+			logger.Error(err, "Invalid -manage-since, expected RFC3339 such as 2024-01-02T15:04:05Z")
+			os.Exit(1)
+		}
+		manageSinceTime = t
+	}
 
 	// 创建 Manager
 	options := ctrl.Options{
 		Scheme: runtime.NewScheme(),
+		Metrics: metricsserver.Options{
+			BindAddress: metricsAddr,
+		},
+		HealthProbeBindAddress: healthProbeAddr,
 		Cache: cache.Options{
 			DefaultLabelSelector: makeLabelSelector(),
+			// Namespace 不会带 managed-by 标签，需要全部缓存才能匹配 target-namespace-selector
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Namespace{}: {Label: labels.Everything()},
+			},
 		},
-		LeaderElection: false, // 开发时关闭 Leader Election
 	}
+	applyLeaderElection(&options, enableLeaderElection, leaderElectionID)
 
 	// 如果指定了 namespace，只监听该 namespace
 	if namespace != "" {
@@ -221,6 +980,14 @@ func main() {
 		logger.Error(err, "Failed to add core/v1 to scheme")
 		os.Exit(1)
 	}
+	if err := batchv1.AddToScheme(options.Scheme); err != nil {
+		logger.Error(err, "Failed to add batch/v1 to scheme")
+		os.Exit(1)
+	}
+	if err := appsv1.AddToScheme(options.Scheme); err != nil {
+		logger.Error(err, "Failed to add apps/v1 to scheme")
+		os.Exit(1)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
@@ -229,21 +996,86 @@ func main() {
 	}
 
 	// 注册 Reconciler
-	if err := (&ConfigMapReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	reconciler := &ConfigMapReconciler{
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		SecretDeleteGrace:    secretDeleteGrace,
+		NoBlockOwnerDeletion: noBlockOwnerDeletion,
+		Recorder:             mgr.GetEventRecorderFor("simple-controller"),
+		FailOnInvalidKeys:    failOnInvalidKeys,
+		MaxSecretKeys:        maxSecretKeys,
+		ForceApply:           forceApply,
+		ManageSince:          manageSinceTime,
+		APIReader:            mgr.GetAPIReader(),
+		SyncAnnotation:       syncAnnotation,
+		RedactKeys:           redactPatterns,
+		FanoutConcurrency:    fanoutConcurrency,
+		MaxFanoutBytes:       maxFanoutBytes,
+	}
+	if dryRun {
+		reconciler.Client = &dryRunClient{Client: reconciler.Client, redact: reconciler.redactKeys}
+		reconciler.Recorder = &dryRunRecorder{logger: ctrl.Log.WithName("dry-run-events")}
+		reconciler.DryRun = true
+		logger.Info("Dry run enabled, no objects will be modified")
+	}
+	if tombstoneConfigMap != "" {
+		if tombstoneNamespace == "" {
+			tombstoneNamespace = controllerNamespace()
+		}
+		reconciler.Tombstones = &recordStore{
+			Client:     reconciler.Client,
+			Namespace:  tombstoneNamespace,
+			Name:       tombstoneConfigMap,
+			MaxEntries: tombstoneMaxEntries,
+		}
+	}
+	if provenanceConfigMap != "" {
+		if provenanceNamespace == "" {
+			provenanceNamespace = controllerNamespace()
+		}
+		reconciler.Provenance = &recordStore{
+			Client:     reconciler.Client,
+			Namespace:  provenanceNamespace,
+			Name:       provenanceConfigMap,
+			MaxEntries: provenanceMaxEntries,
+		}
+	}
+	if eventWebhookURL != "" && dryRun {
+		logger.Info("Dry run enabled, event webhook disabled", "url", eventWebhookURL)
+	} else if eventWebhookURL != "" {
+		reconciler.Webhook = newEventWebhook(eventWebhookURL, eventWebhookBuffer)
+		if err := mgr.Add(reconciler.Webhook); err != nil {
+			logger.Error(err, "Unable to create event webhook")
+			os.Exit(1)
+		}
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
 		logger.Error(err, "Unable to create controller")
 		os.Exit(1)
 	}
+	// 收到 SIGUSR1 时把受管对象清单输出到日志
+	if err := mgr.Add(manager.RunnableFunc(reconciler.dumpInventoryOnSignal)); err != nil {
+		logger.Error(err, "Unable to create inventory dump")
+		os.Exit(1)
+	}
+
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		logger.Error(err, "Unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("cache-sync", cacheSyncedCheck(mgr)); err != nil {
+		logger.Error(err, "Unable to set up ready check")
+		os.Exit(1)
+	}
 
-	fmt.Println(`
+	// 注解名可以通过 -sync-annotation 修改，横幅显示实际生效的注解
+	fmt.Printf(`
 ╔══════════════════════════════════════════════════════════════╗
 ║           Simple ConfigMap-to-Secret Controller              ║
 ╠══════════════════════════════════════════════════════════════╣
 ║  监听带有 annotation 的 ConfigMap，自动同步到 Secret           ║
 ║                                                              ║
-║  Annotation: simple-controller/sync-to-secret                ║
+║  Annotation: %-48s║
 ║                                                              ║
 ║  测试方法:                                                    ║
 ║  kubectl create configmap test-cm \                          ║
@@ -251,11 +1083,11 @@ func main() {
 ║    --from-literal=password=secret123                         ║
 ║                                                              ║
 ║  kubectl annotate configmap test-cm \                        ║
-║    simple-controller/sync-to-secret=true                     ║
+║    %-58s║
 ║                                                              ║
 ║  kubectl get secret test-cm-synced -o yaml                   ║
 ╚══════════════════════════════════════════════════════════════╝
-`)
+`, syncAnnotation, syncAnnotation+"=true")
 
 	logger.Info("Starting manager...")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+func TestCheckBindAddress(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	_, busyPort, _ := net.SplitHostPort(busy.Addr().String())
+
+	tests := []struct {
+		name     string
+		addr     string
+		wantErrs []string
+	}{
+		{name: "disabled", addr: "0"},
+		{name: "free port", addr: "127.0.0.1:0"},
+		{name: "busy port", addr: busy.Addr().String(), wantErrs: []string{"-metrics-addr=", "port " + busyPort + " is not available", "-metrics-addr=:0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBindAddress("metrics-addr", tt.addr)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("checkBindAddress(%q) = %v, want nil", tt.addr, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("checkBindAddress(%q) = nil, want an error", tt.addr)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyLeaderElection(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		id      string
+		want    ctrl.Options
+	}{
+		{name: "disabled by default", id: defaultLeaderElectionID, want: ctrl.Options{}},
+		{
+			name:    "enabled with the default lease",
+			enabled: true,
+			id:      defaultLeaderElectionID,
+			want:    ctrl.Options{LeaderElection: true, LeaderElectionID: defaultLeaderElectionID, LeaderElectionNamespace: controllerNamespace()},
+		},
+		{
+			name:    "custom lease name",
+			enabled: true,
+			id:      "team-a-sync",
+			want:    ctrl.Options{LeaderElection: true, LeaderElectionID: "team-a-sync", LeaderElectionNamespace: controllerNamespace()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got ctrl.Options
+			applyLeaderElection(&got, tt.enabled, tt.id)
+			if got.LeaderElection != tt.want.LeaderElection || got.LeaderElectionID != tt.want.LeaderElectionID ||
+				got.LeaderElectionNamespace != tt.want.LeaderElectionNamespace {
+				t.Fatalf("options = {LeaderElection: %v, ID: %q, Namespace: %q}, want {%v, %q, %q}",
+					got.LeaderElection, got.LeaderElectionID, got.LeaderElectionNamespace,
+					tt.want.LeaderElection, tt.want.LeaderElectionID, tt.want.LeaderElectionNamespace)
+			}
+		})
+	}
+}
+
+// stubManager 只实现 cacheSyncedCheck 用到的 GetCache
+type stubManager struct {
+	ctrl.Manager
+	synced bool
+}
+
+func (m stubManager) GetCache() cache.Cache { return stubCache{synced: m.synced} }
+
+type stubCache struct {
+	cache.Cache
+	synced bool
+}
+
+func (c stubCache) WaitForCacheSync(context.Context) bool { return c.synced }
+
+func TestHealthProbes(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		synced bool
+		want   int
+	}{
+		{name: "healthz", path: "/healthz", want: http.StatusOK},
+		{name: "readyz after cache sync", path: "/readyz", synced: true, want: http.StatusOK},
+		{name: "readyz before cache sync", path: "/readyz", want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle("/healthz", http.StripPrefix("/healthz", &healthz.Handler{Checks: map[string]healthz.Checker{"ping": healthz.Ping}}))
+			mux.Handle("/readyz", http.StripPrefix("/readyz", &healthz.Handler{Checks: map[string]healthz.Checker{
+				"cache-sync": cacheSyncedCheck(stubManager{synced: tt.synced}),
+			}}))
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			resp, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("GET %s = %d, want %d", tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// reconcileRateWindow 是计算调谐速率的滑动窗口长度
+const reconcileRateWindow = time.Minute
+
+// reconcileRate 记录每次调谐的时间，用于计算最近一个窗口内的调谐速率
+var reconcileRate = newRateWindow(reconcileRateWindow)
+
+// reconcileDurationBuckets 覆盖几毫秒的缓存命中到数十秒的慢调谐，便于计算 p50/p95/p99
+var reconcileDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// reconcileDuration 按控制器和资源 GVK 记录调谐耗时
+var reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "reconcile_duration_seconds",
+	Help:    "Duration of reconciles in seconds, including ones that returned an error.",
+	Buckets: reconcileDurationBuckets,
+}, []string{"controller", "group", "version", "kind"})
+
+// syncedSecretBytes 记录每次同步写入的 Secret 数据大小，Secret 上限是 1MiB，桶覆盖 64B 到 1MiB
+var syncedSecretBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "synced_secret_bytes",
+	Help:    "Total size in bytes of the data written to a Secret by a successful sync.",
+	Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+})
+
+// sync 结果，作为 configmap_sync_total 的 result 标签
+const (
+	syncResultCreated = "created"
+	syncResultUpdated = "updated"
+	syncResultDeleted = "deleted"
+	syncResultError   = "error"
+)
+
+// syncTotal 按结果统计 Secret 的同步操作，error 按调谐计数，可用于在同步持续失败时告警
+var syncTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "configmap_sync_total",
+	Help: "Secret sync operations of the ConfigMap controller by result (created, updated, deleted, error).",
+}, []string{"result"})
+
+// countSync 增加一次同步计数，dry-run 时没有真正写入，不计数
+func (r *ConfigMapReconciler) countSync(result string) {
+	if r.DryRun {
+		return
+	}
+	syncTotal.WithLabelValues(result).Inc()
+}
+
+// observeSecretSize 记录一次成功同步写入的数据大小
+func observeSecretSize(data map[string][]byte) {
+	size := 0
+	for _, v := range data {
+		size += len(v)
+	}
+	syncedSecretBytes.Observe(float64(size))
+}
+
+// observeReconcileDuration 记录一次 ConfigMap 调谐的耗时，配合 defer 使用，错误返回时也会记录
+func observeReconcileDuration(start time.Time) {
+	reconcileDuration.WithLabelValues("configmap", "", "v1", "ConfigMap").Observe(time.Since(start).Seconds())
+}
+
+func init() {
+	metrics.Registry.MustRegister(reconcileDuration, syncedSecretBytes, syncTotal)
+	// 预先初始化所有结果，没有发生过的结果也会以 0 导出，告警规则可以直接使用 rate()
+	for _, result := range []string{syncResultCreated, syncResultUpdated, syncResultDeleted, syncResultError} {
+		syncTotal.WithLabelValues(result)
+	}
+
+	// 使用 GaugeFunc，在抓取时按当前时间计算速率，调谐停止后数值会自然回落到 0
+	metrics.Registry.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "configmap_reconcile_rate",
+			Help: "Reconciles per second of the ConfigMap controller over a sliding window of the last minute.",
+		},
+		func() float64 { return reconcileRate.Rate(time.Now()) },
+	))
+}
+
+// rateWindow 用滑动窗口计算事件速率（次/秒）
+type rateWindow struct {
+	mu     sync.Mutex
+	window time.Duration
+	events []time.Time
+}
+
+func newRateWindow(window time.Duration) *rateWindow {
+	return &rateWindow{window: window}
+}
+
+// Observe 记录一次发生在 t 时刻的事件
+func (w *rateWindow) Observe(t time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.events = append(w.events, t)
+	w.prune(t)
+}
+
+// Rate 返回截至 now 的窗口内平均速率
+func (w *rateWindow) Rate(now time.Time) float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.prune(now)
+	return float64(len(w.events)) / w.window.Seconds()
+}
+
+// prune 丢弃已经滑出窗口的事件，调用方需持有锁
+func (w *rateWindow) prune(now time.Time) {
+	cutoff := now.Add(-w.window)
+	i := 0
+	for i < len(w.events) && !w.events[i].After(cutoff) {
+		i++
+	}
+	w.events = w.events[i:]
+}
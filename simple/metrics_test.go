@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRateWindow(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(seconds ...int) []time.Time {
+		var out []time.Time
+		for _, s := range seconds {
+			out = append(out, base.Add(time.Duration(s)*time.Second))
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		window time.Duration
+		events []time.Time
+		now    time.Time
+		want   float64
+	}{
+		{"no events", time.Minute, nil, base, 0},
+		{"all inside the window", time.Minute, at(0, 10, 20, 30, 40, 50), base.Add(55 * time.Second), 0.1},
+		{"old events pruned", time.Minute, at(0, 10, 70, 80, 90), base.Add(100 * time.Second), 3.0 / 60},
+		{"event on the cutoff excluded", time.Minute, at(0, 30), base.Add(time.Minute), 1.0 / 60},
+		{"decays to zero", 10 * time.Second, at(0, 1, 2), base.Add(time.Minute), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newRateWindow(tt.window)
+			for _, ev := range tt.events {
+				w.Observe(ev)
+			}
+			if got := w.Rate(tt.now); got != tt.want {
+				t.Fatalf("Rate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// histogramOf 读取直方图的当前值
+func histogramOf(t *testing.T, observer prometheus.Observer) *dto.Histogram {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := observer.(prometheus.Histogram).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram()
+}
+
+func TestReconcileDurationObservedOnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		getErr  error
+		wantErr bool
+	}{
+		{"success", nil, false},
+		{"get fails", errors.New("apiserver unavailable"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			funcs := interceptor.Funcs{}
+			if tt.getErr != nil {
+				funcs.Get = func(context.Context, client.WithWatch, client.ObjectKey, client.Object, ...client.GetOption) error {
+					return tt.getErr
+				}
+			}
+			env := newTestEnv(t, []client.Object{newConfigMap("app")}, withInterceptor(funcs))
+			observer := reconcileDuration.WithLabelValues("configmap", "", "v1", "ConfigMap")
+
+			before := histogramOf(t, observer).GetSampleCount()
+			_, err := env.r.Reconcile(context.Background(), requestFor("app"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reconcile error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := histogramOf(t, observer).GetSampleCount() - before; got != 1 {
+				t.Fatalf("recorded %d observations, want 1", got)
+			}
+		})
+	}
+}
+
+func TestReconcileRecordsSecretSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		wantBucket float64
+	}{
+		{"tiny", 10, 64},
+		{"100 bytes", 100, 256},
+		{"3 KiB", 3 * 1024, 4096},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Data = map[string]string{"payload": strings.Repeat("x", tt.size)}
+			})})
+			before := histogramOf(t, syncedSecretBytes)
+			env.reconcile(t, "app")
+			after := histogramOf(t, syncedSecretBytes)
+
+			if got := after.GetSampleCount() - before.GetSampleCount(); got != 1 {
+				t.Fatalf("recorded %d observations, want 1", got)
+			}
+			if got := after.GetSampleSum() - before.GetSampleSum(); got != float64(tt.size) {
+				t.Fatalf("observed %v bytes, want %d", got, tt.size)
+			}
+			// 只有上界不小于数据大小的桶计数增加
+			for i, b := range after.GetBucket() {
+				delta := b.GetCumulativeCount() - before.GetBucket()[i].GetCumulativeCount()
+				want := uint64(0)
+				if b.GetUpperBound() >= tt.wantBucket {
+					want = 1
+				}
+				if delta != want {
+					t.Fatalf("bucket le=%v grew by %d, want %d", b.GetUpperBound(), delta, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileCountsSyncs(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newConfigMap("app")})
+	failApply := false
+	env.c = interceptor.NewClient(env.c, interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if failApply {
+				return errors.New("apiserver unavailable")
+			}
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	})
+	env.r.Client = env.c
+
+	steps := []struct {
+		name    string
+		do      func(t *testing.T)
+		result  string
+		wantErr bool
+	}{
+		{name: "create", result: syncResultCreated},
+		{name: "update", result: syncResultUpdated, do: func(t *testing.T) {
+			env.updateConfigMap(t, "app", func(cm *corev1.ConfigMap) { cm.Data["password"] = "rotated" })
+		}},
+		{name: "error", result: syncResultError, wantErr: true, do: func(t *testing.T) {
+			env.updateConfigMap(t, "app", func(cm *corev1.ConfigMap) { cm.Data["password"] = "again" })
+			failApply = true
+		}},
+		{name: "delete", result: syncResultDeleted, do: func(t *testing.T) {
+			failApply = false
+			env.deleteConfigMap(t, "app")
+		}},
+	}
+	for _, step := range steps {
+		if step.do != nil {
+			step.do(t)
+		}
+		before := testutil.ToFloat64(syncTotal.WithLabelValues(step.result))
+		_, err := env.r.Reconcile(context.Background(), requestFor("app"))
+		if (err != nil) != step.wantErr {
+			t.Fatalf("%s: Reconcile error = %v, want error %v", step.name, err, step.wantErr)
+		}
+		if got := testutil.ToFloat64(syncTotal.WithLabelValues(step.result)) - before; got != 1 {
+			t.Fatalf("%s: configmap_sync_total{result=%q} increased by %v, want 1", step.name, step.result, got)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileNameHash(t *testing.T) {
+	hashed := hashedSecretName(testNamespace, "app")
+	tests := []struct {
+		name     string
+		nameHash string
+		want     string
+		wantNot  string
+	}{
+		{name: "name-hash enabled", nameHash: "true", want: hashed, wantNot: "app-synced"},
+		{name: "name-hash disabled", nameHash: "false", want: "app-synced", wantNot: hashed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[nameHashAnnotation] = tt.nameHash
+			})})
+			env.reconcile(t, "app")
+
+			if got := env.secret(t, testNamespace, tt.want); string(got.Data["password"]) != "s3cret" {
+				t.Fatalf("Secret %s has data %v", tt.want, got.Data)
+			}
+			if env.secretExists(t, testNamespace, tt.wantNot) {
+				t.Fatalf("unexpected Secret %s", tt.wantNot)
+			}
+
+			env.deleteConfigMap(t, "app")
+			env.reconcile(t, "app")
+			if env.secretExists(t, testNamespace, tt.want) {
+				t.Fatalf("expected Secret %s to be deleted with its ConfigMap", tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileNameHashToggle(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newConfigMap("app")})
+	env.reconcile(t, "app")
+
+	// 开启 name-hash 后写入新名称的 Secret，并删除旧名称的 Secret
+	env.updateConfigMap(t, "app", func(cm *corev1.ConfigMap) { cm.Annotations[nameHashAnnotation] = "true" })
+	env.reconcile(t, "app")
+	if !env.secretExists(t, testNamespace, hashedSecretName(testNamespace, "app")) {
+		t.Fatal("expected the hashed Secret to be created")
+	}
+	if env.secretExists(t, testNamespace, "app-synced") {
+		t.Fatal("expected the Secret with the old name to be pruned")
+	}
+}
+
+func TestHashedSecretName(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		cm        string
+	}{
+		{name: "short name", namespace: "team-a", cm: "app"},
+		{name: "truncated long name", namespace: "team-a", cm: strings.Repeat("a", 250)},
+		{name: "truncation trims trailing separators", namespace: "team-a", cm: strings.Repeat("a", 235) + "-" + strings.Repeat("b", 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hashedSecretName(tt.namespace, tt.cm)
+			if errs := validation.IsDNS1123Subdomain(got); len(errs) > 0 {
+				t.Fatalf("%q is not a valid name: %v", got, errs)
+			}
+			if got != hashedSecretName(tt.namespace, tt.cm) {
+				t.Fatal("expected a stable name")
+			}
+			if got == hashedSecretName("team-b", tt.cm) {
+				t.Fatal("expected different source namespaces to get different names")
+			}
+		})
+	}
+}
+
+func TestReconcileSecretName(t *testing.T) {
+	tests := []struct {
+		name       string
+		secretName string
+		wantSecret string
+		wantErr    bool
+	}{
+		{name: "custom name", secretName: "db-creds", wantSecret: "db-creds"},
+		{name: "absent falls back to the default", wantSecret: "app-synced"},
+		{name: "invalid name rejected", secretName: "DB_Creds", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				if tt.secretName != "" {
+					cm.Annotations[secretNameAnnotation] = tt.secretName
+				}
+			})})
+			env.reconcile(t, "app")
+
+			if tt.wantErr {
+				if env.secretExists(t, testNamespace, "app-synced") {
+					t.Fatal("expected no Secret for an invalid name")
+				}
+				if got := env.configMap(t, "app").Annotations[syncErrorAnnotation]; !strings.Contains(got, secretNameAnnotation) {
+					t.Fatalf("%s = %q, want it to mention %s", syncErrorAnnotation, got, secretNameAnnotation)
+				}
+				return
+			}
+			if got := env.secret(t, testNamespace, tt.wantSecret); string(got.Data["password"]) != "s3cret" {
+				t.Fatalf("Secret %s has data %v", tt.wantSecret, got.Data)
+			}
+
+			env.deleteConfigMap(t, "app")
+			env.reconcile(t, "app")
+			if env.secretExists(t, testNamespace, tt.wantSecret) {
+				t.Fatalf("expected Secret %s to be deleted with its ConfigMap", tt.wantSecret)
+			}
+		})
+	}
+}
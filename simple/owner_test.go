@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileBlockOwnerDeletion(t *testing.T) {
+	tests := []struct {
+		name                 string
+		noBlockOwnerDeletion bool
+		want                 bool
+	}{
+		{"default", false, true},
+		{"disabled by flag", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app")}, withReconciler(func(r *ConfigMapReconciler) {
+				r.NoBlockOwnerDeletion = tt.noBlockOwnerDeletion
+			}))
+			env.reconcile(t, "app")
+
+			refs := env.secret(t, testNamespace, "app-synced").OwnerReferences
+			if len(refs) != 1 {
+				t.Fatalf("owner references = %v, want one", refs)
+			}
+			if got := ptr.Deref(refs[0].BlockOwnerDeletion, false); got != tt.want {
+				t.Fatalf("blockOwnerDeletion = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileOwnerModes(t *testing.T) {
+	tests := []struct {
+		mode          string
+		wantRefs      int
+		wantCtrl      bool
+		wantFinalizer bool
+	}{
+		{mode: ownerModeController, wantRefs: 1, wantCtrl: true},
+		{mode: ownerModeReference, wantRefs: 1, wantCtrl: false},
+		{mode: ownerModeNone, wantRefs: 0, wantFinalizer: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[ownerModeAnnotation] = tt.mode
+			})})
+			env.reconcile(t, "app")
+
+			refs := env.secret(t, testNamespace, "app-synced").OwnerReferences
+			if len(refs) != tt.wantRefs {
+				t.Fatalf("owner references = %v, want %d", refs, tt.wantRefs)
+			}
+			if tt.wantRefs > 0 {
+				if refs[0].Kind != "ConfigMap" || refs[0].Name != "app" || refs[0].UID != "app-uid" {
+					t.Fatalf("owner reference = %+v, want the source ConfigMap", refs[0])
+				}
+				if got := ptr.Deref(refs[0].Controller, false); got != tt.wantCtrl {
+					t.Fatalf("controller = %v, want %v", got, tt.wantCtrl)
+				}
+			}
+			hasFinalizer := containsFinalizer(env.configMap(t, "app").Finalizers, finalizerName)
+			if hasFinalizer != tt.wantFinalizer {
+				t.Fatalf("finalizer present = %v, want %v", hasFinalizer, tt.wantFinalizer)
+			}
+
+			// controller/reference 模式由 GC 级联删除（fake client 没有 GC），none 模式由 Finalizer 清理
+			if !tt.wantFinalizer {
+				return
+			}
+			env.deleteConfigMap(t, "app")
+			env.reconcile(t, "app")
+			if env.secretExists(t, testNamespace, "app-synced") {
+				t.Fatal("expected the Secret to be deleted with the ConfigMap")
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// 注解：设置为 true 时把 ConfigMap 的标签和非系统注解复制到 Secret 上，方便下游按标签筛选 Secret
+const propagateLabelsAnnotation = "simple-controller/propagate-labels"
+
+// systemAnnotationDomains 是不复制的注解所属的域，这些注解由 Kubernetes 组件或 kubectl 维护，与 Secret 无关
+var systemAnnotationDomains = []string{"kubernetes.io", "k8s.io"}
+
+// propagatedMetadata 返回需要从 ConfigMap 复制到 Secret 的标签和注解。
+// 控制器自己的注解（包括自定义的同步注解）和系统注解不复制；控制器管理的标签和注解由调用方保证不被覆盖
+func (r *ConfigMapReconciler) propagatedMetadata(cm *corev1.ConfigMap) (labels, annotations map[string]string) {
+	if !annotationEnabled(cm, propagateLabelsAnnotation) {
+		return nil, nil
+	}
+	labels = make(map[string]string, len(cm.Labels))
+	for k, v := range cm.Labels {
+		labels[k] = v
+	}
+	annotations = map[string]string{}
+	for k, v := range cm.Annotations {
+		if strings.HasPrefix(k, annotationPrefix) || k == r.syncAnnotation() || isSystemAnnotation(k) {
+			continue
+		}
+		annotations[k] = v
+	}
+	return labels, annotations
+}
+
+// isSystemAnnotation 判断注解是否属于 kubernetes.io 或 k8s.io 域（包括其子域）
+func isSystemAnnotation(key string) bool {
+	prefix, _, ok := strings.Cut(key, "/")
+	if !ok {
+		return false
+	}
+	for _, domain := range systemAnnotationDomains {
+		if prefix == domain || strings.HasSuffix(prefix, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeMissing 把 extra 中 dst 没有的 key 复制到 dst，已有的 key 保持不变
+func mergeMissing(dst, extra map[string]string) {
+	for k, v := range extra {
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+}
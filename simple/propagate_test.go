@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcilePropagateLabels(t *testing.T) {
+	tests := []struct {
+		name      string
+		propagate string
+		wantCopy  bool
+	}{
+		{name: "enabled", propagate: "true", wantCopy: true},
+		{name: "disabled", propagate: "false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[propagateLabelsAnnotation] = tt.propagate
+				cm.Annotations["team.example.com/owner"] = "payments"
+				cm.Annotations["kubectl.kubernetes.io/last-applied-configuration"] = "{}"
+				cm.Labels["tier"] = "backend"
+				// 与控制器管理的标签同名，不能覆盖
+				cm.Labels[versionLabel] = "spoofed"
+			})})
+			env.reconcile(t, "app")
+			secret := env.secret(t, testNamespace, "app-synced")
+
+			if got := secret.Labels["tier"] == "backend"; got != tt.wantCopy {
+				t.Fatalf("label tier copied = %v, want %v", got, tt.wantCopy)
+			}
+			if got := secret.Annotations["team.example.com/owner"] == "payments"; got != tt.wantCopy {
+				t.Fatalf("annotation team.example.com/owner copied = %v, want %v", got, tt.wantCopy)
+			}
+			if got := secret.Labels[versionLabel]; got != version {
+				t.Fatalf("%s = %q, want the controller's value", versionLabel, got)
+			}
+			for _, key := range []string{defaultSyncAnnotation, propagateLabelsAnnotation, "kubectl.kubernetes.io/last-applied-configuration"} {
+				if _, ok := secret.Annotations[key]; ok {
+					t.Fatalf("annotation %s should not be copied", key)
+				}
+			}
+		})
+	}
+}
+
+func TestIsSystemAnnotation(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"kubernetes.io/description", true},
+		{"kubectl.kubernetes.io/last-applied-configuration", true},
+		{"deployment.k8s.io/revision", true},
+		{"example.com/owner", false},
+		{"notkubernetes.io/owner", false},
+		{"plain", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := isSystemAnnotation(tt.key); got != tt.want {
+				t.Fatalf("isSystemAnnotation(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// provenance 记录一次写入 Secret 的同步，用于合规审计：来源、目标、内容哈希，以及最后修改 ConfigMap 数据的人和时间
+type provenance struct {
+	Source      string     `json:"source"`
+	Target      string     `json:"target"`
+	Result      string     `json:"result"`
+	ContentHash string     `json:"contentHash"`
+	ModifiedBy  string     `json:"modifiedBy,omitempty"`
+	ModifiedAt  *time.Time `json:"modifiedAt,omitempty"`
+	SyncedAt    time.Time  `json:"syncedAt"`
+}
+
+// lastDataModifier 从 managedFields 中找出最后一次修改 data 字段的管理者（如 kubectl-client-side-apply、argocd-controller）。
+// managedFields 只记录每个管理者最近一次操作的时间，同一管理者的多次修改以最后一次为准；找不到时返回空
+func lastDataModifier(cm *corev1.ConfigMap) (manager string, at *time.Time) {
+	for _, entry := range cm.ManagedFields {
+		if entry.FieldsV1 == nil || !bytes.Contains(entry.FieldsV1.Raw, []byte(`"f:data"`)) || entry.Time == nil {
+			continue
+		}
+		if at == nil || entry.Time.Time.After(*at) {
+			t := entry.Time.Time.UTC()
+			manager, at = entry.Manager, &t
+		}
+	}
+	return manager, at
+}
+
+// recordProvenance 记录一次成功写入 Secret 的同步。记录总会写入日志，配置了 Provenance 时还会追加到记录 ConfigMap；
+// 写入失败只记录错误，不影响同步
+func (r *ConfigMapReconciler) recordProvenance(ctx context.Context, cm *corev1.ConfigMap, secret *corev1.Secret, result string) {
+	logger := log.FromContext(ctx)
+	p := provenance{
+		Source:      cm.Namespace + "/" + cm.Name,
+		Target:      secret.Namespace + "/" + secret.Name,
+		Result:      result,
+		ContentHash: secret.Annotations[contentHashAnnotation],
+		SyncedAt:    time.Now().UTC(),
+	}
+	p.ModifiedBy, p.ModifiedAt = lastDataModifier(cm)
+	logger.V(1).Info("Sync provenance", "provenance", p)
+	if r.Provenance != nil {
+		if err := r.Provenance.Append(ctx, p); err != nil {
+			logger.Error(err, "Failed to record sync provenance", "configmap", r.Provenance.Name)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileRecordsProvenance(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newConfigMap("app")}, withReconciler(func(r *ConfigMapReconciler) {
+		r.Provenance = &recordStore{Client: r.Client, Namespace: testNamespace, Name: "provenance", MaxEntries: 10}
+	}))
+	start := time.Now().UTC()
+	env.reconcile(t, "app")
+
+	records := env.configMap(t, "provenance").Data
+	if len(records) != 1 {
+		t.Fatalf("got %d provenance records, want 1: %v", len(records), records)
+	}
+	for _, value := range records {
+		var got provenance
+		if err := json.Unmarshal([]byte(value), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Source != testNamespace+"/app" || got.Target != testNamespace+"/app-synced" || got.Result != syncResultCreated {
+			t.Fatalf("provenance = %+v, want the source, target and result", got)
+		}
+		if got.SyncedAt.Before(start.Add(-time.Second)) || got.SyncedAt.After(time.Now().Add(time.Second)) {
+			t.Fatalf("syncedAt = %v, want around %v", got.SyncedAt, start)
+		}
+		if got.ContentHash == "" {
+			t.Fatal("expected the content hash")
+		}
+	}
+}
+
+func TestLastDataModifier(t *testing.T) {
+	earlier := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	later := metav1.NewTime(earlier.Add(time.Hour))
+	entry := func(manager string, at *metav1.Time, fields string) metav1.ManagedFieldsEntry {
+		return metav1.ManagedFieldsEntry{Manager: manager, Time: at, FieldsV1: &metav1.FieldsV1{Raw: []byte(fields)}}
+	}
+	tests := []struct {
+		name    string
+		entries []metav1.ManagedFieldsEntry
+		want    string
+		wantAt  *metav1.Time
+	}{
+		{name: "no managed fields"},
+		{
+			name:    "latest data writer wins",
+			entries: []metav1.ManagedFieldsEntry{entry("kubectl", &earlier, `{"f:data":{}}`), entry("argocd-controller", &later, `{"f:data":{}}`)},
+			want:    "argocd-controller",
+			wantAt:  &later,
+		},
+		{
+			name:    "metadata-only writers ignored",
+			entries: []metav1.ManagedFieldsEntry{entry("kubectl", &earlier, `{"f:data":{}}`), entry("labeler", &later, `{"f:metadata":{}}`)},
+			want:    "kubectl",
+			wantAt:  &earlier,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newConfigMap("app", func(cm *corev1.ConfigMap) { cm.ManagedFields = tt.entries })
+			manager, at := lastDataModifier(cm)
+			if manager != tt.want {
+				t.Fatalf("manager = %q, want %q", manager, tt.want)
+			}
+			if (at == nil) != (tt.wantAt == nil) || (at != nil && !at.Equal(tt.wantAt.Time)) {
+				t.Fatalf("time = %v, want %v", at, tt.wantAt)
+			}
+		})
+	}
+}
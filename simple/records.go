@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// recordStore 把 JSON 记录追加到一个 ConfigMap 中，key 是补零的纳秒时间戳，按字典序即时间顺序，
+// 超过 MaxEntries 时淘汰最旧的记录。ConfigMap 带 managed-by 标签，才能通过控制器的缓存读到
+type recordStore struct {
+	Client     client.Client
+	Namespace  string
+	Name       string
+	MaxEntries int
+
+	mu sync.Mutex
+}
+
+// Append 追加一条记录，遇到并发修改时重试
+func (s *recordStore) Append(ctx context.Context, record any) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := fmt.Sprintf("%020d", time.Now().UnixNano())
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		err := s.Client.Get(ctx, types.NamespacedName{Namespace: s.Namespace, Name: s.Name}, cm)
+		if errors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: s.Namespace,
+					Name:      s.Name,
+					Labels:    map[string]string{managedByLabel: managedByValue},
+				},
+				Data: map[string]string{key: string(value)},
+			}
+			return s.Client.Create(ctx, cm)
+		}
+		if err != nil {
+			return err
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[key] = string(value)
+		if s.MaxEntries > 0 && len(cm.Data) > s.MaxEntries {
+			keys := make([]string, 0, len(cm.Data))
+			for k := range cm.Data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys[:len(keys)-s.MaxEntries] {
+				delete(cm.Data, k)
+			}
+		}
+		return s.Client.Update(ctx, cm)
+	})
+}
+
+// controllerNamespace 返回控制器 Pod 所在的 namespace，集群外运行时返回 default
+func controllerNamespace() string {
+	if ns, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		if ns := strings.TrimSpace(string(ns)); ns != "" {
+			return ns
+		}
+	}
+	return "default"
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// redactedKey 替换日志和事件中被脱敏的 key 名称
+const redactedKey = "***"
+
+// parseRedactPatterns 解析逗号分隔的 key 名称通配符（path.Match 语法，不区分大小写），如 "*token*,*password*"
+func parseRedactPatterns(v string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(v, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// redactKey 判断 key 名称是否匹配任一脱敏规则
+func (r *ConfigMapReconciler) redactKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range r.RedactKeys {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactKeys 返回用于日志和事件的 key 列表，匹配脱敏规则的名称替换为 ***
+func (r *ConfigMapReconciler) redactKeys(keys []string) []string {
+	if len(r.RedactKeys) == 0 {
+		return keys
+	}
+	redacted := make([]string, len(keys))
+	for i, k := range keys {
+		if r.redactKey(k) {
+			k = redactedKey
+		}
+		redacted[i] = k
+	}
+	return redacted
+}
+
+// changedKeys 返回 existing 与 desired 之间新增、删除或值发生变化的 key（按字母排序），只用于日志
+func changedKeys(existing, desired map[string]string) []string {
+	var keys []string
+	for k, v := range desired {
+		if old, ok := existing[k]; !ok || old != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range existing {
+		if _, ok := desired[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
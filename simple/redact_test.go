@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestReconcileRedactsChangedKeys(t *testing.T) {
+	tests := []struct {
+		name         string
+		patterns     []string
+		wantVerbatim bool
+	}{
+		{name: "matching key redacted", patterns: []string{"*token*"}},
+		{name: "case-insensitive match", patterns: []string{"api-*"}},
+		{name: "no patterns", wantVerbatim: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app")}, withReconciler(func(r *ConfigMapReconciler) {
+				r.RedactKeys = tt.patterns
+			}))
+			env.reconcile(t, "app")
+			env.updateConfigMap(t, "app", func(cm *corev1.ConfigMap) { cm.Data["API-Token"] = "abc" })
+
+			var logs []string
+			logger := funcr.New(func(prefix, args string) {
+				logs = append(logs, args)
+			}, funcr.Options{Verbosity: 1})
+			if _, err := env.r.Reconcile(log.IntoContext(context.Background(), logger), requestFor("app")); err != nil {
+				t.Fatal(err)
+			}
+
+			verbatim := slices.ContainsFunc(logs, func(l string) bool { return strings.Contains(l, "API-Token") })
+			if verbatim != tt.wantVerbatim {
+				t.Fatalf("key logged verbatim = %v, want %v; logs: %v", verbatim, tt.wantVerbatim, logs)
+			}
+			redacted := slices.ContainsFunc(logs, func(l string) bool { return strings.Contains(l, redactedKey) })
+			if redacted == tt.wantVerbatim {
+				t.Fatalf("redacted key logged = %v, want %v; logs: %v", redacted, !tt.wantVerbatim, logs)
+			}
+		})
+	}
+}
+
+func TestParseRedactPatterns(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: " *Token*, ,*password* ", want: []string{"*token*", "*password*"}},
+		{value: "[", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseRedactPatterns(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("patterns = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// decodedSecretCacheSize 是解码缓存最多保存的 Secret 版本数
+const decodedSecretCacheSize = 1024
+
+// decodedSecrets 缓存 Secret 解码后的数据，事件过滤和同步前的比较共用
+var decodedSecrets = newDecodedSecretCache(decodedSecretCacheSize)
+
+// decodedSecretKey 用 UID 和 resourceVersion 标识 Secret 的一个版本，版本变化后自然不再命中
+type decodedSecretKey struct {
+	uid             types.UID
+	resourceVersion string
+}
+
+type decodedSecretEntry struct {
+	key  decodedSecretKey
+	data map[string]string
+}
+
+// decodedSecretCache 是按最近使用淘汰的 Secret 解码缓存，大规模集群中避免每次事件和调谐都重复转换 Data
+type decodedSecretCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[decodedSecretKey]*list.Element
+}
+
+func newDecodedSecretCache(size int) *decodedSecretCache {
+	return &decodedSecretCache{
+		size:    size,
+		order:   list.New(),
+		entries: map[decodedSecretKey]*list.Element{},
+	}
+}
+
+// Data 返回 Secret 数据的字符串形式，调用方不能修改返回的 map。
+// 没有 UID 或 resourceVersion 的对象（尚未写入 API Server）不缓存
+func (c *decodedSecretCache) Data(s *corev1.Secret) map[string]string {
+	key := decodedSecretKey{uid: s.UID, resourceVersion: s.ResourceVersion}
+	cacheable := key.uid != "" && key.resourceVersion != ""
+
+	if cacheable {
+		c.mu.Lock()
+		if e, ok := c.entries[key]; ok {
+			c.order.MoveToFront(e)
+			data := e.Value.(*decodedSecretEntry).data
+			c.mu.Unlock()
+			return data
+		}
+		c.mu.Unlock()
+	}
+
+	data := make(map[string]string, len(s.Data))
+	for k, v := range s.Data {
+		data[k] = string(v)
+	}
+	if !cacheable {
+		return data
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.order.PushFront(&decodedSecretEntry{key: key, data: data})
+		for c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*decodedSecretEntry).key)
+		}
+	}
+	return data
+}
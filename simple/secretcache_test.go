@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDecodedSecretCache(t *testing.T) {
+	secret := func(uid, rv, value string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{UID: types.UID(uid), ResourceVersion: rv},
+			Data:       map[string][]byte{"password": []byte(value)},
+		}
+	}
+	tests := []struct {
+		name   string
+		first  *corev1.Secret
+		second *corev1.Secret
+		want   string
+	}{
+		// 命中时直接返回缓存，不会重新转换 Data，所以看不到同一版本上被改动的内容
+		{name: "same version hits the cache", first: secret("a", "1", "old"), second: secret("a", "1", "new"), want: "old"},
+		{name: "new resourceVersion decodes again", first: secret("a", "1", "old"), second: secret("a", "2", "new"), want: "new"},
+		{name: "different UID decodes again", first: secret("a", "1", "old"), second: secret("b", "1", "new"), want: "new"},
+		{name: "objects without resourceVersion are not cached", first: secret("a", "", "old"), second: secret("a", "", "new"), want: "new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newDecodedSecretCache(8)
+			c.Data(tt.first)
+			if got := c.Data(tt.second)["password"]; got != tt.want {
+				t.Fatalf("password = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodedSecretCacheEviction(t *testing.T) {
+	c := newDecodedSecretCache(2)
+	a := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "a", ResourceVersion: "1"}}
+	b := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "b", ResourceVersion: "1"}}
+	d := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "d", ResourceVersion: "1"}}
+	c.Data(a)
+	c.Data(b)
+	c.Data(a) // a 变为最近使用
+	c.Data(d) // 淘汰 b
+
+	if c.order.Len() != 2 {
+		t.Fatalf("cache holds %d entries, want 2", c.order.Len())
+	}
+	for _, s := range []*corev1.Secret{a, d} {
+		if _, ok := c.entries[decodedSecretKey{uid: s.UID, resourceVersion: s.ResourceVersion}]; !ok {
+			t.Fatalf("expected %s to stay cached", s.UID)
+		}
+	}
+	if _, ok := c.entries[decodedSecretKey{uid: "b", resourceVersion: "1"}]; ok {
+		t.Fatal("expected the least recently used entry to be evicted")
+	}
+}
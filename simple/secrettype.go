@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// 注解：同步出的 Secret 的类型，默认 Opaque。类型创建后不可修改，变更时需要 allow-recreate 才会删除并重建 Secret
+const secretTypeAnnotation = "simple-controller/secret-type"
+
+// allowedSecretTypes 是 secret-type 注解允许的类型。service-account-token 等由集群组件管理的类型不在其中
+var allowedSecretTypes = map[corev1.SecretType]bool{
+	corev1.SecretTypeOpaque:           true,
+	corev1.SecretTypeTLS:              true,
+	corev1.SecretTypeDockerConfigJson: true,
+	corev1.SecretTypeDockercfg:        true,
+	corev1.SecretTypeBasicAuth:        true,
+	corev1.SecretTypeSSHAuth:          true,
+}
+
+// secretType 返回 ConfigMap 期望的 Secret 类型，没有设置时为 Opaque。
+// 特定类型要求的 key（如 tls.crt、tls.key）由 API Server 校验
+func secretType(cm *corev1.ConfigMap) (corev1.SecretType, error) {
+	v, ok := cm.Annotations[secretTypeAnnotation]
+	if !ok || v == "" {
+		return corev1.SecretTypeOpaque, nil
+	}
+	t := corev1.SecretType(v)
+	if !allowedSecretTypes[t] {
+		return "", fmt.Errorf("invalid %s %q: must be one of %s, %s, %s, %s, %s, %s", secretTypeAnnotation, v,
+			corev1.SecretTypeOpaque, corev1.SecretTypeTLS, corev1.SecretTypeDockerConfigJson,
+			corev1.SecretTypeDockercfg, corev1.SecretTypeBasicAuth, corev1.SecretTypeSSHAuth)
+	}
+	return t, nil
+}
+
+// 注解：设置为 true 时允许删除并重建 Secret 以修改不可变的字段（目前是 type），默认拒绝修改
+const allowRecreateAnnotation = "simple-controller/allow-recreate"
+
+// secretTypeConflicts 检查目标 namespace 中已有的 Secret 是否与期望的类型不同。
+// 类型不可修改，只能删除重建；没有设置 allow-recreate 时返回拒绝原因，避免意外删除正在使用的 Secret
+func (r *ConfigMapReconciler) secretTypeConflicts(ctx context.Context, cm *corev1.ConfigMap, targets []string) (string, error) {
+	if annotationEnabled(cm, allowRecreateAnnotation) {
+		return "", nil
+	}
+	desired, err := secretType(cm)
+	if err != nil {
+		return "", err
+	}
+	name := secretName(cm)
+	for _, ns := range targets {
+		existing := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: ns, Name: name}, existing); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		if existing.Type != desired {
+			return fmt.Sprintf("Secret %s/%s has type %s, changing it to %s requires recreating the Secret because the type is immutable; set %s=true to allow it",
+				ns, name, existing.Type, desired, allowRecreateAnnotation), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileSecretType(t *testing.T) {
+	tests := []struct {
+		name       string
+		secretType string
+		data       map[string]string
+		want       corev1.SecretType
+		wantErr    bool
+	}{
+		{
+			name:       "tls",
+			secretType: string(corev1.SecretTypeTLS),
+			data:       map[string]string{corev1.TLSCertKey: "cert", corev1.TLSPrivateKeyKey: "key"},
+			want:       corev1.SecretTypeTLS,
+		},
+		{
+			name:       "dockerconfigjson",
+			secretType: string(corev1.SecretTypeDockerConfigJson),
+			data:       map[string]string{corev1.DockerConfigJsonKey: `{"auths":{}}`},
+			want:       corev1.SecretTypeDockerConfigJson,
+		},
+		{
+			name: "default opaque",
+			data: map[string]string{"password": "s3cret"},
+			want: corev1.SecretTypeOpaque,
+		},
+		{
+			name:       "unknown type rejected",
+			secretType: string(corev1.SecretTypeServiceAccountToken),
+			data:       map[string]string{"token": "abc"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				if tt.secretType != "" {
+					cm.Annotations[secretTypeAnnotation] = tt.secretType
+				}
+				cm.Data = tt.data
+			})})
+			env.reconcile(t, "app")
+
+			if tt.wantErr {
+				if env.secretExists(t, testNamespace, "app-synced") {
+					t.Fatal("expected no Secret for an unknown type")
+				}
+				return
+			}
+			if got := env.secret(t, testNamespace, "app-synced").Type; got != tt.want {
+				t.Fatalf("Secret type = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileSecretTypeRecreate(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+		cm.Annotations[allowRecreateAnnotation] = "true"
+		cm.Data = map[string]string{corev1.TLSCertKey: "cert", corev1.TLSPrivateKeyKey: "key"}
+	})})
+	env.reconcile(t, "app")
+
+	// 类型不可修改，允许重建时删除旧 Secret 后重新创建
+	env.updateConfigMap(t, "app", func(cm *corev1.ConfigMap) {
+		cm.Annotations[secretTypeAnnotation] = string(corev1.SecretTypeTLS)
+	})
+	env.writes.reset()
+	env.reconcile(t, "app")
+
+	if got := env.secret(t, testNamespace, "app-synced").Type; got != corev1.SecretTypeTLS {
+		t.Fatalf("Secret type = %s, want %s", got, corev1.SecretTypeTLS)
+	}
+	if got := env.writes.get("delete/Secret"); got != 1 {
+		t.Fatalf("deleted the Secret %d times, want 1", got)
+	}
+	if !containsEvent(env.events(), "SecretRecreated", string(corev1.SecretTypeTLS)) {
+		t.Fatal("expected a SecretRecreated event")
+	}
+}
+
+func TestReconcileSecretTypeChangeRefused(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+		cm.Data = map[string]string{corev1.TLSCertKey: "cert", corev1.TLSPrivateKeyKey: "key"}
+	})})
+	env.reconcile(t, "app")
+
+	env.updateConfigMap(t, "app", func(cm *corev1.ConfigMap) {
+		cm.Annotations[secretTypeAnnotation] = string(corev1.SecretTypeTLS)
+	})
+	env.writes.reset()
+	env.reconcile(t, "app")
+
+	if got := env.secret(t, testNamespace, "app-synced").Type; got != corev1.SecretTypeOpaque {
+		t.Fatalf("Secret type = %s, want it left as %s", got, corev1.SecretTypeOpaque)
+	}
+	if got := env.writes.get("delete/Secret") + env.writes.get("apply/Secret"); got != 0 {
+		t.Fatalf("wrote the Secret %d times, want 0", got)
+	}
+	if !containsEvent(env.events(), "SecretTypeImmutable", allowRecreateAnnotation) {
+		t.Fatal("expected a SecretTypeImmutable event explaining how to allow the change")
+	}
+	if got := env.configMap(t, "app").Annotations[syncErrorAnnotation]; !strings.Contains(got, "immutable") {
+		t.Fatalf("%s = %q, want the immutability explained", syncErrorAnnotation, got)
+	}
+
+	// 拒绝原因不变时不重复记录事件
+	env.reconcile(t, "app")
+	if containsEvent(env.events(), "SecretTypeImmutable", "") {
+		t.Fatal("expected no repeated SecretTypeImmutable event")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// 注解：逗号分隔的同 namespace ConfigMap 名称，按顺序合并到 Secret 中，后面的来源覆盖前面的同名 key。
+// 附加来源同样需要带 managed-by 标签，否则不在缓存中
+const additionalSourcesAnnotation = "simple-controller/additional-sources"
+
+// additionalSourcesIndex 是按附加来源名称查找 ConfigMap 的字段索引
+const additionalSourcesIndex = ".metadata.annotations.additionalSources"
+
+// additionalSources 解析附加来源列表
+func additionalSources(cm *corev1.ConfigMap) ([]string, error) {
+	v, ok := cm.Annotations[additionalSourcesAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var names []string
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid %s: %q is not a valid ConfigMap name", additionalSourcesAnnotation, name)
+		}
+		if name == cm.Name {
+			return nil, fmt.Errorf("invalid %s: a ConfigMap cannot list itself", additionalSourcesAnnotation)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func indexAdditionalSources(obj client.Object) []string {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return nil
+	}
+	names, _ := additionalSources(cm)
+	return names
+}
+
+// mergedSource 返回合并了附加来源数据的 ConfigMap 副本，只用于计算 Secret 内容，不能写回 API Server。
+// 来源不存在时返回的 missing 非空
+func (r *ConfigMapReconciler) mergedSource(ctx context.Context, cm *corev1.ConfigMap) (merged *corev1.ConfigMap, missing string, err error) {
+	names, err := additionalSources(cm)
+	if err != nil || len(names) == 0 {
+		return cm, "", err
+	}
+
+	merged = cm.DeepCopy()
+	merged.Data = make(map[string]string, len(cm.Data))
+	for k, v := range cm.Data {
+		merged.Data[k] = v
+	}
+	for _, name := range names {
+		source := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: cm.Namespace, Name: name}, source); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, name, nil
+			}
+			return nil, "", err
+		}
+		for k, v := range source.Data {
+			merged.Data[k] = v
+		}
+	}
+	return merged, "", nil
+}
+
+// sourceToConfigMaps 附加来源变化时，调谐把它列为来源的 ConfigMap；同一个 ConfigMap 多次列出该来源时只入队一次
+func (r *ConfigMapReconciler) sourceToConfigMaps(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &corev1.ConfigMapList{}
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace()), client.MatchingFields{additionalSourcesIndex: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ConfigMaps for additional source", "configmap", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, cm := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cm)})
+	}
+	return dedupRequests(requests)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileAdditionalSources(t *testing.T) {
+	// 附加来源只提供数据，本身不同步
+	source := func(name string, data map[string]string) *corev1.ConfigMap {
+		return newConfigMap(name, func(cm *corev1.ConfigMap) {
+			delete(cm.Annotations, defaultSyncAnnotation)
+			cm.Data = data
+		})
+	}
+	base := map[string]string{"password": "s3cret", "level": "base"}
+	tests := []struct {
+		name        string
+		sources     string
+		wantData    map[string]string
+		wantMissing bool
+	}{
+		{
+			name:     "later sources override earlier ones",
+			sources:  "first, second",
+			wantData: map[string]string{"password": "s3cret", "level": "first", "region": "second", "zone": "first"},
+		},
+		{
+			name:     "order decides precedence",
+			sources:  "second,first",
+			wantData: map[string]string{"password": "s3cret", "level": "first", "region": "first", "zone": "first"},
+		},
+		{
+			name:        "missing source",
+			sources:     "first,absent",
+			wantMissing: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[additionalSourcesAnnotation] = tt.sources
+				cm.Data = base
+			})
+			env := newTestEnv(t, []client.Object{
+				cm,
+				source("first", map[string]string{"level": "first", "region": "first", "zone": "first"}),
+				source("second", map[string]string{"region": "second"}),
+			})
+			env.reconcile(t, "app")
+
+			if tt.wantMissing {
+				if env.secretExists(t, testNamespace, "app-synced") {
+					t.Fatal("expected no Secret while a source is missing")
+				}
+				if !containsEvent(env.events(), "SourceNotFound", "absent") {
+					t.Fatal("expected a SourceNotFound event")
+				}
+				return
+			}
+			got := map[string]string{}
+			for k, v := range env.secret(t, testNamespace, "app-synced").Data {
+				got[k] = string(v)
+			}
+			if !reflect.DeepEqual(got, tt.wantData) {
+				t.Fatalf("Secret data = %v, want %v", got, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestSourceToConfigMaps(t *testing.T) {
+	dependent := func(name, sources string) *corev1.ConfigMap {
+		return newConfigMap(name, func(cm *corev1.ConfigMap) {
+			cm.Annotations[additionalSourcesAnnotation] = sources
+		})
+	}
+	env := newTestEnv(t, []client.Object{
+		// 同一个来源列出多次
+		dependent("app", "base, first, base"),
+		dependent("web", "base"),
+		dependent("other", "first"),
+		newConfigMap("base"),
+	})
+
+	requests := env.r.sourceToConfigMaps(context.Background(), newConfigMap("base"))
+	var got []string
+	for _, req := range requests {
+		got = append(got, req.Name)
+	}
+	slices.Sort(got)
+	if want := []string{"app", "web"}; !slices.Equal(got, want) {
+		t.Fatalf("sourceToConfigMaps(base) = %v, want each dependent once: %v", got, want)
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const testNamespace = "default"
+
+func testScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+// writeCounter 按操作统计 fake client 上的写入次数，key 如 "apply/Secret"、"update/ConfigMap"
+type writeCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (w *writeCounter) add(op string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.counts == nil {
+		w.counts = map[string]int{}
+	}
+	w.counts[op]++
+}
+
+func (w *writeCounter) get(op string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.counts[op]
+}
+
+func (w *writeCounter) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.counts = nil
+}
+
+// testFuncs 统计写入次数，并用 emulateApply 模拟 fake client 不支持的 Server-Side Apply：
+// 对象不存在时创建，存在时整体替换为 Apply 声明的内容
+func testFuncs(w *writeCounter, scheme *runtime.Scheme) interceptor.Funcs {
+	kind := func(obj client.Object) string {
+		gvks, _, _ := scheme.ObjectKinds(obj)
+		if len(gvks) == 0 {
+			return "Unknown"
+		}
+		return gvks[0].Kind
+	}
+	return interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			w.add("create/" + kind(obj))
+			return c.Create(ctx, obj, opts...)
+		},
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			w.add("update/" + kind(obj))
+			return c.Update(ctx, obj, opts...)
+		},
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if patch.Type() != types.ApplyPatchType {
+				w.add("patch/" + kind(obj))
+				return c.Patch(ctx, obj, patch, opts...)
+			}
+			w.add("apply/" + kind(obj))
+			return emulateApply(ctx, c, obj)
+		},
+		Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+			w.add("delete/" + kind(obj))
+			return c.Delete(ctx, obj, opts...)
+		},
+	}
+}
+
+// emulateApply 用 Create/Update 模拟 Server-Side Apply。fake client 创建对象时不生成 UID，
+// 这里像 API Server 一样补上，控制器靠它区分创建和更新
+func emulateApply(ctx context.Context, c client.Client, obj client.Object) error {
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		obj.SetResourceVersion("")
+		obj.SetUID(uuid.NewUUID())
+		return c.Create(ctx, obj)
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	obj.SetUID(existing.GetUID())
+	obj.SetCreationTimestamp(existing.GetCreationTimestamp())
+	return c.Update(ctx, obj)
+}
+
+// chainFuncs 让 first 中设置的函数优先处理，没有设置的交给 next
+func chainFuncs(first, next interceptor.Funcs) interceptor.Funcs {
+	if first.Create == nil {
+		first.Create = next.Create
+	}
+	if first.Update == nil {
+		first.Update = next.Update
+	}
+	if first.Patch == nil {
+		first.Patch = next.Patch
+	}
+	if first.Delete == nil {
+		first.Delete = next.Delete
+	}
+	return first
+}
+
+// testEnv 是使用 fake client 的 ConfigMapReconciler 及其观察手段
+type testEnv struct {
+	r        *ConfigMapReconciler
+	c        client.WithWatch
+	recorder *record.FakeRecorder
+	writes   *writeCounter
+}
+
+// envConfig 是 newTestEnv 的可选配置
+type envConfig struct {
+	funcs     *interceptor.Funcs
+	configure []func(*ConfigMapReconciler)
+}
+
+type envOption func(*envConfig)
+
+// withInterceptor 注入 fake client 的行为（如返回错误），没有设置的操作照常执行并计数
+func withInterceptor(funcs interceptor.Funcs) envOption {
+	return func(c *envConfig) { c.funcs = &funcs }
+}
+
+// withReconciler 修改 Reconciler 的配置，如 SecretDeleteGrace
+func withReconciler(configure func(*ConfigMapReconciler)) envOption {
+	return func(c *envConfig) { c.configure = append(c.configure, configure) }
+}
+
+// newTestEnv 创建使用 fake client 的 Reconciler，默认包含 default namespace
+func newTestEnv(t *testing.T, objs []client.Object, opts ...envOption) *testEnv {
+	t.Helper()
+	cfg := &envConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	scheme := testScheme(t)
+	writes := &writeCounter{}
+	f := testFuncs(writes, scheme)
+	if cfg.funcs != nil {
+		f = chainFuncs(*cfg.funcs, f)
+	}
+	objs = append(objs, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNamespace}})
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithIndex(&corev1.ConfigMap{}, additionalSourcesIndex, indexAdditionalSources).
+		WithInterceptorFuncs(f).
+		Build()
+	recorder := record.NewFakeRecorder(100)
+	r := &ConfigMapReconciler{
+		Client:    cl,
+		Scheme:    scheme,
+		APIReader: cl,
+		Recorder:  recorder,
+	}
+	for _, configure := range cfg.configure {
+		configure(r)
+	}
+	return &testEnv{r: r, c: cl, recorder: recorder, writes: writes}
+}
+
+// newConfigMap 返回带 managed-by 标签和同步注解的 ConfigMap
+func newConfigMap(name string, mutate ...func(*corev1.ConfigMap)) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   testNamespace,
+			UID:         types.UID(name + "-uid"),
+			Labels:      map[string]string{managedByLabel: managedByValue},
+			Annotations: map[string]string{defaultSyncAnnotation: "true"},
+		},
+		Data: map[string]string{"password": "s3cret"},
+	}
+	for _, m := range mutate {
+		m(cm)
+	}
+	return cm
+}
+
+func newNamespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func requestFor(name string) ctrl.Request {
+	return ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}}
+}
+
+// reconcile 调谐一次并在出错时终止测试
+func (e *testEnv) reconcile(t *testing.T, name string) ctrl.Result {
+	t.Helper()
+	result, err := e.r.Reconcile(context.Background(), requestFor(name))
+	if err != nil {
+		t.Fatalf("reconcile %s: %v", name, err)
+	}
+	return result
+}
+
+func (e *testEnv) configMap(t *testing.T, name string) *corev1.ConfigMap {
+	t.Helper()
+	cm := &corev1.ConfigMap{}
+	if err := e.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: name}, cm); err != nil {
+		t.Fatalf("get ConfigMap %s: %v", name, err)
+	}
+	return cm
+}
+
+// updateConfigMap 修改 ConfigMap 并写回
+func (e *testEnv) updateConfigMap(t *testing.T, name string, mutate func(*corev1.ConfigMap)) {
+	t.Helper()
+	cm := e.configMap(t, name)
+	mutate(cm)
+	if err := e.c.Update(context.Background(), cm); err != nil {
+		t.Fatalf("update ConfigMap %s: %v", name, err)
+	}
+}
+
+// deleteConfigMap 删除 ConfigMap；带 finalizer 时 fake client 只设置 deletionTimestamp
+func (e *testEnv) deleteConfigMap(t *testing.T, name string) {
+	t.Helper()
+	if err := e.c.Delete(context.Background(), e.configMap(t, name)); err != nil {
+		t.Fatalf("delete ConfigMap %s: %v", name, err)
+	}
+}
+
+func (e *testEnv) secret(t *testing.T, namespace, name string) *corev1.Secret {
+	t.Helper()
+	secret := &corev1.Secret{}
+	if err := e.c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		t.Fatalf("get Secret %s/%s: %v", namespace, name, err)
+	}
+	return secret
+}
+
+// secretExists 返回 Secret 是否存在
+func (e *testEnv) secretExists(t *testing.T, namespace, name string) bool {
+	t.Helper()
+	err := e.c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, &corev1.Secret{})
+	if err != nil && !errors.IsNotFound(err) {
+		t.Fatal(err)
+	}
+	return err == nil
+}
+
+// events 取出 FakeRecorder 中已记录的全部事件
+func (e *testEnv) events() []string {
+	var events []string
+	for {
+		select {
+		case ev := <-e.recorder.Events:
+			events = append(events, ev)
+		default:
+			return events
+		}
+	}
+}
+
+// equalSorted 忽略顺序比较两个字符串切片
+func equalSorted(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// containsEvent 判断是否记录了原因为 reason 且消息包含 substr 的事件
+func containsEvent(events []string, reason, substr string) bool {
+	for _, ev := range events {
+		if strings.Contains(ev, " "+reason+" ") && strings.Contains(ev, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestReconcileRecordsSourceResourceVersion(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newConfigMap("app")})
+	steps := []struct {
+		name   string
+		mutate func(*corev1.ConfigMap)
+	}{
+		{name: "initial sync"},
+		{name: "data changed", mutate: func(cm *corev1.ConfigMap) { cm.Data["password"] = "rotated" }},
+		{name: "key added", mutate: func(cm *corev1.ConfigMap) { cm.Data["username"] = "admin" }},
+	}
+	for _, step := range steps {
+		if step.mutate != nil {
+			env.updateConfigMap(t, "app", step.mutate)
+		}
+		env.reconcile(t, "app")
+
+		want := env.configMap(t, "app").ResourceVersion
+		if got := env.secret(t, testNamespace, "app-synced").Annotations[sourceResourceVersionAnnotation]; got != want {
+			t.Fatalf("%s: %s = %q, want %q", step.name, sourceResourceVersionAnnotation, got, want)
+		}
+	}
+}
+
+func TestReconcileSkipsControllerManagedConfigMaps(t *testing.T) {
+	tests := []struct {
+		name       string
+		labels     map[string]string
+		wantSecret bool
+	}{
+		{"user ConfigMap", nil, true},
+		{"mirror with source label", map[string]string{sourceLabel: "origin"}, false},
+		{"mirror with source namespace label", map[string]string{sourceNamespaceLabel: "other"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				for k, v := range tt.labels {
+					cm.Labels[k] = v
+				}
+			})})
+			env.reconcile(t, "app")
+			if got := env.secretExists(t, testNamespace, "app-synced"); got != tt.wantSecret {
+				t.Fatalf("Secret exists = %v, want %v", got, tt.wantSecret)
+			}
+		})
+	}
+}
+
+// conflictingApply 模拟另一个字段管理者持有 Secret 的字段：不强制接管的 Apply 返回冲突
+func conflictingApply() interceptor.Funcs {
+	return interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if patch.Type() != types.ApplyPatchType {
+				return c.Patch(ctx, obj, patch, opts...)
+			}
+			po := &client.PatchOptions{}
+			po.ApplyOptions(opts)
+			if po.Force == nil || !*po.Force {
+				return apierrors.NewConflict(schema.GroupResource{Resource: "secrets"}, obj.GetName(),
+					errors.New(`Apply failed with 1 conflict: conflict with "other-operator": .data.password`))
+			}
+			return emulateApply(ctx, c, obj)
+		},
+	}
+}
+
+func TestReconcileFieldManagerConflict(t *testing.T) {
+	tests := []struct {
+		name       string
+		forceApply bool
+		wantSecret bool
+		wantEvent  bool
+	}{
+		{"conflict skipped by default", false, false, true},
+		{"conflict taken over with -force-apply", true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app")},
+				withInterceptor(conflictingApply()),
+				withReconciler(func(r *ConfigMapReconciler) { r.ForceApply = tt.forceApply }))
+			env.reconcile(t, "app")
+
+			if got := env.secretExists(t, testNamespace, "app-synced"); got != tt.wantSecret {
+				t.Fatalf("Secret exists = %v, want %v", got, tt.wantSecret)
+			}
+			if got := containsEvent(env.events(), "ApplyConflict", "other-operator"); got != tt.wantEvent {
+				t.Fatalf("ApplyConflict event = %v, want %v", got, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestReconcileEmptySyncAnnotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		wantHint bool
+	}{
+		{name: "empty value syncs and recommends true", value: "", wantHint: true},
+		{name: "true syncs without a hint", value: "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[defaultSyncAnnotation] = tt.value
+			})})
+			var logs []string
+			logger := funcr.New(func(prefix, args string) {
+				logs = append(logs, args)
+			}, funcr.Options{Verbosity: 1})
+			if _, err := env.r.Reconcile(log.IntoContext(context.Background(), logger), requestFor("app")); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := env.secret(t, testNamespace, "app-synced"); string(got.Data["password"]) != "s3cret" {
+				t.Fatalf("Secret data = %v, want the ConfigMap data", got.Data)
+			}
+			hinted := slices.ContainsFunc(logs, func(l string) bool {
+				return strings.Contains(l, `set it to \"true\"`)
+			})
+			if hinted != tt.wantHint {
+				t.Fatalf("recommendation logged = %v, want %v; logs: %v", hinted, tt.wantHint, logs)
+			}
+		})
+	}
+}
+
+func TestReconcileManageSince(t *testing.T) {
+	cutoff := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name        string
+		manageSince time.Time
+		created     time.Time
+		wantSecret  bool
+	}{
+		{name: "created before the cutoff", manageSince: cutoff, created: cutoff.Add(-time.Hour)},
+		{name: "created at the cutoff", manageSince: cutoff, created: cutoff, wantSecret: true},
+		{name: "created after the cutoff", manageSince: cutoff, created: cutoff.Add(time.Hour), wantSecret: true},
+		{name: "no cutoff", created: cutoff.Add(-time.Hour), wantSecret: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.CreationTimestamp = metav1.NewTime(tt.created)
+			})
+			env := newTestEnv(t, []client.Object{cm}, withReconciler(func(r *ConfigMapReconciler) {
+				r.ManageSince = tt.manageSince
+			}))
+			env.reconcile(t, "app")
+
+			if got := env.secretExists(t, testNamespace, "app-synced"); got != tt.wantSecret {
+				t.Fatalf("Secret exists = %v, want %v", got, tt.wantSecret)
+			}
+		})
+	}
+}
+
+func TestReconcileVersionLabel(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+	}{
+		{name: "default build", version: "dev"},
+		{name: "release build", version: "v1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 模拟构建时通过 -ldflags 注入的版本
+			old := version
+			version = tt.version
+			t.Cleanup(func() { version = old })
+
+			env := newTestEnv(t, []client.Object{newConfigMap("app")})
+			env.reconcile(t, "app")
+
+			if got := env.secret(t, testNamespace, "app-synced").Labels[versionLabel]; got != tt.version {
+				t.Fatalf("%s = %q, want %q", versionLabel, got, tt.version)
+			}
+		})
+	}
+}
+
+func TestReconcileCustomSyncAnnotation(t *testing.T) {
+	const custom = "example.com/sync-to-secret"
+	tests := []struct {
+		name           string
+		syncAnnotation string
+		annotation     string
+		wantSecret     bool
+	}{
+		{name: "custom annotation present", syncAnnotation: custom, annotation: custom, wantSecret: true},
+		{name: "default annotation ignored when customized", syncAnnotation: custom, annotation: defaultSyncAnnotation},
+		{name: "default annotation when not customized", annotation: defaultSyncAnnotation, wantSecret: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations = map[string]string{tt.annotation: "true"}
+			})
+			env := newTestEnv(t, []client.Object{cm}, withReconciler(func(r *ConfigMapReconciler) {
+				r.SyncAnnotation = tt.syncAnnotation
+			}))
+			env.reconcile(t, "app")
+
+			if got := env.secretExists(t, testNamespace, "app-synced"); got != tt.wantSecret {
+				t.Fatalf("Secret exists = %v, want %v", got, tt.wantSecret)
+			}
+		})
+	}
+}
+
+func TestReconcileRevertsDrift(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*corev1.Secret)
+	}{
+		{name: "value edited", mutate: func(s *corev1.Secret) { s.Data["password"] = []byte("hacked") }},
+		{name: "key added", mutate: func(s *corev1.Secret) { s.Data["extra"] = []byte("x") }},
+		{name: "key removed", mutate: func(s *corev1.Secret) { delete(s.Data, "password") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app")})
+			env.reconcile(t, "app")
+
+			// 模拟 kubectl edit 直接修改 Secret
+			secret := env.secret(t, testNamespace, "app-synced")
+			tt.mutate(secret)
+			if err := env.c.Update(context.Background(), secret); err != nil {
+				t.Fatal(err)
+			}
+			env.reconcile(t, "app")
+
+			got := env.secret(t, testNamespace, "app-synced").Data
+			if len(got) != 1 || string(got["password"]) != "s3cret" {
+				t.Fatalf("Secret data = %v, want it restored from the ConfigMap", got)
+			}
+			if !containsEvent(env.events(), "DriftReverted", "app-synced") {
+				t.Fatal("expected a DriftReverted event")
+			}
+
+			// 恢复后再次调谐不应再写入
+			env.writes.reset()
+			env.reconcile(t, "app")
+			if got := env.writes.get("apply/Secret"); got != 0 {
+				t.Fatalf("applied the Secret %d times after the drift was reverted, want 0", got)
+			}
+		})
+	}
+}
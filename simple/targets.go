@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// 注解：Namespace 标签选择器，Secret 会被同步到所有匹配的 namespace 中
+const targetNamespaceSelectorAnnotation = "simple-controller/target-namespace-selector"
+
+// 注解：逗号分隔的 namespace 列表，Secret 会被同步到列出的每个 namespace 中，可以与 target-namespace-selector 同时使用
+const targetNamespacesAnnotation = "simple-controller/target-namespaces"
+
+// 注解：设置为 true 时，跨 namespace 同步的目标 namespace 不存在则先创建它（带 managed-by 标签）。
+// 控制器从不删除 namespace，清理时只删除 Secret
+const createNamespaceAnnotation = "simple-controller/create-namespace"
+
+// ensureNamespace 在 namespace 不存在时创建它
+func (r *ConfigMapReconciler) ensureNamespace(ctx context.Context, name string) error {
+	ns := &corev1.Namespace{}
+	err := r.Get(ctx, types.NamespacedName{Name: name}, ns)
+	if !errors.IsNotFound(err) {
+		return err
+	}
+	ns = &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{managedByLabel: managedByValue},
+		},
+	}
+	if err := r.Create(ctx, ns); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	log.FromContext(ctx).Info("Created target namespace", "namespace", name)
+	return nil
+}
+
+// targetNamespaceSelector 解析 target-namespace-selector 注解，未设置时返回 nil
+func targetNamespaceSelector(cm *corev1.ConfigMap) (labels.Selector, error) {
+	raw, ok := cm.Annotations[targetNamespaceSelectorAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	sel, err := labels.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", targetNamespaceSelectorAnnotation, raw, err)
+	}
+	return sel, nil
+}
+
+// targetNamespaceList 解析 target-namespaces 注解，未设置时返回 nil
+func targetNamespaceList(cm *corev1.ConfigMap) ([]string, error) {
+	raw, ok := cm.Annotations[targetNamespacesAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var namespaces []string
+	for _, ns := range strings.Split(raw, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid %s %q: namespace %q: %s", targetNamespacesAnnotation, raw, ns, strings.Join(errs, "; "))
+		}
+		namespaces = append(namespaces, ns)
+	}
+	if len(namespaces) == 0 {
+		return nil, fmt.Errorf("invalid %s %q: no namespaces listed", targetNamespacesAnnotation, raw)
+	}
+	return namespaces, nil
+}
+
+// targetNamespaces 返回 Secret 需要写入的 namespace 列表（已排序）：target-namespaces 列出的
+// 和 target-namespace-selector 匹配的 namespace 的并集，都没有设置时只有 ConfigMap 所在的 namespace
+func (r *ConfigMapReconciler) targetNamespaces(ctx context.Context, cm *corev1.ConfigMap) ([]string, error) {
+	sel, err := targetNamespaceSelector(cm)
+	if err != nil {
+		return nil, err
+	}
+	listed, err := targetNamespaceList(cm)
+	if err != nil {
+		return nil, err
+	}
+	if sel == nil && listed == nil {
+		return []string{cm.Namespace}, nil
+	}
+
+	var targets []string
+	for _, name := range listed {
+		// 不存在的 namespace 只有开启 create-namespace 时才写入，否则等它被创建后再同步
+		ns := &corev1.Namespace{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name}, ns); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, err
+			}
+			if cm.Annotations[createNamespaceAnnotation] != "true" {
+				log.FromContext(ctx).V(1).Info("Target namespace does not exist, skipping", "namespace", name)
+				continue
+			}
+		} else if !ns.DeletionTimestamp.IsZero() {
+			continue
+		}
+		targets = append(targets, name)
+	}
+	if sel == nil {
+		slices.Sort(targets)
+		return slices.Compact(targets), nil
+	}
+
+	list := &corev1.NamespaceList{}
+	if err := r.List(ctx, list, client.MatchingLabelsSelector{Selector: sel}); err != nil {
+		return nil, err
+	}
+	for _, ns := range list.Items {
+		// 正在删除的 namespace 中无法创建对象
+		if !ns.DeletionTimestamp.IsZero() {
+			continue
+		}
+		targets = append(targets, ns.Name)
+	}
+	slices.Sort(targets)
+	return slices.Compact(targets), nil
+}
+
+// fanoutBudgetExceeded 检查跨 namespace 同步写入的总数据量（单个 Secret 的数据大小乘以目标数量）是否超过 MaxFanoutBytes，
+// 超过时返回拒绝原因。只写入 ConfigMap 所在 namespace 时不检查，单个 Secret 的大小由 API Server 限制
+func (r *ConfigMapReconciler) fanoutBudgetExceeded(source *corev1.ConfigMap, targets []string) string {
+	if r.MaxFanoutBytes <= 0 || (len(targets) == 1 && targets[0] == source.Namespace) {
+		return ""
+	}
+	var size int64
+	for _, v := range secretData(source, contentHash(source)) {
+		size += int64(len(v))
+	}
+	if total := size * int64(len(targets)); total > r.MaxFanoutBytes {
+		return fmt.Sprintf("fan-out of %d bytes to %d namespaces (%d bytes in total) exceeds the budget of %d bytes",
+			size, len(targets), total, r.MaxFanoutBytes)
+	}
+	return ""
+}
+
+// defaultFanoutConcurrency 是没有设置 FanoutConcurrency 时同时写入的目标 namespace 数量
+const defaultFanoutConcurrency = 10
+
+// syncTargets 并发地在每个目标 namespace 中同步 Secret，并发数受 FanoutConcurrency 限制。
+// 一个 namespace 失败不影响其他 namespace，所有失败合并为一个错误返回，调谐会重试
+func (r *ConfigMapReconciler) syncTargets(ctx context.Context, source *corev1.ConfigMap, targets []string, mode string) error {
+	limit := r.FanoutConcurrency
+	if limit <= 0 {
+		limit = defaultFanoutConcurrency
+	}
+	var (
+		g    errgroup.Group
+		mu   sync.Mutex
+		errs []error
+	)
+	g.SetLimit(limit)
+	// go.mod 声明的是 go 1.21，循环变量在迭代间共享，通过参数绑定每个任务的 namespace
+	syncTarget := func(ns string) func() error {
+		return func() error {
+			if err := r.syncSecret(ctx, source, ns, mode); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("namespace %s: %w", ns, err))
+				mu.Unlock()
+			}
+			return nil
+		}
+	}
+	for _, ns := range targets {
+		g.Go(syncTarget(ns))
+	}
+	_ = g.Wait()
+	return utilerrors.NewAggregate(errs)
+}
+
+// secretToConfigMap 通过标签把 Secret 映射回源 ConfigMap
+func secretToConfigMap(_ context.Context, obj client.Object) []reconcile.Request {
+	l := obj.GetLabels()
+	name, ok := l[sourceLabel]
+	if !ok {
+		return nil
+	}
+	namespace := l[sourceNamespaceLabel]
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}}
+}
+
+// secretChangePredicate 过滤控制器自己写入 Secret 产生的更新事件：
+// 同步一次会触发 Secret 的创建或更新事件，如果再映射回来源 ConfigMap，同一次变化会被调谐两次。
+// 只有 Secret 被删除，或内容被其他人改得与 content-hash 不一致时才需要调谐来源
+var secretChangePredicate = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		s, ok := e.Object.(*corev1.Secret)
+		if !ok {
+			return true
+		}
+		return !secretMatchesHash(s)
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		s, ok := e.ObjectNew.(*corev1.Secret)
+		if !ok {
+			return true
+		}
+		return !secretMatchesHash(s)
+	},
+}
+
+// dedupRequests 去掉重复的请求并保持顺序，避免同一个事件让同一个对象入队多次
+func dedupRequests(requests []reconcile.Request) []reconcile.Request {
+	seen := make(map[reconcile.Request]struct{}, len(requests))
+	out := requests[:0]
+	for _, req := range requests {
+		if _, ok := seen[req]; ok {
+			continue
+		}
+		seen[req] = struct{}{}
+		out = append(out, req)
+	}
+	return out
+}
+
+// namespaceToConfigMaps 找出 target-namespace-selector 匹配该 Namespace 的所有 ConfigMap
+func (r *ConfigMapReconciler) namespaceToConfigMaps(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	list := &corev1.ConfigMapList{}
+	if err := r.List(ctx, list); err != nil {
+		logger.Error(err, "Failed to list ConfigMaps for Namespace event", "namespace", obj.GetName())
+		return nil
+	}
+
+	nsLabels := labels.Set(obj.GetLabels())
+	var requests []reconcile.Request
+	for i := range list.Items {
+		cm := &list.Items[i]
+		if listed, err := targetNamespaceList(cm); err == nil && slices.Contains(listed, obj.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(cm)})
+			continue
+		}
+		sel, err := targetNamespaceSelector(cm)
+		if err != nil || sel == nil || !sel.Matches(nsLabels) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(cm)})
+	}
+	return requests
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileTargetNamespaceSelector(t *testing.T) {
+	namespaces := []client.Object{
+		newNamespace("team-a", map[string]string{"sync": "yes"}),
+		newNamespace("team-b", map[string]string{"sync": "yes"}),
+		newNamespace("other", map[string]string{"sync": "no"}),
+	}
+	tests := []struct {
+		name     string
+		selector string
+		want     []string
+		wantNot  []string
+	}{
+		{"two matching namespaces", "sync=yes", []string{"team-a", "team-b"}, []string{"other", testNamespace}},
+		{"single match", "sync=no", []string{"other"}, []string{"team-a", "team-b"}},
+		{"no match", "sync=maybe", nil, []string{"team-a", "team-b", "other"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[targetNamespaceSelectorAnnotation] = tt.selector
+			})
+			env := newTestEnv(t, append([]client.Object{cm}, namespaces...))
+			env.reconcile(t, "app")
+
+			for _, ns := range tt.want {
+				if got := env.secret(t, ns, "app-synced"); string(got.Data["password"]) != "s3cret" {
+					t.Errorf("Secret in %s has data %v", ns, got.Data)
+				}
+			}
+			for _, ns := range tt.wantNot {
+				if env.secretExists(t, ns, "app-synced") {
+					t.Errorf("unexpected Secret in namespace %s", ns)
+				}
+			}
+		})
+	}
+}
+
+func TestSecretChangePredicate(t *testing.T) {
+	env := newTestEnv(t, []client.Object{newConfigMap("app")})
+	env.reconcile(t, "app")
+	own := env.secret(t, testNamespace, "app-synced")
+
+	tampered := own.DeepCopy()
+	tampered.Data["password"] = []byte("changed by hand")
+	// 手工修改会产生新的 resourceVersion，解码缓存按版本区分
+	tampered.ResourceVersion += "1"
+	unmanaged := own.DeepCopy()
+	delete(unmanaged.Annotations, contentHashAnnotation)
+
+	tests := []struct {
+		name   string
+		secret *corev1.Secret
+		want   bool
+	}{
+		{"controller's own write", own, false},
+		{"edited outside the controller", tampered, true},
+		{"missing content hash", unmanaged, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := secretChangePredicate.Create(event.CreateEvent{Object: tt.secret}); got != tt.want {
+				t.Errorf("Create = %v, want %v", got, tt.want)
+			}
+			if got := secretChangePredicate.Update(event.UpdateEvent{ObjectOld: own, ObjectNew: tt.secret}); got != tt.want {
+				t.Errorf("Update = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateEventsCollapseToOneReconcile(t *testing.T) {
+	cm := newConfigMap("app", func(cm *corev1.ConfigMap) {
+		cm.Annotations[targetNamespaceSelectorAnnotation] = "sync=yes"
+	})
+	env := newTestEnv(t, []client.Object{
+		cm,
+		newNamespace("team-a", map[string]string{"sync": "yes"}),
+		newNamespace("team-b", map[string]string{"sync": "yes"}),
+	})
+	env.reconcile(t, "app")
+
+	// 同一个来源的多个 Secret 副本以及 Namespace 的变化都映射回同一个 ConfigMap
+	var requests []reconcile.Request
+	for _, ns := range []string{"team-a", "team-b"} {
+		requests = append(requests, secretToConfigMap(context.Background(), env.secret(t, ns, "app-synced"))...)
+	}
+	requests = append(requests, env.r.namespaceToConfigMaps(context.Background(), newNamespace("team-a", map[string]string{"sync": "yes"}))...)
+	if len(requests) < 3 {
+		t.Fatalf("expected every event to map to the ConfigMap, got %v", requests)
+	}
+
+	queue := workqueue.New()
+	defer queue.ShutDown()
+	for _, req := range requests {
+		queue.Add(req)
+	}
+	if n := queue.Len(); n != 1 {
+		t.Fatalf("queue length = %d, want 1", n)
+	}
+}
+
+func TestDedupRequests(t *testing.T) {
+	a := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "a"}}
+	b := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "b"}}
+	tests := []struct {
+		name string
+		in   []reconcile.Request
+		want []reconcile.Request
+	}{
+		{"empty", nil, nil},
+		{"no duplicates", []reconcile.Request{a, b}, []reconcile.Request{a, b}},
+		{"duplicates keep first order", []reconcile.Request{b, a, b, a}, []reconcile.Request{b, a}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dedupRequests(tt.in)
+			if len(got) != len(tt.want) {
+				t.Fatalf("dedupRequests = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("dedupRequests = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileCreateNamespace(t *testing.T) {
+	tests := []struct {
+		name          string
+		create        string
+		wantNamespace bool
+	}{
+		{name: "create-namespace enabled", create: "true", wantNamespace: true},
+		{name: "create-namespace disabled", create: "false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[targetNamespacesAnnotation] = "team-new"
+				cm.Annotations[createNamespaceAnnotation] = tt.create
+			})
+			// 按顺序记录写入，确认先创建 namespace 再写 Secret
+			var order []string
+			env := newTestEnv(t, []client.Object{cm}, withInterceptor(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					if _, ok := obj.(*corev1.Namespace); ok {
+						order = append(order, "namespace")
+					}
+					return c.Create(ctx, obj, opts...)
+				},
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					if _, ok := obj.(*corev1.Secret); ok && patch.Type() == types.ApplyPatchType {
+						order = append(order, "secret")
+						return emulateApply(ctx, c, obj)
+					}
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+			}))
+			env.reconcile(t, "app")
+
+			if !tt.wantNamespace {
+				if len(order) != 0 {
+					t.Fatalf("writes = %v, want none", order)
+				}
+				return
+			}
+			if want := []string{"namespace", "secret"}; !slices.Equal(order, want) {
+				t.Fatalf("writes = %v, want %v", order, want)
+			}
+			ns := &corev1.Namespace{}
+			if err := env.c.Get(context.Background(), types.NamespacedName{Name: "team-new"}, ns); err != nil {
+				t.Fatal(err)
+			}
+			if ns.Labels[managedByLabel] != managedByValue {
+				t.Fatalf("namespace labels = %v, want %s=%s", ns.Labels, managedByLabel, managedByValue)
+			}
+			if got := env.secret(t, "team-new", "app-synced"); string(got.Data["password"]) != "s3cret" {
+				t.Fatalf("Secret in team-new has data %v", got.Data)
+			}
+		})
+	}
+}
+
+func TestEnsureNamespace(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   *corev1.Namespace
+		wantLabels map[string]string
+	}{
+		{name: "missing namespace created", wantLabels: map[string]string{managedByLabel: managedByValue}},
+		{name: "existing namespace untouched", existing: newNamespace("team-new", map[string]string{"team": "new"}), wantLabels: map[string]string{"team": "new"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []client.Object
+			if tt.existing != nil {
+				objs = append(objs, tt.existing)
+			}
+			env := newTestEnv(t, objs)
+			if err := env.r.ensureNamespace(context.Background(), "team-new"); err != nil {
+				t.Fatal(err)
+			}
+
+			ns := &corev1.Namespace{}
+			if err := env.c.Get(context.Background(), types.NamespacedName{Name: "team-new"}, ns); err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(ns.Labels, tt.wantLabels) {
+				t.Fatalf("namespace labels = %v, want %v", ns.Labels, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestReconcileTargetNamespacesFanOut(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets string
+		want    []string
+	}{
+		{name: "two other namespaces", targets: "team-a,team-b", want: []string{"team-a", "team-b"}},
+		{name: "including the source namespace", targets: testNamespace + ", team-a", want: []string{testNamespace, "team-a"}},
+		{name: "missing namespace skipped", targets: "team-a,absent", want: []string{"team-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[targetNamespacesAnnotation] = tt.targets
+			})
+			env := newTestEnv(t, []client.Object{cm, newNamespace("team-a", nil), newNamespace("team-b", nil)})
+			env.reconcile(t, "app")
+
+			for _, ns := range tt.want {
+				secret := env.secret(t, ns, "app-synced")
+				if string(secret.Data["password"]) != "s3cret" {
+					t.Fatalf("Secret in %s has data %v", ns, secret.Data)
+				}
+				// OwnerReference 不能跨 namespace，其他 namespace 中的副本靠标签清理
+				if ns != testNamespace && len(secret.OwnerReferences) != 0 {
+					t.Fatalf("Secret in %s has owner references %v", ns, secret.OwnerReferences)
+				}
+			}
+			if !slices.Contains(tt.want, testNamespace) && env.secretExists(t, testNamespace, "app-synced") {
+				t.Fatal("unexpected Secret in the source namespace")
+			}
+
+			env.deleteConfigMap(t, "app")
+			env.reconcile(t, "app")
+			for _, ns := range tt.want {
+				if env.secretExists(t, ns, "app-synced") {
+					t.Fatalf("expected the Secret in %s to be cleaned up", ns)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileFanOutPartialFailure(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+	}{
+		{name: "serial", concurrency: 1},
+		{name: "default concurrency"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[targetNamespacesAnnotation] = "team-a,team-b,team-c,team-d"
+			})
+			objs := []client.Object{cm}
+			for _, ns := range []string{"team-a", "team-b", "team-c", "team-d"} {
+				objs = append(objs, newNamespace(ns, nil))
+			}
+			env := newTestEnv(t, objs,
+				withInterceptor(interceptor.Funcs{
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+						if patch.Type() != types.ApplyPatchType {
+							return c.Patch(ctx, obj, patch, opts...)
+						}
+						if obj.GetNamespace() == "team-c" {
+							return errors.New("admission webhook denied the request")
+						}
+						return emulateApply(ctx, c, obj)
+					},
+				}),
+				withReconciler(func(r *ConfigMapReconciler) { r.FanoutConcurrency = tt.concurrency }))
+
+			// 返回错误让调谐按退避重试
+			_, err := env.r.Reconcile(context.Background(), requestFor("app"))
+			if err == nil || !strings.Contains(err.Error(), "namespace team-c") {
+				t.Fatalf("Reconcile error = %v, want the team-c failure", err)
+			}
+			for _, ns := range []string{"team-a", "team-b", "team-d"} {
+				if !env.secretExists(t, ns, "app-synced") {
+					t.Fatalf("expected the Secret in %s despite the team-c failure", ns)
+				}
+			}
+			if env.secretExists(t, "team-c", "app-synced") {
+				t.Fatal("unexpected Secret in team-c")
+			}
+		})
+	}
+}
+
+func TestReconcileFanoutBudget(t *testing.T) {
+	tests := []struct {
+		name       string
+		targets    string
+		budget     int64
+		wantSecret bool
+	}{
+		// 数据 "s3cret" 是 6 字节，两个目标共 12 字节
+		{name: "over the budget", targets: "team-a,team-b", budget: 11},
+		{name: "exactly the budget", targets: "team-a,team-b", budget: 12, wantSecret: true},
+		{name: "source namespace only is not checked", targets: testNamespace, budget: 1, wantSecret: true},
+		{name: "no budget", targets: "team-a,team-b", wantSecret: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[targetNamespacesAnnotation] = tt.targets
+			})
+			env := newTestEnv(t, []client.Object{cm, newNamespace("team-a", nil), newNamespace("team-b", nil)},
+				withReconciler(func(r *ConfigMapReconciler) { r.MaxFanoutBytes = tt.budget }))
+			env.reconcile(t, "app")
+
+			for _, ns := range strings.Split(tt.targets, ",") {
+				if got := env.secretExists(t, ns, "app-synced"); got != tt.wantSecret {
+					t.Fatalf("Secret in %s exists = %v, want %v", ns, got, tt.wantSecret)
+				}
+			}
+			if refused := containsEvent(env.events(), "FanoutTooLarge", "exceeds the budget"); refused == tt.wantSecret {
+				t.Fatalf("FanoutTooLarge event recorded = %v, want %v", refused, !tt.wantSecret)
+			}
+		})
+	}
+}
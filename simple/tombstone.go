@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// tombstone 记录一个因 ConfigMap 删除而被删除的 Secret，用于审计配置是何时被移除的
+type tombstone struct {
+	Namespace   string    `json:"namespace"`
+	Name        string    `json:"name"`
+	Source      string    `json:"source"`
+	ContentHash string    `json:"contentHash,omitempty"`
+	DeletedAt   time.Time `json:"deletedAt"`
+}
+
+// deleteSyncedSecret 删除 ConfigMap 对应的 Secret，删除成功时记录墓碑。
+// 墓碑总会写入日志，配置了 Tombstones 时还会追加到墓碑 ConfigMap；写入失败不影响删除
+func (r *ConfigMapReconciler) deleteSyncedSecret(ctx context.Context, secret *corev1.Secret, source string) error {
+	if err := r.Delete(ctx, secret); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	r.countSync(syncResultDeleted)
+
+	logger := log.FromContext(ctx)
+	t := tombstone{
+		Namespace:   secret.Namespace,
+		Name:        secret.Name,
+		Source:      source,
+		ContentHash: secret.Annotations[contentHashAnnotation],
+		DeletedAt:   time.Now().UTC(),
+	}
+	logger.Info("Secret deleted after ConfigMap deletion", "tombstone", t)
+	if r.Tombstones != nil {
+		if err := r.Tombstones.Append(ctx, t); err != nil {
+			logger.Error(err, "Failed to record tombstone", "configmap", r.Tombstones.Name)
+		}
+	}
+	return nil
+}
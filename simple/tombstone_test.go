@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileRecordsTombstone(t *testing.T) {
+	tests := []struct {
+		name       string
+		store      bool
+		wantRecord bool
+	}{
+		{name: "recorded in the tombstone ConfigMap", store: true, wantRecord: true},
+		{name: "log only without a store", store: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, []client.Object{newConfigMap("app")}, withReconciler(func(r *ConfigMapReconciler) {
+				if tt.store {
+					r.Tombstones = &recordStore{Client: r.Client, Namespace: testNamespace, Name: "tombstones", MaxEntries: 10}
+				}
+			}))
+			env.reconcile(t, "app")
+			hash := env.secret(t, testNamespace, "app-synced").Annotations[contentHashAnnotation]
+			env.deleteConfigMap(t, "app")
+			start := time.Now().UTC()
+			env.reconcile(t, "app")
+
+			if env.secretExists(t, testNamespace, "app-synced") {
+				t.Fatal("expected the Secret to be deleted")
+			}
+			if !tt.wantRecord {
+				return
+			}
+			// 兜底按名称删除的 Secret 已不存在，只应记录一条
+			records := env.configMap(t, "tombstones").Data
+			if len(records) != 1 {
+				t.Fatalf("got %d tombstones, want 1: %v", len(records), records)
+			}
+			for _, value := range records {
+				var got tombstone
+				if err := json.Unmarshal([]byte(value), &got); err != nil {
+					t.Fatal(err)
+				}
+				if got.Namespace != testNamespace || got.Name != "app-synced" || got.Source != testNamespace+"/app" {
+					t.Fatalf("tombstone = %+v, want the deleted Secret and its source", got)
+				}
+				if got.ContentHash != hash {
+					t.Fatalf("contentHash = %q, want %q", got.ContentHash, hash)
+				}
+				if got.DeletedAt.Before(start.Add(-time.Second)) {
+					t.Fatalf("deletedAt = %v, want after %v", got.DeletedAt, start)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// 注解：同步前运行的校验 Job 模板，值为同 namespace 下一个 suspend=true 的 Job 名称。
+// 控制器按 ConfigMap 内容为每个版本复制一次模板并运行，成功后才写入 Secret
+const validationJobAnnotation = "simple-controller/validation-job"
+
+// validationPollInterval 是等待校验 Job 时的重新调谐间隔，Job 状态变化也会通过 Owns 触发调谐
+const validationPollInterval = 30 * time.Second
+
+// 注解：校验 Job 对应的 ConfigMap 内容 hash
+const validationHashAnnotation = "simple-controller/validation-hash"
+
+// validationResult 是校验 Job 的状态
+type validationResult int
+
+const (
+	validationRunning validationResult = iota
+	validationSucceeded
+	validationFailed
+)
+
+// validationJobName 返回当前内容对应的校验 Job 名称，内容不变时名称不变，Job 只运行一次
+func validationJobName(cm *corev1.ConfigMap, hash string) string {
+	name := cm.Name
+	if len(name) > 63-len("-validate-")-8 {
+		name = name[:63-len("-validate-")-8]
+	}
+	return name + "-validate-" + hash[:8]
+}
+
+// runValidationJob 确保当前内容的校验 Job 存在并返回它的状态，同时删除旧内容的校验 Job
+func (r *ConfigMapReconciler) runValidationJob(ctx context.Context, cm *corev1.ConfigMap) (validationResult, string, error) {
+	logger := log.FromContext(ctx)
+	hash := contentHash(cm)
+	name := validationJobName(cm, hash)
+
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: cm.Namespace, Name: name}, job)
+	if errors.IsNotFound(err) {
+		job, err = r.newValidationJob(ctx, cm, name, hash)
+		if err != nil {
+			return validationFailed, err.Error(), nil
+		}
+		if err := r.Create(ctx, job); err != nil && !errors.IsAlreadyExists(err) {
+			return validationRunning, "", err
+		}
+		// dry-run 时 Job 不会真正创建，等待只会一直轮询，视为已通过以便记录后续的写操作
+		if r.DryRun {
+			logger.Info("Dry run, treating validation Job as succeeded", "job", name, "configmap", cm.Name)
+			return validationSucceeded, "", nil
+		}
+		logger.Info("Validation Job created", "job", name, "configmap", cm.Name)
+		if err := r.deleteStaleValidationJobs(ctx, cm, name); err != nil {
+			return validationRunning, "", err
+		}
+		return validationRunning, "", nil
+	}
+	if err != nil {
+		return validationRunning, "", err
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return validationSucceeded, "", nil
+		case batchv1.JobFailed:
+			return validationFailed, fmt.Sprintf("validation Job %s failed: %s", name, cond.Message), nil
+		}
+	}
+	return validationRunning, "", nil
+}
+
+// newValidationJob 从模板 Job 复制出校验 Job。模板由用户创建，不带 managed-by 标签，不在缓存中，需要直接读取
+func (r *ConfigMapReconciler) newValidationJob(ctx context.Context, cm *corev1.ConfigMap, name, hash string) (*batchv1.Job, error) {
+	templateName := cm.Annotations[validationJobAnnotation]
+	template := &batchv1.Job{}
+	if err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: cm.Namespace, Name: templateName}, template); err != nil {
+		return nil, fmt.Errorf("get validation Job template %s: %w", templateName, err)
+	}
+	if !ptr.Deref(template.Spec.Suspend, false) {
+		return nil, fmt.Errorf("validation Job template %s must set spec.suspend=true so it does not run on its own", templateName)
+	}
+
+	spec := *template.Spec.DeepCopy()
+	spec.Suspend = nil
+	// 模板的 selector 和 Pod 标签由 API Server 生成，复制后会与新 Job 冲突，交给 API Server 重新生成
+	spec.Selector = nil
+	spec.ManualSelector = nil
+	delete(spec.Template.Labels, "controller-uid")
+	delete(spec.Template.Labels, "job-name")
+	delete(spec.Template.Labels, batchv1.ControllerUidLabel)
+	delete(spec.Template.Labels, batchv1.JobNameLabel)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cm.Namespace,
+			Labels: map[string]string{
+				managedByLabel:       managedByValue,
+				sourceLabel:          cm.Name,
+				sourceNamespaceLabel: cm.Namespace,
+			},
+			Annotations: map[string]string{
+				validationHashAnnotation: hash,
+			},
+		},
+		Spec: spec,
+	}
+	if err := controllerutil.SetControllerReference(cm, job, r.Scheme); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// deleteStaleValidationJobs 删除该 ConfigMap 旧内容的校验 Job
+func (r *ConfigMapReconciler) deleteStaleValidationJobs(ctx context.Context, cm *corev1.ConfigMap, current string) error {
+	list := &batchv1.JobList{}
+	if err := r.List(ctx, list, client.InNamespace(cm.Namespace), client.MatchingLabels{
+		managedByLabel:       managedByValue,
+		sourceLabel:          cm.Name,
+		sourceNamespaceLabel: cm.Namespace,
+	}); err != nil {
+		return err
+	}
+	for i := range list.Items {
+		if list.Items[i].Name == current {
+			continue
+		}
+		if err := r.Delete(ctx, &list.Items[i], client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
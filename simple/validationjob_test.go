@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileValidationJob(t *testing.T) {
+	tests := []struct {
+		name       string
+		condition  batchv1.JobConditionType
+		wantSecret bool
+	}{
+		{name: "Job succeeds", condition: batchv1.JobComplete, wantSecret: true},
+		{name: "Job fails", condition: batchv1.JobFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "validate"},
+				Spec: batchv1.JobSpec{
+					Suspend: ptr.To(true),
+					Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+						RestartPolicy: corev1.RestartPolicyNever,
+						Containers:    []corev1.Container{{Name: "check", Image: "busybox"}},
+					}},
+				},
+			}
+			cm := newConfigMap("app", func(cm *corev1.ConfigMap) {
+				cm.Annotations[validationJobAnnotation] = "validate"
+			})
+			env := newTestEnv(t, []client.Object{cm, template})
+
+			// 第一次调谐创建校验 Job 并等待，不写入 Secret
+			result := env.reconcile(t, "app")
+			if result.RequeueAfter != validationPollInterval {
+				t.Fatalf("RequeueAfter = %v, want %v", result.RequeueAfter, validationPollInterval)
+			}
+			if env.secretExists(t, testNamespace, "app-synced") {
+				t.Fatal("expected no Secret before the validation Job finishes")
+			}
+			job := &batchv1.Job{}
+			name := validationJobName(cm, contentHash(cm))
+			if err := env.c.Get(context.Background(), types.NamespacedName{Namespace: testNamespace, Name: name}, job); err != nil {
+				t.Fatalf("get validation Job: %v", err)
+			}
+			if job.Spec.Suspend != nil {
+				t.Fatalf("validation Job suspend = %v, want unset", *job.Spec.Suspend)
+			}
+
+			// 模拟 Job 控制器上报结果
+			job.Status.Conditions = []batchv1.JobCondition{{Type: tt.condition, Status: corev1.ConditionTrue, Message: "exit code 1"}}
+			if err := env.c.Status().Update(context.Background(), job); err != nil {
+				t.Fatal(err)
+			}
+			env.reconcile(t, "app")
+
+			if got := env.secretExists(t, testNamespace, "app-synced"); got != tt.wantSecret {
+				t.Fatalf("Secret exists = %v, want %v", got, tt.wantSecret)
+			}
+			failed := containsEvent(env.events(), "ValidationFailed", name)
+			if failed == tt.wantSecret {
+				t.Fatalf("ValidationFailed event recorded = %v, want %v", failed, !tt.wantSecret)
+			}
+			if got := env.configMap(t, "app").Annotations[syncErrorAnnotation]; (got != "") == tt.wantSecret {
+				t.Fatalf("%s = %q", syncErrorAnnotation, got)
+			}
+		})
+	}
+}
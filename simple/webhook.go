@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// webhookEvent 是推送到外部 webhook 的调谐结果
+type webhookEvent struct {
+	Object    webhookObjectRef `json:"object"`
+	Action    string           `json:"action"`
+	Result    string           `json:"result"`
+	Error     string           `json:"error,omitempty"`
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+type webhookObjectRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+}
+
+// webhookEventsDropped 记录因缓冲区已满或重试耗尽而丢弃的事件数
+var webhookEventsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "event_webhook_dropped_total",
+	Help: "Reconcile events not delivered to the event webhook, by reason.",
+}, []string{"reason"})
+
+func init() {
+	metrics.Registry.MustRegister(webhookEventsDropped)
+}
+
+// eventWebhook 把调谐结果以 JSON POST 到外部地址。事件先进入缓冲区，由后台协程发送，
+// 不会阻塞调谐；缓冲区满时丢弃新事件，遇到网络错误或 5xx/429 时按指数退避重试
+type eventWebhook struct {
+	URL        string
+	Client     *http.Client
+	MaxRetries int
+
+	events chan webhookEvent
+}
+
+func newEventWebhook(url string, bufferSize int) *eventWebhook {
+	return &eventWebhook{
+		URL:        url,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+		MaxRetries: 5,
+		events:     make(chan webhookEvent, bufferSize),
+	}
+}
+
+// send 记录一次调谐结果，缓冲区已满时直接丢弃
+func (w *eventWebhook) send(key types.NamespacedName, err error) {
+	event := webhookEvent{
+		Object:    webhookObjectRef{APIVersion: "v1", Kind: "ConfigMap", Namespace: key.Namespace, Name: key.Name},
+		Action:    "reconcile",
+		Result:    "success",
+		Timestamp: time.Now().UTC(),
+	}
+	if err != nil {
+		event.Result = "error"
+		event.Error = err.Error()
+	}
+	select {
+	case w.events <- event:
+	default:
+		webhookEventsDropped.WithLabelValues("buffer_full").Inc()
+	}
+}
+
+// Start 实现 manager.Runnable，逐个发送缓冲区中的事件，Manager 停止时退出
+func (w *eventWebhook) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("event-webhook")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-w.events:
+			if err := w.deliver(ctx, event); err != nil {
+				webhookEventsDropped.WithLabelValues("delivery_failed").Inc()
+				logger.Error(err, "Failed to deliver event, dropping it", "object", event.Object)
+			}
+		}
+	}
+}
+
+// deliver 发送一个事件，可重试的错误按 1s、2s、4s... 退避
+func (w *eventWebhook) deliver(ctx context.Context, event webhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		retryable, err := w.post(ctx, body)
+		if err == nil || !retryable || attempt >= w.MaxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post 发送一次请求，返回错误是否值得重试
+func (w *eventWebhook) post(ctx context.Context, body []byte) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("event webhook returned %s", resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
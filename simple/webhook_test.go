@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestEventWebhookPayload(t *testing.T) {
+	tests := []struct {
+		name       string
+		funcs      interceptor.Funcs
+		wantResult string
+		wantError  bool
+	}{
+		{name: "successful sync", wantResult: "success"},
+		{
+			name: "failed sync",
+			funcs: interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					return errors.New("apiserver unavailable")
+				},
+			},
+			wantResult: "error",
+			wantError:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan webhookEvent, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("got %s with Content-Type %q, want a JSON POST", req.Method, req.Header.Get("Content-Type"))
+				}
+				var event webhookEvent
+				if err := json.NewDecoder(req.Body).Decode(&event); err != nil {
+					t.Errorf("decode payload: %v", err)
+				}
+				received <- event
+			}))
+			defer server.Close()
+
+			webhook := newEventWebhook(server.URL, 10)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go webhook.Start(ctx)
+
+			env := newTestEnv(t, []client.Object{newConfigMap("app")},
+				withInterceptor(tt.funcs),
+				withReconciler(func(r *ConfigMapReconciler) { r.Webhook = webhook }))
+			_, err := env.r.Reconcile(context.Background(), requestFor("app"))
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Reconcile error = %v, want error %v", err, tt.wantError)
+			}
+
+			select {
+			case event := <-received:
+				want := webhookObjectRef{APIVersion: "v1", Kind: "ConfigMap", Namespace: testNamespace, Name: "app"}
+				if event.Object != want || event.Action != "reconcile" || event.Result != tt.wantResult {
+					t.Fatalf("payload = %+v, want object %+v with result %s", event, want, tt.wantResult)
+				}
+				if (event.Error != "") != tt.wantError {
+					t.Fatalf("error = %q, want error %v", event.Error, tt.wantError)
+				}
+				if event.Timestamp.IsZero() {
+					t.Fatal("expected a timestamp")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("webhook received nothing")
+			}
+		})
+	}
+}
+
+func TestEventWebhookPost(t *testing.T) {
+	tests := []struct {
+		status        int
+		wantErr       bool
+		wantRetryable bool
+	}{
+		{status: http.StatusOK},
+		{status: http.StatusBadRequest, wantErr: true},
+		{status: http.StatusTooManyRequests, wantErr: true, wantRetryable: true},
+		{status: http.StatusServiceUnavailable, wantErr: true, wantRetryable: true},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			retryable, err := newEventWebhook(server.URL, 1).post(context.Background(), []byte("{}"))
+			if (err != nil) != tt.wantErr || retryable != tt.wantRetryable {
+				t.Fatalf("post = (%v, %v), want retryable %v and error %v", retryable, err, tt.wantRetryable, tt.wantErr)
+			}
+		})
+	}
+}